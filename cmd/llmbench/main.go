@@ -0,0 +1,359 @@
+// Command llmbench runs, scores and reports on every example through the
+// shared bench interface, so one command covers "everything for model X".
+//
+//	llmbench list
+//	llmbench run    [-model m | -models a,b | -models-file f] [-examples a,b] [-parallel n] [-stream] [-fail-under q]
+//	llmbench score  [-examples a,b] [-fail-under q]
+//	llmbench report [-examples a,b]
+//
+// run saves each model's raw outputs under examples/<name>/results/llmbench/
+// and scores them; score and report re-read those files without calling a
+// model. The exit status is 0 on success, 1 if a model call failed or a
+// scenario scored below -fail-under, and 2 on usage errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/reporting"
+	"github.com/statherm/local-llm-examples/shared/sweep"
+	"github.com/statherm/local-llm-examples/shared/types"
+
+	_ "github.com/statherm/local-llm-examples/examples/classification-routing/classify"
+	_ "github.com/statherm/local-llm-examples/examples/format-conversion/convert"
+	_ "github.com/statherm/local-llm-examples/examples/function-calling/toolcall"
+	_ "github.com/statherm/local-llm-examples/examples/search-reranking/rerank"
+	_ "github.com/statherm/local-llm-examples/examples/structured-extraction/extract"
+	_ "github.com/statherm/local-llm-examples/examples/summarization/summarize"
+	_ "github.com/statherm/local-llm-examples/examples/test-data-generation/datagen"
+	_ "github.com/statherm/local-llm-examples/examples/validation-gatekeeping/gatekeep"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	var code int
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "list":
+		code = list()
+	case "run":
+		code = run(args)
+	case "score":
+		code = score(args)
+	case "report":
+		code = report(args)
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "llmbench: unknown command %q\n\n", cmd)
+		usage()
+		code = exitUsage
+	}
+	os.Exit(code)
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: llmbench <command> [flags]
+
+Commands:
+  list     List the available examples
+  run      Run examples against one or more models and score the outputs
+  score    Score saved outputs
+  report   Print the benchmark report and model comparison for saved outputs
+
+Run "llmbench <command> -h" for a command's flags.
+`)
+}
+
+// common holds the flags shared by run, score and report. pricing is only
+// registered by the commands that print a report.
+type common struct {
+	root      string
+	examples  string
+	pricing   string
+	failUnder float64
+}
+
+func newFlagSet(name string, c *common) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&c.root, "root", ".", "Repository root containing examples/")
+	fs.StringVar(&c.examples, "examples", "", "Comma-separated examples to include (default all)")
+	return fs
+}
+
+// addPricingFlag registers -pricing for the commands that print a report.
+func addPricingFlag(fs *flag.FlagSet, c *common) {
+	fs.StringVar(&c.pricing, "pricing", "", "Pricing table for the Cost/Call column (default: pricing.json under -root)")
+}
+
+// prices loads the pricing table selected by -pricing.
+func (c common) prices() (*pricing.Table, error) {
+	return pricing.Load(c.pricing, c.root)
+}
+
+// selected resolves -examples to registered examples.
+func (c common) selected() ([]bench.Example, error) {
+	if c.examples == "" {
+		return bench.All(), nil
+	}
+	var out []bench.Example
+	for _, name := range strings.Split(c.examples, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		ex, ok := bench.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown example %q (see llmbench list)", name)
+		}
+		out = append(out, ex)
+	}
+	return out, nil
+}
+
+func (c common) dir(ex bench.Example) string {
+	return filepath.Join(c.root, "examples", ex.Name())
+}
+
+func list() int {
+	for _, ex := range bench.All() {
+		fmt.Printf("%-24s %s\n", ex.Name(), ex.Description())
+	}
+	return exitOK
+}
+
+func run(args []string) int {
+	var c common
+	fs := newFlagSet("run", &c)
+	addPricingFlag(fs, &c)
+	model := fs.String("model", "qwen3:4b", "Ollama model to use")
+	modelList := fs.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile := fs.String("models-file", "", "File listing models to run and compare, one per line")
+	parallel := fs.Int("parallel", 1, "Number of concurrent model requests")
+	conn := provider.AddFlags(fs)
+	reasks := fs.Int("reask", 0, "Times to re-ask the model with the parse error when its JSON cannot be repaired")
+	fs.Float64Var(&c.failUnder, "fail-under", 0, "Exit 1 if any scenario's quality is below this value (0-1)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	examples, err := c.selected()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+		return exitUsage
+	}
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+		return exitUsage
+	}
+
+	prices, err := c.prices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+		return exitUsage
+	}
+	client, err := conn.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+		return exitUsage
+	}
+	dirs := make([]string, len(examples))
+	for i, ex := range examples {
+		dirs[i] = c.dir(ex)
+	}
+	for _, m := range models {
+		if err := bench.Preflight(context.Background(), client, m, conn.Pull, dirs...); err != nil {
+			fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+			return exitFail
+		}
+	}
+
+	code := exitOK
+	var rows []types.BenchmarkResult
+	for _, m := range models {
+		for _, ex := range examples {
+			fmt.Printf("=== %s (model: %s, parallel=%d) ===\n", ex.Name(), m, *parallel)
+			runFile, summary, err := bench.Run(context.Background(), client, ex, c.dir(ex), m, *parallel, *reasks)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+				code = exitFail
+				continue
+			}
+			var tokensOut int
+			for _, out := range runFile.Outputs {
+				tokensOut += out.Meta.TokensOut
+			}
+			fmt.Printf("  %s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(tokensOut))
+
+			cases, err := ex.Load(c.dir(ex))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "llmbench: %s: %v\n", ex.Name(), err)
+				code = exitFail
+				continue
+			}
+			r, callErrs := scoreRun(ex, cases, runFile)
+			if callErrs > 0 {
+				code = exitFail
+			}
+			rows = append(rows, bench.WithModelInfo(c.dir(ex), r)...)
+			fmt.Println()
+		}
+	}
+
+	prices.Apply(rows)
+	fmt.Print(reporting.GenerateReport(rows))
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(rows))
+	}
+	if belowThreshold(rows, c.failUnder) {
+		code = exitFail
+	}
+	return code
+}
+
+func score(args []string) int {
+	var c common
+	fs := newFlagSet("score", &c)
+	fs.Float64Var(&c.failUnder, "fail-under", 0, "Exit 1 if any scenario's quality is below this value (0-1)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	rows, code := scoreSaved(c)
+	if code != exitOK {
+		return code
+	}
+	if belowThreshold(rows, c.failUnder) {
+		return exitFail
+	}
+	return exitOK
+}
+
+func report(args []string) int {
+	var c common
+	fs := newFlagSet("report", &c)
+	addPricingFlag(fs, &c)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	prices, err := c.prices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+		return exitUsage
+	}
+	rows, code := scoreSaved(c)
+	if code == exitUsage {
+		return code
+	}
+	prices.Apply(rows)
+	fmt.Print(reporting.GenerateReport(rows))
+	fmt.Print(reporting.GenerateComparison(rows))
+	return code
+}
+
+// scoreSaved scores every saved run of the selected examples.
+func scoreSaved(c common) ([]types.BenchmarkResult, int) {
+	examples, err := c.selected()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llmbench: %v\n", err)
+		return nil, exitUsage
+	}
+
+	code := exitOK
+	var rows []types.BenchmarkResult
+	for _, ex := range examples {
+		runs, err := bench.LoadRuns(c.dir(ex))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "llmbench: %s: %v\n", ex.Name(), err)
+			code = exitFail
+			continue
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		cases, err := ex.Load(c.dir(ex))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "llmbench: %s: %v\n", ex.Name(), err)
+			code = exitFail
+			continue
+		}
+		for _, runFile := range runs {
+			fmt.Printf("=== %s (model: %s) ===\n", ex.Name(), runFile.Model)
+			r, callErrs := scoreRun(ex, cases, runFile)
+			if callErrs > 0 {
+				code = exitFail
+			}
+			rows = append(rows, bench.WithModelInfo(c.dir(ex), r)...)
+			fmt.Println()
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Println("No saved runs found; use llmbench run first.")
+	}
+	return rows, code
+}
+
+// scoreRun scores one run, printing a line per scenario and one per case
+// that could not be scored. It returns the scenario rows and the number of
+// failed model calls.
+func scoreRun(ex bench.Example, cases []bench.Case, runFile bench.RunFile) ([]types.BenchmarkResult, int) {
+	results := bench.ScoreRun(ex, cases, runFile)
+	callErrs := 0
+	for _, r := range results {
+		if r.Error != "" {
+			callErrs++
+		}
+		if r.Failed != "" {
+			fmt.Printf("  FAIL %s/%s: %s\n", r.Scenario, r.ID, r.Failed)
+		}
+	}
+
+	rows := bench.Summarize(ex, runFile.Model, results)
+	for _, row := range rows {
+		fmt.Printf("  %-40s %s=%.3f", row.Example, row.QualityName, row.Quality)
+		if row.SchemaChecks > 0 {
+			fmt.Printf("  schema_valid=%d/%d", row.SchemaValid, row.SchemaChecks)
+		}
+		if row.JSONChecks > 0 {
+			fmt.Printf("  json_raw=%d/%d json_repaired=%d", row.JSONRaw, row.JSONChecks, row.JSONRepaired)
+		}
+		fmt.Println()
+	}
+	return rows, callErrs
+}
+
+// belowThreshold reports (and prints) every row whose quality is under min.
+func belowThreshold(rows []types.BenchmarkResult, min float64) bool {
+	if min <= 0 {
+		return false
+	}
+	below := false
+	for _, row := range rows {
+		if row.Quality < min {
+			fmt.Fprintf(os.Stderr, "llmbench: %s (%s) %s=%.3f is below %.3f\n",
+				row.Example, row.Model, row.QualityName, row.Quality, min)
+			below = true
+		}
+	}
+	return below
+}
@@ -0,0 +1,137 @@
+// Package classify holds the classification-routing example's data types,
+// prompts and output schemas, shared by the example's main and the llmbench
+// CLI.
+package classify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// --- Data types ---
+
+type Issue struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type IssueLabel struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Priority string `json:"priority"`
+	// SchemaValid records whether the raw model output satisfied
+	// IssueLabelSchema; it is nil in expected fixtures.
+	SchemaValid *bool `json:"schema_valid,omitempty"`
+	// JSON records whether the output parsed as returned or needed repair;
+	// it is empty in expected fixtures.
+	JSON repair.Outcome `json:"json,omitempty"`
+	// Meta is the call's performance metadata, used by the report and the
+	// multi-model comparison; it is nil in expected fixtures.
+	Meta *types.ModelMetadata `json:"metadata,omitempty"`
+}
+
+type Message struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type MessageLabel struct {
+	ID          string               `json:"id"`
+	Intent      string               `json:"intent"`
+	Sentiment   string               `json:"sentiment"`
+	NeedsHuman  bool                 `json:"needs_human"`
+	SchemaValid *bool                `json:"schema_valid,omitempty"`
+	JSON        repair.Outcome       `json:"json,omitempty"`
+	Meta        *types.ModelMetadata `json:"metadata,omitempty"`
+}
+
+// --- Prompt templates ---
+
+const IssueTriageSystem = `You are an issue triage classifier. Classify the given GitHub issue into exactly one category and one priority level.
+
+Categories: bug, feature, question, docs, performance
+Priorities: critical, high, medium, low
+
+Guidelines:
+- "bug": something is broken or not working as expected
+- "feature": a request for new functionality
+- "question": the user is asking how to do something
+- "docs": documentation is missing, wrong, or unclear
+- "performance": the system is slow or resource-intensive
+
+Priority guidelines:
+- "critical": data loss, security issue, complete breakage, or affects all users
+- "high": significant impact, no workaround, or affects many users
+- "medium": moderate impact, workaround exists
+- "low": minor inconvenience, cosmetic, or affects few users
+
+Respond with JSON only: {"category": "...", "priority": "..."}`
+
+const IntentDetectionSystem = `You are a customer support intent classifier. Classify the customer message into exactly one intent, one sentiment, and whether it needs a human agent.
+
+Intents: billing, technical, account, cancellation, feedback, other
+Sentiments: positive, neutral, negative
+
+Guidelines for needs_human:
+- true: refund requests, cancellation requests, complaints requiring action, compliance/legal
+- false: general questions, positive feedback, technical issues solvable with docs, informational requests
+
+Respond with JSON only: {"intent": "...", "sentiment": "...", "needs_human": true/false}`
+
+// --- Output schemas ---
+
+// The label schemas mirror the JSON contract in each system prompt. Outputs
+// are validated before normalization, so a label in the wrong case or outside
+// the allowed set counts against the schema-valid rate.
+
+var IssueLabelSchema = schema.MustParse(`{
+  "type": "object",
+  "properties": {
+    "category": {"enum": ["bug", "feature", "question", "docs", "performance"]},
+    "priority": {"enum": ["critical", "high", "medium", "low"]}
+  },
+  "required": ["category", "priority"]
+}`)
+
+var MessageLabelSchema = schema.MustParse(`{
+  "type": "object",
+  "properties": {
+    "intent": {"enum": ["billing", "technical", "account", "cancellation", "feedback", "other"]},
+    "sentiment": {"enum": ["positive", "neutral", "negative"]},
+    "needs_human": {"type": "boolean"}
+  },
+  "required": ["intent", "sentiment", "needs_human"]
+}`)
+
+// IssuePrompt renders an issue as the user prompt for issue triage.
+func IssuePrompt(issue Issue) string {
+	return fmt.Sprintf("Title: %s\n\nBody: %s", issue.Title, issue.Body)
+}
+
+// ParseIssueLabel decodes a triage response and normalizes its labels.
+func ParseIssueLabel(resp string) (IssueLabel, error) {
+	var label IssueLabel
+	if err := repair.Unmarshal(resp, &label); err != nil {
+		return IssueLabel{}, err
+	}
+	label.Category = strings.ToLower(strings.TrimSpace(label.Category))
+	label.Priority = strings.ToLower(strings.TrimSpace(label.Priority))
+	return label, nil
+}
+
+// ParseMessageLabel decodes an intent detection response and normalizes its
+// labels.
+func ParseMessageLabel(resp string) (MessageLabel, error) {
+	var label MessageLabel
+	if err := repair.Unmarshal(resp, &label); err != nil {
+		return MessageLabel{}, err
+	}
+	label.Intent = strings.ToLower(strings.TrimSpace(label.Intent))
+	label.Sentiment = strings.ToLower(strings.TrimSpace(label.Sentiment))
+	return label, nil
+}
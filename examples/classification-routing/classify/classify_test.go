@@ -0,0 +1,121 @@
+package classify
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/cassette"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+)
+
+func TestParseIssueLabel(t *testing.T) {
+	tests := []struct {
+		resp string
+		want IssueLabel
+	}{
+		{`{"category": "bug", "priority": "high"}`, IssueLabel{Category: "bug", Priority: "high"}},
+		{`{"category": " Docs ", "priority": "LOW"}`, IssueLabel{Category: "docs", Priority: "low"}},
+		{"```json\n{\"category\": \"feature\", \"priority\": \"medium\",}\n```", IssueLabel{Category: "feature", Priority: "medium"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseIssueLabel(tt.resp)
+		if err != nil {
+			t.Errorf("ParseIssueLabel(%q): %v", tt.resp, err)
+			continue
+		}
+		if got.Category != tt.want.Category || got.Priority != tt.want.Priority {
+			t.Errorf("ParseIssueLabel(%q) = %s/%s, want %s/%s", tt.resp, got.Category, got.Priority, tt.want.Category, tt.want.Priority)
+		}
+	}
+	if _, err := ParseIssueLabel("I think this is a bug."); err == nil {
+		t.Error("ParseIssueLabel(prose) succeeded, want error")
+	}
+}
+
+func TestParseMessageLabel(t *testing.T) {
+	got, err := ParseMessageLabel(`<think>refund</think>{"intent": "Billing", "sentiment": "negative", "needs_human": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Intent != "billing" || got.Sentiment != "negative" || !got.NeedsHuman {
+		t.Errorf("ParseMessageLabel = %+v", got)
+	}
+}
+
+// TestIssueTriageReplay replays a recorded issue-triage run and scores it
+// as the example does, so a change to parsing, repair or scoring that moves
+// the numbers shows up here. The cassette holds the replies of a scripted
+// ollamatest server: three wrong labels, a fenced reply, a reply behind a
+// <think> block and one capitalized category. To record a real model
+// instead, run `go run . -scenario issues -record classify/testdata/cassette`
+// from the example directory and update the counts below.
+func TestIssueTriageReplay(t *testing.T) {
+	var issues []Issue
+	if err := bench.LoadJSON(filepath.Join("..", "testdata", "issues.json"), &issues); err != nil {
+		t.Fatal(err)
+	}
+	var expected []IssueLabel
+	if err := bench.LoadJSON(filepath.Join("..", "expected", "issues.json"), &expected); err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string]IssueLabel)
+	for _, e := range expected {
+		want[e.ID] = e
+	}
+
+	rep, err := cassette.New(filepath.Join("testdata", "cassette"), cassette.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ollama.NewClient()
+	client.BaseURL = "http://127.0.0.1:1" // nothing may reach the network
+	client.HTTPClient.Transport = rep
+
+	var catPred, catLabel, priPred, priLabel []string
+	var schemaValid, combined int
+	outcomes := make(map[repair.Outcome]int)
+	for _, issue := range issues {
+		req := ollama.NewChatRequest("qwen3:4b", IssueTriageSystem, IssuePrompt(issue), true)
+		reply, err := repair.Complete(context.Background(), client, req, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", issue.ID, err)
+		}
+		outcomes[reply.Outcome]++
+		if IssueLabelSchema.ValidateJSON([]byte(reply.Raw)) == nil {
+			schemaValid++
+		}
+		label, err := ParseIssueLabel(reply.Text)
+		if err != nil {
+			t.Fatalf("%s: %v", issue.ID, err)
+		}
+
+		e := want[issue.ID]
+		catPred, catLabel = append(catPred, label.Category), append(catLabel, e.Category)
+		priPred, priLabel = append(priPred, label.Priority), append(priLabel, e.Priority)
+		if label.Category == e.Category && label.Priority == e.Priority {
+			combined++
+		}
+	}
+
+	catAcc, err := scoring.AccuracyScore(catPred, catLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priAcc, err := scoring.AccuracyScore(priPred, priLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catAcc != 0.9 || priAcc != 0.95 || combined != 17 {
+		t.Errorf("category %.2f, priority %.2f, combined %d/20; want 0.90, 0.95, 17/20", catAcc, priAcc, combined)
+	}
+	if schemaValid != 17 {
+		t.Errorf("schema-valid = %d/20, want 17/20", schemaValid)
+	}
+	if outcomes[repair.Raw] != 18 || outcomes[repair.Repaired] != 2 {
+		t.Errorf("JSON outcomes = %v, want 18 raw and 2 repaired", outcomes)
+	}
+}
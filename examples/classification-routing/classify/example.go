@@ -0,0 +1,122 @@
+package classify
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs issue triage and intent detection through the bench
+// interface. Issue triage scores a case correct only when both category and
+// priority match; intent detection scores the intent alone, matching the
+// example's report.
+type Example struct{}
+
+type issueCase struct {
+	Issue    Issue
+	Expected IssueLabel
+}
+
+type messageCase struct {
+	Message  Message
+	Expected MessageLabel
+}
+
+func (Example) Name() string { return "classification-routing" }
+
+func (Example) Description() string {
+	return "Classify GitHub issues and support messages into fixed label sets"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	var issues []Issue
+	var issueLabels []IssueLabel
+	var messages []Message
+	var messageLabels []MessageLabel
+	for path, v := range map[string]any{
+		filepath.Join(dir, "testdata", "issues.json"):   &issues,
+		filepath.Join(dir, "expected", "issues.json"):   &issueLabels,
+		filepath.Join(dir, "testdata", "messages.json"): &messages,
+		filepath.Join(dir, "expected", "messages.json"): &messageLabels,
+	} {
+		if err := bench.LoadJSON(path, v); err != nil {
+			return nil, err
+		}
+	}
+
+	expectedIssues := make(map[string]IssueLabel, len(issueLabels))
+	for _, l := range issueLabels {
+		expectedIssues[l.ID] = l
+	}
+	expectedMessages := make(map[string]MessageLabel, len(messageLabels))
+	for _, l := range messageLabels {
+		expectedMessages[l.ID] = l
+	}
+
+	var cases []bench.Case
+	for _, issue := range issues {
+		e, ok := expectedIssues[issue.ID]
+		if !ok {
+			return nil, fmt.Errorf("no expected label for %s", issue.ID)
+		}
+		cases = append(cases, bench.Case{ID: issue.ID, Scenario: "issues", Data: issueCase{issue, e}})
+	}
+	for _, msg := range messages {
+		e, ok := expectedMessages[msg.ID]
+		if !ok {
+			return nil, fmt.Errorf("no expected label for %s", msg.ID)
+		}
+		cases = append(cases, bench.Case{ID: msg.ID, Scenario: "messages", Data: messageCase{msg, e}})
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	switch d := c.Data.(type) {
+	case issueCase:
+		return ollama.NewChatRequest("", IssueTriageSystem, IssuePrompt(d.Issue), true)
+	case messageCase:
+		return ollama.NewChatRequest("", IntentDetectionSystem, d.Message.Text, true)
+	}
+	panic(fmt.Sprintf("classify: unexpected case data %T", c.Data))
+}
+
+func (Example) Parse(c bench.Case, raw string) (any, error) {
+	if _, ok := c.Data.(issueCase); ok {
+		return ParseIssueLabel(raw)
+	}
+	return ParseMessageLabel(raw)
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	switch d := c.Data.(type) {
+	case issueCase:
+		got := parsed.(IssueLabel)
+		ok := scoring.ExactMatch(d.Expected.Category, got.Category) && scoring.ExactMatch(d.Expected.Priority, got.Priority)
+		return bench.Score{Quality: boolScore(ok), Metric: "Combined Acc"}
+	case messageCase:
+		got := parsed.(MessageLabel)
+		return bench.Score{Quality: boolScore(scoring.ExactMatch(d.Expected.Intent, got.Intent)), Metric: "Intent Acc"}
+	}
+	return bench.Score{}
+}
+
+func (Example) Schema(c bench.Case) *schema.Schema {
+	if _, ok := c.Data.(issueCase); ok {
+		return IssueLabelSchema
+	}
+	return MessageLabelSchema
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -10,76 +10,30 @@ import (
 	"strings"
 	"time"
 
+	"github.com/statherm/local-llm-examples/examples/classification-routing/classify"
+	"github.com/statherm/local-llm-examples/shared/bench"
 	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
 	"github.com/statherm/local-llm-examples/shared/reporting"
+	"github.com/statherm/local-llm-examples/shared/runner"
 	"github.com/statherm/local-llm-examples/shared/scoring"
+	"github.com/statherm/local-llm-examples/shared/sweep"
 	"github.com/statherm/local-llm-examples/shared/types"
 )
 
-// --- Data types ---
-
-type Issue struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-	Body  string `json:"body"`
-}
-
-type IssueLabel struct {
-	ID       string `json:"id"`
-	Category string `json:"category"`
-	Priority string `json:"priority"`
-}
-
-type Message struct {
-	ID   string `json:"id"`
-	Text string `json:"text"`
-}
-
-type MessageLabel struct {
-	ID         string `json:"id"`
-	Intent     string `json:"intent"`
-	Sentiment  string `json:"sentiment"`
-	NeedsHuman bool   `json:"needs_human"`
-}
-
-// --- Prompt templates ---
-
-const issueTriageSystem = `You are an issue triage classifier. Classify the given GitHub issue into exactly one category and one priority level.
-
-Categories: bug, feature, question, docs, performance
-Priorities: critical, high, medium, low
-
-Guidelines:
-- "bug": something is broken or not working as expected
-- "feature": a request for new functionality
-- "question": the user is asking how to do something
-- "docs": documentation is missing, wrong, or unclear
-- "performance": the system is slow or resource-intensive
-
-Priority guidelines:
-- "critical": data loss, security issue, complete breakage, or affects all users
-- "high": significant impact, no workaround, or affects many users
-- "medium": moderate impact, workaround exists
-- "low": minor inconvenience, cosmetic, or affects few users
-
-Respond with JSON only: {"category": "...", "priority": "..."}`
-
-const intentDetectionSystem = `You are a customer support intent classifier. Classify the customer message into exactly one intent, one sentiment, and whether it needs a human agent.
-
-Intents: billing, technical, account, cancellation, feedback, other
-Sentiments: positive, neutral, negative
-
-Guidelines for needs_human:
-- true: refund requests, cancellation requests, complaints requiring action, compliance/legal
-- false: general questions, positive feedback, technical issues solvable with docs, informational requests
-
-Respond with JSON only: {"intent": "...", "sentiment": "...", "needs_human": true/false}`
-
 func main() {
 	model := flag.String("model", "qwen3:4b", "Ollama model to use")
+	modelList := flag.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile := flag.String("models-file", "", "File listing models to run and compare, one per line")
 	scenario := flag.String("scenario", "all", "Scenario to run: issues, messages, or all")
 	scoreOnly := flag.Bool("score", false, "Score existing results instead of running models")
 	reportOnly := flag.Bool("report", false, "Generate report from existing results")
+	parallel := flag.Int("parallel", 1, "Number of concurrent model requests")
+	conn := provider.AddFlags(flag.CommandLine)
+	pricingFile := flag.String("pricing", "", "Pricing table for the Cost/Call column (default: pricing.json in this or a parent directory)")
+	reasks := flag.Int("reask", 0, "Times to re-ask the model with the parse error when its JSON cannot be repaired")
 	flag.Parse()
 
 	exampleDir := filepath.Dir(os.Args[0])
@@ -87,110 +41,169 @@ func main() {
 		exampleDir = abs
 	}
 
+	prices, err := pricing.Load(*pricingFile, exampleDir)
+	if err != nil {
+		log.Fatalf("Failed to load pricing: %v", err)
+	}
+
 	if *scoreOnly {
 		scoreResults(exampleDir, *scenario)
 		return
 	}
 	if *reportOnly {
-		generateReport(exampleDir)
+		generateReport(exampleDir, prices)
 		return
 	}
 
-	client := ollama.NewClient()
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		log.Fatalf("Failed to resolve models: %v", err)
+	}
 
-	if *scenario == "all" || *scenario == "issues" {
-		runIssueTriage(client, *model, exampleDir)
+	client, err := conn.New()
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
 	}
-	if *scenario == "all" || *scenario == "messages" {
-		runIntentDetection(client, *model, exampleDir)
+	for _, m := range models {
+		if err := bench.Preflight(context.Background(), client, m, conn.Pull, exampleDir); err != nil {
+			log.Fatalf("Failed to check model: %v", err)
+		}
 	}
-}
 
-func runIssueTriage(client *ollama.Client, model, dir string) {
-	issues := loadJSON[[]Issue](filepath.Join(dir, "testdata", "issues.json"))
-	fmt.Printf("=== Issue Triage (%s) — %d issues ===\n", model, len(issues))
+	for _, m := range models {
+		if *scenario == "all" || *scenario == "issues" {
+			runIssueTriage(client, m, exampleDir, *parallel, *reasks)
+		}
+		if *scenario == "all" || *scenario == "messages" {
+			runIntentDetection(client, m, exampleDir, *parallel, *reasks)
+		}
+	}
 
-	var results []IssueLabel
-	var totalTokensIn, totalTokensOut int
-	var totalDuration time.Duration
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(prices.Apply(sweep.Only(bench.WithModelInfo(exampleDir, benchmarkResults(exampleDir)), models, bench.SanitizeModelName))))
+	}
+}
 
-	for i, issue := range issues {
-		prompt := fmt.Sprintf("Title: %s\n\nBody: %s", issue.Title, issue.Body)
-		resp, meta, err := client.ChatCompletion(model, issueTriageSystem, prompt, true)
+func runIssueTriage(client provider.Provider, model, dir string, parallel, reasks int) {
+	issues := loadJSON[[]classify.Issue](filepath.Join(dir, "testdata", "issues.json"))
+	fmt.Printf("=== Issue Triage (%s) — %d issues, parallel=%d ===\n", model, len(issues), parallel)
+
+	metas := make([]types.ModelMetadata, len(issues))
+	runs, summary := runner.Run(context.Background(), issues, parallel, func(ctx context.Context, i int, issue classify.Issue) (classify.IssueLabel, error) {
+		reply, err := repair.Complete(ctx, client, ollama.NewChatRequest(model, classify.IssueTriageSystem, classify.IssuePrompt(issue), true), reasks)
+		meta := reply.Meta
+		metas[i] = meta
 		if err != nil {
 			log.Printf("  [%d/%d] %s: ERROR: %v", i+1, len(issues), issue.ID, err)
-			results = append(results, IssueLabel{ID: issue.ID})
-			continue
+			return classify.IssueLabel{ID: issue.ID}, err
 		}
 
-		var label IssueLabel
-		if err := json.Unmarshal([]byte(resp), &label); err != nil {
-			log.Printf("  [%d/%d] %s: JSON parse error: %v (raw: %s)", i+1, len(issues), issue.ID, err, resp)
-			results = append(results, IssueLabel{ID: issue.ID})
-			continue
+		// The schema check is on the reply as returned, so repaired output
+		// still counts against the schema-valid rate.
+		valid := len(classify.IssueLabelSchema.ValidateJSON([]byte(reply.Raw))) == 0
+
+		label, err := classify.ParseIssueLabel(reply.Text)
+		if err != nil {
+			log.Printf("  [%d/%d] %s: JSON parse error: %v (raw: %s)", i+1, len(issues), issue.ID, err, reply.Raw)
+			return classify.IssueLabel{ID: issue.ID, SchemaValid: &valid, JSON: reply.Outcome}, nil
 		}
 		label.ID = issue.ID
-		label.Category = strings.ToLower(strings.TrimSpace(label.Category))
-		label.Priority = strings.ToLower(strings.TrimSpace(label.Priority))
-
-		totalTokensIn += meta.TokensIn
-		totalTokensOut += meta.TokensOut
-		totalDuration += meta.TotalTime
+		label.SchemaValid = &valid
+		label.JSON = reply.Outcome
 
 		fmt.Printf("  [%d/%d] %s → category=%s priority=%s (%.0fms, %.1f tok/s)\n",
 			i+1, len(issues), issue.ID, label.Category, label.Priority,
 			meta.TotalTime.Seconds()*1000, meta.TokensPerSec)
 
-		results = append(results, label)
+		return label, nil
+	})
+
+	results := make([]classify.IssueLabel, len(runs))
+	var totalTokensIn, totalTokensOut, schemaValid int
+	var totalDuration time.Duration
+	for i, r := range runs {
+		results[i] = r.Value
+		if metas[i].TotalTime > 0 || metas[i].Retries > 0 {
+			meta := metas[i]
+			meta.QueueTime = r.Queue
+			results[i].Meta = &meta
+		}
+		if r.Value.SchemaValid != nil && *r.Value.SchemaValid {
+			schemaValid++
+		}
+		totalTokensIn += metas[i].TokensIn
+		totalTokensOut += metas[i].TokensOut
+		totalDuration += metas[i].TotalTime
 	}
 
-	outPath := filepath.Join(dir, "results", fmt.Sprintf("issues-%s.json", sanitizeModelName(model)))
-	writeJSON(outPath, results)
-	fmt.Printf("  Wrote %s (%d results, %d tok in, %d tok out, %.1fs total)\n\n",
-		outPath, len(results), totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	outPath := filepath.Join(dir, "results", fmt.Sprintf("issues-%s.json", bench.SanitizeModelName(model)))
+	if err := bench.WriteJSON(outPath, results); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+	fmt.Printf("  Wrote %s (%d results, %d schema-valid, %d tok in, %d tok out, %.1fs total)\n",
+		outPath, len(results), schemaValid, totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	fmt.Printf("  %s, %.1f tok/s aggregate\n\n", summary, summary.TokensPerSec(totalTokensOut))
 }
 
-func runIntentDetection(client *ollama.Client, model, dir string) {
-	messages := loadJSON[[]Message](filepath.Join(dir, "testdata", "messages.json"))
-	fmt.Printf("=== Intent Detection (%s) — %d messages ===\n", model, len(messages))
+func runIntentDetection(client provider.Provider, model, dir string, parallel, reasks int) {
+	messages := loadJSON[[]classify.Message](filepath.Join(dir, "testdata", "messages.json"))
+	fmt.Printf("=== Intent Detection (%s) — %d messages, parallel=%d ===\n", model, len(messages), parallel)
 
-	var results []MessageLabel
-	var totalTokensIn, totalTokensOut int
-	var totalDuration time.Duration
-
-	for i, msg := range messages {
-		resp, meta, err := client.ChatCompletion(model, intentDetectionSystem, msg.Text, true)
+	metas := make([]types.ModelMetadata, len(messages))
+	runs, summary := runner.Run(context.Background(), messages, parallel, func(ctx context.Context, i int, msg classify.Message) (classify.MessageLabel, error) {
+		reply, err := repair.Complete(ctx, client, ollama.NewChatRequest(model, classify.IntentDetectionSystem, msg.Text, true), reasks)
+		meta := reply.Meta
+		metas[i] = meta
 		if err != nil {
 			log.Printf("  [%d/%d] %s: ERROR: %v", i+1, len(messages), msg.ID, err)
-			results = append(results, MessageLabel{ID: msg.ID})
-			continue
+			return classify.MessageLabel{ID: msg.ID}, err
 		}
 
-		var label MessageLabel
-		if err := json.Unmarshal([]byte(resp), &label); err != nil {
-			log.Printf("  [%d/%d] %s: JSON parse error: %v (raw: %s)", i+1, len(messages), msg.ID, err, resp)
-			results = append(results, MessageLabel{ID: msg.ID})
-			continue
+		// The schema check is on the reply as returned, so repaired output
+		// still counts against the schema-valid rate.
+		valid := len(classify.MessageLabelSchema.ValidateJSON([]byte(reply.Raw))) == 0
+
+		label, err := classify.ParseMessageLabel(reply.Text)
+		if err != nil {
+			log.Printf("  [%d/%d] %s: JSON parse error: %v (raw: %s)", i+1, len(messages), msg.ID, err, reply.Raw)
+			return classify.MessageLabel{ID: msg.ID, SchemaValid: &valid, JSON: reply.Outcome}, nil
 		}
 		label.ID = msg.ID
-		label.Intent = strings.ToLower(strings.TrimSpace(label.Intent))
-		label.Sentiment = strings.ToLower(strings.TrimSpace(label.Sentiment))
-
-		totalTokensIn += meta.TokensIn
-		totalTokensOut += meta.TokensOut
-		totalDuration += meta.TotalTime
+		label.SchemaValid = &valid
+		label.JSON = reply.Outcome
 
 		fmt.Printf("  [%d/%d] %s → intent=%s sentiment=%s needs_human=%v (%.0fms, %.1f tok/s)\n",
 			i+1, len(messages), msg.ID, label.Intent, label.Sentiment, label.NeedsHuman,
 			meta.TotalTime.Seconds()*1000, meta.TokensPerSec)
 
-		results = append(results, label)
+		return label, nil
+	})
+
+	results := make([]classify.MessageLabel, len(runs))
+	var totalTokensIn, totalTokensOut, schemaValid int
+	var totalDuration time.Duration
+	for i, r := range runs {
+		results[i] = r.Value
+		if metas[i].TotalTime > 0 || metas[i].Retries > 0 {
+			meta := metas[i]
+			meta.QueueTime = r.Queue
+			results[i].Meta = &meta
+		}
+		if r.Value.SchemaValid != nil && *r.Value.SchemaValid {
+			schemaValid++
+		}
+		totalTokensIn += metas[i].TokensIn
+		totalTokensOut += metas[i].TokensOut
+		totalDuration += metas[i].TotalTime
 	}
 
-	outPath := filepath.Join(dir, "results", fmt.Sprintf("messages-%s.json", sanitizeModelName(model)))
-	writeJSON(outPath, results)
-	fmt.Printf("  Wrote %s (%d results, %d tok in, %d tok out, %.1fs total)\n\n",
-		outPath, len(results), totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	outPath := filepath.Join(dir, "results", fmt.Sprintf("messages-%s.json", bench.SanitizeModelName(model)))
+	if err := bench.WriteJSON(outPath, results); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+	fmt.Printf("  Wrote %s (%d results, %d schema-valid, %d tok in, %d tok out, %.1fs total)\n",
+		outPath, len(results), schemaValid, totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	fmt.Printf("  %s, %.1f tok/s aggregate\n\n", summary, summary.TokensPerSec(totalTokensOut))
 }
 
 func scoreResults(dir, scenario string) {
@@ -203,15 +216,15 @@ func scoreResults(dir, scenario string) {
 }
 
 func scoreIssues(dir string) {
-	expected := loadJSON[[]IssueLabel](filepath.Join(dir, "expected", "issues.json"))
-	expectedMap := make(map[string]IssueLabel)
+	expected := loadJSON[[]classify.IssueLabel](filepath.Join(dir, "expected", "issues.json"))
+	expectedMap := make(map[string]classify.IssueLabel)
 	for _, e := range expected {
 		expectedMap[e.ID] = e
 	}
 
 	resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", "issues-*.json"))
 	for _, rf := range resultFiles {
-		actual := loadJSON[[]IssueLabel](rf)
+		actual := loadJSON[[]classify.IssueLabel](rf)
 		modelName := strings.TrimPrefix(filepath.Base(rf), "issues-")
 		modelName = strings.TrimSuffix(modelName, ".json")
 
@@ -236,15 +249,15 @@ func scoreIssues(dir string) {
 }
 
 func scoreMessages(dir string) {
-	expected := loadJSON[[]MessageLabel](filepath.Join(dir, "expected", "messages.json"))
-	expectedMap := make(map[string]MessageLabel)
+	expected := loadJSON[[]classify.MessageLabel](filepath.Join(dir, "expected", "messages.json"))
+	expectedMap := make(map[string]classify.MessageLabel)
 	for _, e := range expected {
 		expectedMap[e.ID] = e
 	}
 
 	resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", "messages-*.json"))
 	for _, rf := range resultFiles {
-		actual := loadJSON[[]MessageLabel](rf)
+		actual := loadJSON[[]classify.MessageLabel](rf)
 		modelName := strings.TrimPrefix(filepath.Base(rf), "messages-")
 		modelName = strings.TrimSuffix(modelName, ".json")
 
@@ -272,8 +285,14 @@ func scoreMessages(dir string) {
 	}
 }
 
-func generateReport(dir string) {
-	// Collect all result files and build benchmark results
+func generateReport(dir string, prices *pricing.Table) {
+	results := prices.Apply(bench.WithModelInfo(dir, benchmarkResults(dir)))
+	fmt.Print(reporting.GenerateReport(results))
+	fmt.Print(reporting.GenerateComparison(results))
+}
+
+// benchmarkResults scores every result file against the expected labels.
+func benchmarkResults(dir string) []types.BenchmarkResult {
 	var results []types.BenchmarkResult
 
 	issueFiles, _ := filepath.Glob(filepath.Join(dir, "results", "issues-*.json"))
@@ -281,14 +300,16 @@ func generateReport(dir string) {
 		modelName := strings.TrimPrefix(filepath.Base(rf), "issues-")
 		modelName = strings.TrimSuffix(modelName, ".json")
 
-		expected := loadJSON[[]IssueLabel](filepath.Join(dir, "expected", "issues.json"))
-		actual := loadJSON[[]IssueLabel](rf)
+		expected := loadJSON[[]classify.IssueLabel](filepath.Join(dir, "expected", "issues.json"))
+		actual := loadJSON[[]classify.IssueLabel](rf)
 
-		expectedMap := make(map[string]IssueLabel)
+		expectedMap := make(map[string]classify.IssueLabel)
 		for _, e := range expected {
 			expectedMap[e.ID] = e
 		}
 		var catPred, catLabel, priPred, priLabel []string
+		var checks, valid, jsonChecks, jsonRaw, jsonRepaired int
+		var metas []types.ModelMetadata
 		for _, a := range actual {
 			if e, ok := expectedMap[a.ID]; ok {
 				catPred = append(catPred, a.Category)
@@ -296,14 +317,25 @@ func generateReport(dir string) {
 				priPred = append(priPred, a.Priority)
 				priLabel = append(priLabel, e.Priority)
 			}
+			countValid(a.SchemaValid, &checks, &valid)
+			c, raw, repaired := a.JSON.Counts()
+			jsonChecks, jsonRaw, jsonRepaired = jsonChecks+c, jsonRaw+raw, jsonRepaired+repaired
+			if a.Meta != nil {
+				metas = append(metas, *a.Meta)
+			}
 		}
 		combined := combinedAccuracy(catPred, catLabel, priPred, priLabel)
-		results = append(results, types.BenchmarkResult{
-			Example:     "Issue Triage",
-			Model:       modelName,
-			Quality:     combined,
-			QualityName: "Combined Acc",
-		})
+		results = append(results, withMeta(types.BenchmarkResult{
+			Example:      "Issue Triage",
+			Model:        modelName,
+			Quality:      combined,
+			QualityName:  "Combined Acc",
+			SchemaChecks: checks,
+			SchemaValid:  valid,
+			JSONChecks:   jsonChecks,
+			JSONRaw:      jsonRaw,
+			JSONRepaired: jsonRepaired,
+		}, metas))
 	}
 
 	msgFiles, _ := filepath.Glob(filepath.Join(dir, "results", "messages-*.json"))
@@ -311,63 +343,76 @@ func generateReport(dir string) {
 		modelName := strings.TrimPrefix(filepath.Base(rf), "messages-")
 		modelName = strings.TrimSuffix(modelName, ".json")
 
-		expected := loadJSON[[]MessageLabel](filepath.Join(dir, "expected", "messages.json"))
-		actual := loadJSON[[]MessageLabel](rf)
+		expected := loadJSON[[]classify.MessageLabel](filepath.Join(dir, "expected", "messages.json"))
+		actual := loadJSON[[]classify.MessageLabel](rf)
 
-		expectedMap := make(map[string]MessageLabel)
+		expectedMap := make(map[string]classify.MessageLabel)
 		for _, e := range expected {
 			expectedMap[e.ID] = e
 		}
 		var intentPred, intentLabel []string
+		var checks, valid, jsonChecks, jsonRaw, jsonRepaired int
+		var metas []types.ModelMetadata
 		for _, a := range actual {
 			if e, ok := expectedMap[a.ID]; ok {
 				intentPred = append(intentPred, a.Intent)
 				intentLabel = append(intentLabel, e.Intent)
 			}
+			countValid(a.SchemaValid, &checks, &valid)
+			c, raw, repaired := a.JSON.Counts()
+			jsonChecks, jsonRaw, jsonRepaired = jsonChecks+c, jsonRaw+raw, jsonRepaired+repaired
+			if a.Meta != nil {
+				metas = append(metas, *a.Meta)
+			}
 		}
 		intentAcc, _ := scoring.AccuracyScore(intentPred, intentLabel)
-		results = append(results, types.BenchmarkResult{
-			Example:     "Intent Detection",
-			Model:       modelName,
-			Quality:     intentAcc,
-			QualityName: "Intent Acc",
-		})
+		results = append(results, withMeta(types.BenchmarkResult{
+			Example:      "Intent Detection",
+			Model:        modelName,
+			Quality:      intentAcc,
+			QualityName:  "Intent Acc",
+			SchemaChecks: checks,
+			SchemaValid:  valid,
+			JSONChecks:   jsonChecks,
+			JSONRaw:      jsonRaw,
+			JSONRepaired: jsonRepaired,
+		}, metas))
 	}
 
-	report := reporting.GenerateReport(results)
-	fmt.Print(report)
+	return results
 }
 
 // --- Helpers ---
 
 func loadJSON[T any](path string) T {
 	var v T
-	data, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatalf("Failed to read %s: %v", path, err)
-	}
-	if err := json.Unmarshal(data, &v); err != nil {
-		log.Fatalf("Failed to parse %s: %v", path, err)
+	if err := bench.LoadJSON(path, &v); err != nil {
+		log.Fatal(err)
 	}
 	return v
 }
 
-func writeJSON(path string, v any) {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		log.Fatalf("Failed to create directory for %s: %v", path, err)
-	}
-	data, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		log.Fatalf("Failed to marshal JSON: %v", err)
+// countValid tallies a recorded schema check; results written before schema
+// validation existed have no flag and are skipped.
+func countValid(flag *bool, checks, valid *int) {
+	if flag == nil {
+		return
 	}
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		log.Fatalf("Failed to write %s: %v", path, err)
+	*checks++
+	if *flag {
+		*valid++
 	}
 }
 
-func sanitizeModelName(model string) string {
-	r := strings.NewReplacer("/", "-", ":", "-", " ", "-")
-	return r.Replace(model)
+// withMeta fills r's performance columns with the mean of the per-call
+// metadata recorded in a result file.
+func withMeta(r types.BenchmarkResult, metas []types.ModelMetadata) types.BenchmarkResult {
+	m := types.MeanMetadata(metas)
+	r.TokensIn, r.TokensOut = m.TokensIn, m.TokensOut
+	r.TTFT, r.TotalTime = m.TTFT, m.TotalTime
+	r.TokensPerSec, r.InterTokenLatency = m.TokensPerSec, m.InterTokenLatency
+	r.Retries = m.Retries
+	return r
 }
 
 func countMatches(pred, label []string) int {
@@ -0,0 +1,263 @@
+// Package convert holds the format-conversion example's scenarios, prompt
+// rendering and scoring, shared by the example's main and the llmbench CLI.
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/statherm/local-llm-examples/shared/manifest"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+)
+
+// Scenario is a conversion test case from the example's manifest
+// (scenarios.json). Input, Expected and Prompt are files relative to the
+// example directory.
+type Scenario struct {
+	manifest.Scenario
+}
+
+// Scorer names. FieldMatch, the default, compares JSON arrays field by
+// field; YAMLF1 compares YAML key-value pairs.
+const (
+	FieldMatch = "field-match"
+	YAMLF1     = "yaml-f1"
+)
+
+// Scorers maps the scorer names a manifest may use to their functions.
+var Scorers = map[string]func(expected, actual string) float64{
+	FieldMatch: ScoreJSONArray,
+	YAMLF1:     ScoreYAMLConfig,
+}
+
+// defaultMaxTokens caps responses for scenarios without max_tokens.
+const defaultMaxTokens = 2048
+
+// LoadScenarios reads the manifest in dir.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	entries, err := manifest.Load(dir, FieldMatch, YAMLF1)
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]Scenario, len(entries))
+	for i, e := range entries {
+		scenarios[i] = Scenario{e}
+	}
+	return scenarios, nil
+}
+
+// Find returns the scenario called name.
+func Find(scenarios []Scenario, name string) (Scenario, bool) {
+	for _, s := range scenarios {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Scenario{}, false
+}
+
+// Fixture is a scenario's input, rendered prompt and expected output.
+type Fixture struct {
+	Input    string
+	Prompt   string
+	Expected string
+}
+
+// Load reads the scenario's files from dir and renders its prompt.
+func (s Scenario) Load(dir string) (Fixture, error) {
+	input, err := os.ReadFile(filepath.Join(dir, s.Input))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("reading input: %w", err)
+	}
+
+	promptTmpl, err := os.ReadFile(filepath.Join(dir, s.Prompt))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("reading prompt: %w", err)
+	}
+
+	prompt, err := RenderPrompt(string(promptTmpl), string(input))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("rendering prompt: %w", err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join(dir, s.Expected))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("reading expected: %w", err)
+	}
+
+	return Fixture{Input: string(input), Prompt: prompt, Expected: string(expected)}, nil
+}
+
+// System returns the scenario's system prompt. It reinforces array output
+// for JSON mode scenarios; small models (qwen2.5:3b) often stop after one
+// JSON object without it.
+func (s Scenario) System() string {
+	if s.JSONMode {
+		return "You respond only with valid JSON. When the user asks for multiple items, you MUST return a JSON array containing ALL items. Do not stop after the first item."
+	}
+	return ""
+}
+
+// MaxTokens returns the scenario's response cap.
+func (s Scenario) MaxTokens() int {
+	if s.Scenario.MaxTokens > 0 {
+		return s.Scenario.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// Score rates actual against expected with the scenario's scorer. A
+// scenario not loaded from a manifest scores 0.
+func (s Scenario) Score(expected, actual string) float64 {
+	score, ok := Scorers[s.Scorer]
+	if !ok {
+		return 0
+	}
+	return score(expected, actual)
+}
+
+// QualityName names the metric Score reports.
+func (s Scenario) QualityName() string {
+	if s.Scorer == "" {
+		return FieldMatch
+	}
+	return s.Scorer
+}
+
+// Schema returns the structure expected of JSON output: a bare array whose
+// objects carry every expected field with the expected type. The schema is
+// inferred from the expected fixture, so wrapped or truncated arrays that
+// ScoreJSONArray recovers from still fail it. It returns nil for scenarios
+// not scored by field match.
+func (s Scenario) Schema(expected string) *schema.Schema {
+	if s.Scorer != FieldMatch {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(expected), &v); err != nil {
+		return nil
+	}
+	return schema.Infer(v)
+}
+
+// ScoreJSONArray compares two JSON arrays element by element using field matching.
+// It handles cases where the model wraps the array in an object (e.g. {"events": [...]}).
+func ScoreJSONArray(expected, actual string) float64 {
+	var expArr []json.RawMessage
+	var actArr []json.RawMessage
+
+	if err := json.Unmarshal([]byte(strings.TrimSpace(expected)), &expArr); err != nil {
+		return 0
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(actual)), &actArr); err != nil {
+		// Try unwrapping from a wrapper object like {"events": [...], "data": [...]}
+		actArr = unwrapJSONArray(actual)
+		if actArr == nil {
+			// Try treating as a single bare object → 1-element array
+			var obj json.RawMessage
+			if err := json.Unmarshal([]byte(strings.TrimSpace(actual)), &obj); err == nil {
+				// Verify it's an object, not something else
+				var m map[string]json.RawMessage
+				if json.Unmarshal(obj, &m) == nil {
+					actArr = []json.RawMessage{obj}
+				}
+			}
+			if actArr == nil {
+				return 0
+			}
+		}
+	}
+
+	if len(expArr) == 0 {
+		return 0
+	}
+
+	var totalMatched, totalFields int
+	limit := len(expArr)
+	if len(actArr) < limit {
+		limit = len(actArr)
+	}
+
+	for i := 0; i < limit; i++ {
+		matched, total, _ := scoring.JSONFieldMatch(expArr[i], actArr[i])
+		totalMatched += matched
+		totalFields += total
+	}
+	// Count missing rows as unmatched fields
+	for i := limit; i < len(expArr); i++ {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(expArr[i], &obj); err == nil {
+			totalFields += len(obj)
+		}
+	}
+
+	if totalFields == 0 {
+		return 0
+	}
+	return float64(totalMatched) / float64(totalFields)
+}
+
+// unwrapJSONArray extracts a JSON array from a wrapper object. Models sometimes
+// return {"events": [...]} or {"data": [...]} instead of a bare array.
+func unwrapJSONArray(s string) []json.RawMessage {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &wrapper); err != nil {
+		return nil
+	}
+	// Find the first value that's an array
+	for _, v := range wrapper {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(v, &arr); err == nil {
+			return arr
+		}
+	}
+	return nil
+}
+
+// ScoreYAMLConfig does a simple key-value token overlap between expected and actual YAML.
+func ScoreYAMLConfig(expected, actual string) float64 {
+	expTokens := extractKeyValues(expected)
+	actTokens := extractKeyValues(actual)
+	return scoring.F1Score(expTokens, actTokens)
+}
+
+// extractKeyValues pulls out "key: value" pairs from YAML-like text.
+func extractKeyValues(s string) []string {
+	var pairs []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			// Include list items as values
+			if strings.HasPrefix(line, "-") {
+				pairs = append(pairs, strings.TrimSpace(strings.TrimPrefix(line, "-")))
+			}
+			continue
+		}
+		if idx := strings.Index(line, ":"); idx > 0 {
+			key := strings.TrimSpace(line[:idx])
+			val := strings.TrimSpace(line[idx+1:])
+			if val != "" {
+				pairs = append(pairs, key+":"+val)
+			} else {
+				pairs = append(pairs, key)
+			}
+		}
+	}
+	return pairs
+}
+
+// RenderPrompt executes a prompt template with input as {{.Input}}.
+func RenderPrompt(tmpl, input string) (string, error) {
+	t, err := template.New("prompt").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	err = t.Execute(&sb, struct{ Input string }{Input: input})
+	return sb.String(), err
+}
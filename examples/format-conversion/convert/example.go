@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"fmt"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs the conversion scenarios through the bench interface, grouped
+// by category, each scored by the scorer its manifest entry names.
+type Example struct{}
+
+type conversionCase struct {
+	Scenario Scenario
+	Fixture  Fixture
+}
+
+func (Example) Name() string { return "format-conversion" }
+
+func (Example) Description() string {
+	return "Convert Markdown tables, logs, CSV and prose into JSON or YAML"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	scenarios, err := LoadScenarios(dir)
+	if err != nil {
+		return nil, err
+	}
+	cases := make([]bench.Case, 0, len(scenarios))
+	for _, s := range scenarios {
+		fx, err := s.Load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		cases = append(cases, bench.Case{ID: s.Name, Scenario: s.Category, Data: conversionCase{s, fx}})
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	d := c.Data.(conversionCase)
+	return ollama.NewChatRequest("", d.Scenario.System(), d.Fixture.Prompt, d.Scenario.JSONMode, d.Scenario.MaxTokens())
+}
+
+// Parse passes the raw output through; both scorers parse leniently.
+func (Example) Parse(_ bench.Case, raw string) (any, error) {
+	return raw, nil
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	d := c.Data.(conversionCase)
+	return bench.Score{
+		Quality: d.Scenario.Score(d.Fixture.Expected, parsed.(string)),
+		Metric:  d.Scenario.QualityName(),
+	}
+}
+
+func (Example) Schema(c bench.Case) *schema.Schema {
+	d := c.Data.(conversionCase)
+	return d.Scenario.Schema(d.Fixture.Expected)
+}
@@ -1,114 +1,63 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
-	"text/template"
 
-	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/examples/format-conversion/convert"
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
 	"github.com/statherm/local-llm-examples/shared/reporting"
-	"github.com/statherm/local-llm-examples/shared/scoring"
+	"github.com/statherm/local-llm-examples/shared/runner"
+	"github.com/statherm/local-llm-examples/shared/sweep"
 	"github.com/statherm/local-llm-examples/shared/types"
 )
 
 var (
-	model      = flag.String("model", "qwen3:4b", "Ollama model to use")
-	scoreOnly  = flag.Bool("score", false, "Score existing results without running the model")
-	reportOnly = flag.Bool("report", false, "Generate a report from existing results")
+	model       = flag.String("model", "qwen3:4b", "Ollama model to use")
+	modelList   = flag.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile  = flag.String("models-file", "", "File listing models to run and compare, one per line")
+	scoreOnly   = flag.Bool("score", false, "Score existing results without running the model")
+	reportOnly  = flag.Bool("report", false, "Generate a report from existing results")
+	parallel    = flag.Int("parallel", 1, "Number of concurrent model requests")
+	conn        = provider.AddFlags(flag.CommandLine)
+	pricingFile = flag.String("pricing", "", "Pricing table for the Cost/Call column (default: pricing.json in this or a parent directory)")
 )
 
-type scenario struct {
-	Name         string
-	Category     string // markdown-to-json, log-to-structured, nl-to-yaml, csv-to-json
-	InputFile    string
-	ExpectedFile string
-	PromptFile   string
-	JSONMode     bool
-}
-
 type result struct {
-	Scenario string             `json:"scenario"`
-	Model    string             `json:"model"`
-	Input    string             `json:"input"`
-	Output   string             `json:"output"`
-	Expected string             `json:"expected"`
+	Scenario string              `json:"scenario"`
+	Model    string              `json:"model"`
+	Input    string              `json:"input"`
+	Output   string              `json:"output"`
+	Expected string              `json:"expected"`
 	Meta     types.ModelMetadata `json:"metadata"`
+	// Error is why the model call failed, after any retries. A failed
+	// scenario is saved with no output and scores zero.
+	Error string `json:"error,omitempty"`
 }
 
 func main() {
 	flag.Parse()
 
-	scenarios := []scenario{
-		{
-			Name:         "markdown-packages",
-			Category:     "markdown-to-json",
-			InputFile:    "testdata/001-markdown-table.md",
-			ExpectedFile: "expected/001-markdown.json",
-			PromptFile:   "prompts/markdown-to-json.txt",
-			JSONMode:     true,
-		},
-		{
-			Name:         "markdown-services",
-			Category:     "markdown-to-json",
-			InputFile:    "testdata/002-markdown-table.md",
-			ExpectedFile: "expected/002-markdown.json",
-			PromptFile:   "prompts/markdown-to-json.txt",
-			JSONMode:     true,
-		},
-		{
-			Name:         "log-structured-app",
-			Category:     "log-to-structured",
-			InputFile:    "testdata/003-log-lines.txt",
-			ExpectedFile: "expected/003-log.json",
-			PromptFile:   "prompts/log-to-structured.txt",
-			JSONMode:     true,
-		},
-		{
-			Name:         "log-structured-nginx",
-			Category:     "log-to-structured",
-			InputFile:    "testdata/004-log-lines.txt",
-			ExpectedFile: "expected/004-log.json",
-			PromptFile:   "prompts/log-to-structured.txt",
-			JSONMode:     true,
-		},
-		{
-			Name:         "nl-config-server",
-			Category:     "nl-to-yaml",
-			InputFile:    "testdata/005-nl-config.txt",
-			ExpectedFile: "expected/005-config.yaml",
-			PromptFile:   "prompts/nl-to-yaml.txt",
-		},
-		{
-			Name:         "nl-config-redis",
-			Category:     "nl-to-yaml",
-			InputFile:    "testdata/006-nl-config.txt",
-			ExpectedFile: "expected/006-config.yaml",
-			PromptFile:   "prompts/nl-to-yaml.txt",
-		},
-		{
-			Name:         "csv-users",
-			Category:     "csv-to-json",
-			InputFile:    "testdata/007-csv-data.csv",
-			ExpectedFile: "expected/007-csv.json",
-			PromptFile:   "prompts/csv-to-json.txt",
-			JSONMode:     true,
-		},
-		{
-			Name:         "csv-products",
-			Category:     "csv-to-json",
-			InputFile:    "testdata/008-csv-data.csv",
-			ExpectedFile: "expected/008-csv.json",
-			PromptFile:   "prompts/csv-to-json.txt",
-			JSONMode:     true,
-		},
+	scenarios, err := convert.LoadScenarios(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	prices, err := pricing.Load(*pricingFile, ".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
 	}
 
 	if *reportOnly {
-		generateReport(scenarios)
+		generateReport(scenarios, prices)
 		return
 	}
 
@@ -117,84 +66,93 @@ func main() {
 		return
 	}
 
-	runScenarios(scenarios)
-}
-
-func runScenarios(scenarios []scenario) {
-	client := ollama.NewClient()
-	var results []result
-
-	for _, s := range scenarios {
-		fmt.Printf("Running: %s\n", s.Name)
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
 
-		input, err := os.ReadFile(s.InputFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR reading input: %v\n", err)
-			continue
+	client, err := conn.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	for _, m := range models {
+		if err := bench.Preflight(context.Background(), client, m, conn.Pull, "."); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
 		}
+	}
 
-		promptTmpl, err := os.ReadFile(s.PromptFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR reading prompt: %v\n", err)
-			continue
-		}
+	for _, m := range models {
+		runScenarios(client, scenarios, m)
+	}
 
-		prompt, err := renderPrompt(string(promptTmpl), string(input))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR rendering prompt: %v\n", err)
-			continue
-		}
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(prices.Apply(sweep.Only(bench.WithModelInfo(".", benchmarkResults(scenarios)), models, nil))))
+	}
+}
 
-		expected, err := os.ReadFile(s.ExpectedFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR reading expected: %v\n", err)
-			continue
-		}
+func runScenarios(client provider.Provider, scenarios []convert.Scenario, model string) {
+	fmt.Printf("=== Model: %s ===\n", model)
 
-		// System prompt reinforces array output for JSON mode scenarios.
-		// Small models (qwen2.5:3b) often stop after one JSON object without this.
-		var sysPrompt string
-		if s.JSONMode {
-			sysPrompt = "You respond only with valid JSON. When the user asks for multiple items, you MUST return a JSON array containing ALL items. Do not stop after the first item."
-		}
-		output, meta, err := client.ChatCompletion(*model, sysPrompt, prompt, s.JSONMode, 2048)
+	runs, summary := runner.Run(context.Background(), scenarios, *parallel, func(_ context.Context, _ int, s convert.Scenario) (result, error) {
+		r, err := runScenario(client, model, s)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "  ERROR from model: %v\n", err)
-			continue
+			fmt.Fprintf(os.Stderr, "%s: ERROR %v\n", s.Name, err)
+			return r, err
 		}
+		fmt.Printf("Ran: %s\n  Model: %s | Tokens: %d in, %d out | %.1f tok/s | %v\n",
+			s.Name, r.Meta.Model, r.Meta.TokensIn, r.Meta.TokensOut, r.Meta.TokensPerSec, r.Meta.TotalTime)
+		return r, nil
+	})
 
-		r := result{
-			Scenario: s.Name,
-			Model:    *model,
-			Input:    string(input),
-			Output:   output,
-			Expected: string(expected),
-			Meta:     meta,
+	var results []result
+	var totalTokensOut int
+	for _, run := range runs {
+		if run.Err != nil && run.Value.Error == "" {
+			continue
 		}
-		results = append(results, r)
+		run.Value.Meta.QueueTime = run.Queue
+		results = append(results, run.Value)
+		totalTokensOut += run.Value.Meta.TokensOut
+	}
+	fmt.Printf("\n%s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(totalTokensOut))
 
-		fmt.Printf("  Model: %s | Tokens: %d in, %d out | %.1f tok/s | %v\n",
-			meta.Model, meta.TokensIn, meta.TokensOut, meta.TokensPerSec, meta.TotalTime)
+	resultsFile := filepath.Join("results", model+".json")
+	if err := bench.WriteJSON(resultsFile, results); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
 	}
+	fmt.Printf("\nResults saved to %s\n", resultsFile)
+}
 
-	resultsFile := filepath.Join("results", *model+".json")
-	data, err := json.MarshalIndent(results, "", "  ")
+// runScenario renders the prompt for one scenario and runs it against the model.
+func runScenario(client provider.Provider, model string, s convert.Scenario) (result, error) {
+	fx, err := s.Load(".")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR marshaling results: %v\n", err)
-		os.Exit(1)
+		return result{}, err
 	}
-	if err := os.MkdirAll("results", 0o755); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR creating results dir: %v\n", err)
-		os.Exit(1)
+
+	output, meta, err := client.ChatCompletion(model, s.System(), fx.Prompt, s.JSONMode, s.MaxTokens())
+	r := result{
+		Scenario: s.Name,
+		Model:    model,
+		Input:    fx.Input,
+		Output:   output,
+		Expected: fx.Expected,
+		Meta:     meta,
 	}
-	if err := os.WriteFile(resultsFile, data, 0o644); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR writing results: %v\n", err)
-		os.Exit(1)
+	if err != nil {
+		// Saved with its retries so the scenario still counts against the
+		// model in the report.
+		r.Error = err.Error()
+		return r, fmt.Errorf("from model: %w", err)
 	}
-	fmt.Printf("\nResults saved to %s\n", resultsFile)
+	return r, nil
 }
 
-func scoreResults(scenarios []scenario) {
+func scoreResults(scenarios []convert.Scenario) {
 	files, err := filepath.Glob("results/*.json")
 	if err != nil || len(files) == 0 {
 		fmt.Println("No result files found in results/")
@@ -216,153 +174,54 @@ func scoreResults(scenarios []scenario) {
 
 		fmt.Printf("=== Scores for %s ===\n", filepath.Base(f))
 		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("  %-25s  quality=0.000  error=%s\n", r.Scenario, r.Error)
+				continue
+			}
 			sc := scoreScenario(r, scenarios)
+			if checked, valid := schemaValid(r, scenarios); checked {
+				fmt.Printf("  %-25s  quality=%.3f  schema_valid=%v\n", r.Scenario, sc, valid)
+				continue
+			}
 			fmt.Printf("  %-25s  quality=%.3f\n", r.Scenario, sc)
 		}
 	}
 }
 
-func scoreScenario(r result, scenarios []scenario) float64 {
-	var cat string
-	for _, s := range scenarios {
-		if s.Name == r.Scenario {
-			cat = s.Category
-			break
-		}
-	}
-
-	switch cat {
-	case "markdown-to-json", "log-to-structured", "csv-to-json":
-		return scoreJSONArray(r.Expected, r.Output)
-	case "nl-to-yaml":
-		return scoreYAMLConfig(r.Expected, r.Output)
-	default:
+func scoreScenario(r result, scenarios []convert.Scenario) float64 {
+	if r.Error != "" {
 		return 0
 	}
+	s, _ := convert.Find(scenarios, r.Scenario)
+	return s.Score(r.Expected, r.Output)
 }
 
-// scoreJSONArray compares two JSON arrays element by element using field matching.
-// It handles cases where the model wraps the array in an object (e.g. {"events": [...]}).
-func scoreJSONArray(expected, actual string) float64 {
-	var expArr []json.RawMessage
-	var actArr []json.RawMessage
-
-	if err := json.Unmarshal([]byte(strings.TrimSpace(expected)), &expArr); err != nil {
-		return 0
-	}
-	if err := json.Unmarshal([]byte(strings.TrimSpace(actual)), &actArr); err != nil {
-		// Try unwrapping from a wrapper object like {"events": [...], "data": [...]}
-		actArr = unwrapJSONArray(actual)
-		if actArr == nil {
-			// Try treating as a single bare object → 1-element array
-			var obj json.RawMessage
-			if err := json.Unmarshal([]byte(strings.TrimSpace(actual)), &obj); err == nil {
-				// Verify it's an object, not something else
-				var m map[string]json.RawMessage
-				if json.Unmarshal(obj, &m) == nil {
-					actArr = []json.RawMessage{obj}
-				}
-			}
-			if actArr == nil {
-				return 0
-			}
-		}
-	}
-
-	if len(expArr) == 0 {
-		return 0
-	}
-
-	var totalMatched, totalFields int
-	limit := len(expArr)
-	if len(actArr) < limit {
-		limit = len(actArr)
-	}
-
-	for i := 0; i < limit; i++ {
-		matched, total, _ := scoring.JSONFieldMatch(expArr[i], actArr[i])
-		totalMatched += matched
-		totalFields += total
-	}
-	// Count missing rows as unmatched fields
-	for i := limit; i < len(expArr); i++ {
-		var obj map[string]json.RawMessage
-		if err := json.Unmarshal(expArr[i], &obj); err == nil {
-			totalFields += len(obj)
-		}
-	}
-
-	if totalFields == 0 {
-		return 0
+// schemaValid reports whether the scenario is scored by field match and, if
+// so, whether the raw model output has the structure of the expected output
+// (see convert.Scenario.Schema). A failed call has no output to check.
+func schemaValid(r result, scenarios []convert.Scenario) (checked, valid bool) {
+	s, _ := convert.Find(scenarios, r.Scenario)
+	sc := s.Schema(r.Expected)
+	if sc == nil || r.Error != "" {
+		return false, false
 	}
-	return float64(totalMatched) / float64(totalFields)
+	return true, len(sc.ValidateJSON([]byte(r.Output))) == 0
 }
 
-// unwrapJSONArray extracts a JSON array from a wrapper object. Models sometimes
-// return {"events": [...]} or {"data": [...]} instead of a bare array.
-func unwrapJSONArray(s string) []json.RawMessage {
-	var wrapper map[string]json.RawMessage
-	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &wrapper); err != nil {
-		return nil
-	}
-	// Find the first value that's an array
-	for _, v := range wrapper {
-		var arr []json.RawMessage
-		if err := json.Unmarshal(v, &arr); err == nil {
-			return arr
-		}
-	}
-	return nil
-}
-
-// scoreYAMLConfig does a simple key-value token overlap between expected and actual YAML.
-func scoreYAMLConfig(expected, actual string) float64 {
-	expTokens := extractKeyValues(expected)
-	actTokens := extractKeyValues(actual)
-	return scoring.F1Score(expTokens, actTokens)
-}
-
-// extractKeyValues pulls out "key: value" pairs from YAML-like text.
-func extractKeyValues(s string) []string {
-	var pairs []string
-	for _, line := range strings.Split(s, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
-			// Include list items as values
-			if strings.HasPrefix(line, "-") {
-				pairs = append(pairs, strings.TrimSpace(strings.TrimPrefix(line, "-")))
-			}
-			continue
-		}
-		if idx := strings.Index(line, ":"); idx > 0 {
-			key := strings.TrimSpace(line[:idx])
-			val := strings.TrimSpace(line[idx+1:])
-			if val != "" {
-				pairs = append(pairs, key+":"+val)
-			} else {
-				pairs = append(pairs, key)
-			}
-		}
+func generateReport(scenarios []convert.Scenario, prices *pricing.Table) {
+	benchmarks := prices.Apply(bench.WithModelInfo(".", benchmarkResults(scenarios)))
+	if len(benchmarks) == 0 {
+		fmt.Println("No result files found in results/")
+		return
 	}
-	return pairs
-}
 
-func renderPrompt(tmpl, input string) (string, error) {
-	t, err := template.New("prompt").Parse(tmpl)
-	if err != nil {
-		return "", err
-	}
-	var sb strings.Builder
-	err = t.Execute(&sb, struct{ Input string }{Input: input})
-	return sb.String(), err
+	fmt.Print(reporting.GenerateReport(benchmarks))
+	fmt.Print(reporting.GenerateComparison(benchmarks))
 }
 
-func generateReport(scenarios []scenario) {
-	files, err := filepath.Glob("results/*.json")
-	if err != nil || len(files) == 0 {
-		fmt.Println("No result files found in results/")
-		return
-	}
+// benchmarkResults scores every scenario in every results file.
+func benchmarkResults(scenarios []convert.Scenario) []types.BenchmarkResult {
+	files, _ := filepath.Glob("results/*.json")
 
 	var benchmarks []types.BenchmarkResult
 	for _, f := range files {
@@ -375,24 +234,32 @@ func generateReport(scenarios []scenario) {
 			continue
 		}
 		for _, r := range results {
-			qName := "field-match"
-			if strings.Contains(r.Scenario, "config") {
-				qName = "F1"
+			s, _ := convert.Find(scenarios, r.Scenario)
+			var checks, valid int
+			if checked, ok := schemaValid(r, scenarios); checked {
+				checks = 1
+				if ok {
+					valid = 1
+				}
 			}
 			benchmarks = append(benchmarks, types.BenchmarkResult{
-				Example:      r.Scenario,
-				Model:        r.Model,
-				Quality:      scoreScenario(r, scenarios),
-				QualityName:  qName,
-				TokensIn:     r.Meta.TokensIn,
-				TokensOut:    r.Meta.TokensOut,
-				TTFT:         r.Meta.TTFT,
-				TotalTime:    r.Meta.TotalTime,
-				TokensPerSec: r.Meta.TokensPerSec,
+				Example:           r.Scenario,
+				Model:             r.Model,
+				Quality:           scoreScenario(r, scenarios),
+				QualityName:       s.QualityName(),
+				TokensIn:          r.Meta.TokensIn,
+				TokensOut:         r.Meta.TokensOut,
+				TTFT:              r.Meta.TTFT,
+				TotalTime:         r.Meta.TotalTime,
+				TokensPerSec:      r.Meta.TokensPerSec,
+				InterTokenLatency: r.Meta.InterTokenLatency,
+				SchemaChecks:      checks,
+				SchemaValid:       valid,
+				Retries:           r.Meta.Retries,
+				Error:             r.Error,
 			})
 		}
 	}
 
-	report := reporting.GenerateReport(benchmarks)
-	fmt.Print(report)
+	return benchmarks
 }
@@ -1,88 +1,42 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/statherm/local-llm-examples/examples/function-calling/toolcall"
+	"github.com/statherm/local-llm-examples/shared/bench"
 	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
 	"github.com/statherm/local-llm-examples/shared/reporting"
+	"github.com/statherm/local-llm-examples/shared/runner"
+	"github.com/statherm/local-llm-examples/shared/sweep"
 	"github.com/statherm/local-llm-examples/shared/types"
 )
 
-// --- Data types ---
-
-type ToolDef struct {
-	Name        string                       `json:"name"`
-	Description string                       `json:"description"`
-	Parameters  map[string]ToolParam         `json:"parameters"`
-	Required    []string                     `json:"required"`
-}
-
-type ToolParam struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-}
-
-type TestCase struct {
-	ID      string `json:"id"`
-	Request string `json:"request"`
-}
-
-type ExpectedCall struct {
-	ID         string         `json:"id"`
-	Tool       string         `json:"tool"`
-	Parameters map[string]any `json:"parameters"`
-}
-
-type ActualCall struct {
-	ID         string         `json:"id"`
-	Tool       string         `json:"tool"`
-	Parameters map[string]any `json:"parameters"`
-	RawOutput  string         `json:"raw_output,omitempty"`
-}
-
-// --- System prompt builder ---
-
-func buildSystemPrompt(tools []ToolDef) string {
-	var sb strings.Builder
-	sb.WriteString("You are a function calling assistant. Given a user request, select the most appropriate tool and provide the correct parameters.\n\n")
-	sb.WriteString("Available tools:\n\n")
-
-	for _, t := range tools {
-		sb.WriteString(fmt.Sprintf("### %s\n%s\n", t.Name, t.Description))
-		if len(t.Parameters) > 0 {
-			sb.WriteString("Parameters:\n")
-			for name, param := range t.Parameters {
-				req := ""
-				for _, r := range t.Required {
-					if r == name {
-						req = " (required)"
-						break
-					}
-				}
-				sb.WriteString(fmt.Sprintf("  - %s (%s): %s%s\n", name, param.Type, param.Description, req))
-			}
-		}
-		sb.WriteString("\n")
-	}
-
-	sb.WriteString("Respond with JSON only: {\"tool\": \"tool_name\", \"parameters\": {...}}\n")
-	sb.WriteString("Only include parameters that are relevant to the request. Use the exact tool names shown above.")
-
-	return sb.String()
-}
-
 func main() {
 	model := flag.String("model", "qwen3:4b", "Ollama model to use")
-	scenario := flag.String("scenario", "all", "Scenario: developer, home, or all")
+	modelList := flag.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile := flag.String("models-file", "", "File listing models to run and compare, one per line")
+	scenario := flag.String("scenario", "all", "Scenario: developer, home, chains, or all")
 	scoreOnly := flag.Bool("score", false, "Score existing results")
 	reportOnly := flag.Bool("report", false, "Generate report from existing results")
+	parallel := flag.Int("parallel", 1, "Number of concurrent model requests")
+	conn := provider.AddFlags(flag.CommandLine)
+	pricingFile := flag.String("pricing", "", "Pricing table for the Cost/Call column (default: pricing.json in this or a parent directory)")
+	reasks := flag.Int("reask", 0, "Times to re-ask the model with the parse error when its JSON cannot be repaired")
+	mode := flag.String("mode", toolcall.ModePrompt, "How tools are offered: prompt (catalog in the system prompt, JSON reply) or native (the chat API's tools field)")
+	maxSteps := flag.Int("max-steps", toolcall.DefaultMaxSteps, "Most tool calls the model may make in one chain")
 	flag.Parse()
 
 	exampleDir := filepath.Dir(os.Args[0])
@@ -90,68 +44,244 @@ func main() {
 		exampleDir = abs
 	}
 
+	prices, err := pricing.Load(*pricingFile, exampleDir)
+	if err != nil {
+		log.Fatalf("Failed to load pricing: %v", err)
+	}
+
 	if *scoreOnly {
 		scoreResults(exampleDir, *scenario)
 		return
 	}
 	if *reportOnly {
-		generateReport(exampleDir)
+		generateReport(exampleDir, prices)
 		return
 	}
 
-	client := ollama.NewClient()
+	if *maxSteps < 1 {
+		log.Fatalf("Invalid -max-steps %d (want at least 1)", *maxSteps)
+	}
 
-	if *scenario == "all" || *scenario == "developer" {
-		runScenario(client, *model, exampleDir, "developer")
+	switch *mode {
+	case toolcall.ModePrompt:
+	case toolcall.ModeNative:
+		if *scenario == toolcall.ChainScenario {
+			log.Fatalf("The %s scenario supports only -mode %s", toolcall.ChainScenario, toolcall.ModePrompt)
+		}
+	default:
+		log.Fatalf("Unknown -mode %q (want %s or %s)", *mode, toolcall.ModePrompt, toolcall.ModeNative)
 	}
-	if *scenario == "all" || *scenario == "home" {
-		runScenario(client, *model, exampleDir, "home-automation")
+
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		log.Fatalf("Failed to resolve models: %v", err)
+	}
+
+	client, err := conn.New()
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	for _, m := range models {
+		if err := bench.Preflight(context.Background(), client, m, conn.Pull, exampleDir); err != nil {
+			log.Fatalf("Failed to check model: %v", err)
+		}
+	}
+
+	for _, m := range models {
+		if *scenario == "all" || *scenario == "developer" {
+			runScenario(client, m, exampleDir, "developer", *mode, *parallel, *reasks)
+		}
+		if *scenario == "all" || *scenario == "home" {
+			runScenario(client, m, exampleDir, "home-automation", *mode, *parallel, *reasks)
+		}
+		if (*scenario == "all" && *mode == toolcall.ModePrompt) || *scenario == "chains" {
+			runChains(client, m, exampleDir, *parallel, *reasks, *maxSteps)
+		}
+	}
+
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(prices.Apply(sweep.Only(bench.WithModelInfo(exampleDir, benchmarkResults(exampleDir)), models, func(m string) string {
+			return bench.SanitizeModelName(types.BaseModel(m))
+		}))))
 	}
 }
 
-func runScenario(client *ollama.Client, model, dir, scenario string) {
-	tools := loadJSON[[]ToolDef](filepath.Join(dir, "tools", scenario+".json"))
-	cases := loadJSON[[]TestCase](filepath.Join(dir, "testdata", scenario+".json"))
-	systemPrompt := buildSystemPrompt(tools)
+func runScenario(client provider.Provider, model, dir, scenario, mode string, parallel, reasks int) {
+	tools := loadJSON[[]toolcall.ToolDef](filepath.Join(dir, "tools", scenario+".json"))
+	cases := loadJSON[[]toolcall.TestCase](filepath.Join(dir, "testdata", scenario+".json"))
+	systemPrompt := toolcall.BuildSystemPrompt(tools)
+	nativeTools := toolcall.NativeTools(tools)
+	outputSchema, err := toolcall.CallSchema(tools)
+	if err != nil {
+		log.Fatalf("Failed to build call schema for %s: %v", scenario, err)
+	}
 
-	fmt.Printf("=== Function Calling: %s (%s) — %d requests ===\n", scenario, model, len(cases))
+	fmt.Printf("=== Function Calling: %s (%s, %s mode) — %d requests, parallel=%d ===\n", scenario, model, mode, len(cases), parallel)
 
-	var results []ActualCall
-	var totalTokensIn, totalTokensOut int
-	var totalDuration time.Duration
+	metas := make([]types.ModelMetadata, len(cases))
+	runs, summary := runner.Run(context.Background(), cases, parallel, func(ctx context.Context, i int, tc toolcall.TestCase) (toolcall.ActualCall, error) {
+		if mode == toolcall.ModeNative {
+			resp, err := client.Complete(ctx, toolcall.NewNativeRequest(model, nativeTools, tc.Request))
+			metas[i] = resp.Meta
+			if err != nil {
+				log.Printf("  [%d/%d] %s: ERROR: %v", i+1, len(cases), tc.ID, err)
+				return toolcall.ActualCall{ID: tc.ID, RawOutput: err.Error()}, err
+			}
+			call, ok := toolcall.NativeCall(resp.Message)
+			if !ok {
+				call = toolcall.NativeDecline(resp.Message.Content)
+				call.RawOutput = resp.Message.Content
+			}
+			call.ID = tc.ID
+			if call.Tool == "" {
+				log.Printf("  [%d/%d] %s: no tool call or decline (raw: %s)", i+1, len(cases), tc.ID, call.RawOutput)
+				return call, nil
+			}
+			printCall(i, len(cases), call, resp.Meta)
+			return call, nil
+		}
 
-	for i, tc := range cases {
-		resp, meta, err := client.ChatCompletion(model, systemPrompt, tc.Request, true)
+		reply, err := repair.Complete(ctx, client, ollama.NewChatRequest(model, systemPrompt, tc.Request, true), reasks)
+		metas[i] = reply.Meta
 		if err != nil {
 			log.Printf("  [%d/%d] %s: ERROR: %v", i+1, len(cases), tc.ID, err)
-			results = append(results, ActualCall{ID: tc.ID, RawOutput: err.Error()})
-			continue
+			return toolcall.ActualCall{ID: tc.ID, RawOutput: err.Error()}, err
 		}
+		resp := reply.Raw
+
+		valid := len(outputSchema.ValidateJSON([]byte(resp))) == 0
 
-		var call ActualCall
-		if err := json.Unmarshal([]byte(resp), &call); err != nil {
+		call, err := toolcall.ParseCall(reply.Text)
+		if err != nil {
 			log.Printf("  [%d/%d] %s: JSON parse error: %v (raw: %s)", i+1, len(cases), tc.ID, err, resp)
-			results = append(results, ActualCall{ID: tc.ID, RawOutput: resp})
-			continue
+			return toolcall.ActualCall{ID: tc.ID, RawOutput: resp, SchemaValid: &valid, JSON: reply.Outcome}, nil
 		}
 		call.ID = tc.ID
+		call.SchemaValid = &valid
+		call.JSON = reply.Outcome
+		if reply.Outcome != repair.Raw {
+			// Keep what the model actually said alongside the repaired call.
+			call.RawOutput = resp
+		}
+
+		printCall(i, len(cases), call, reply.Meta)
+		return call, nil
+	})
+
+	results := make([]toolcall.ActualCall, len(runs))
+	var totalTokensIn, totalTokensOut, schemaValid int
+	var totalDuration time.Duration
+	for i, r := range runs {
+		results[i] = r.Value
+		if metas[i].TotalTime > 0 || metas[i].Retries > 0 {
+			meta := metas[i]
+			meta.QueueTime = r.Queue
+			results[i].Meta = &meta
+		}
+		if r.Value.SchemaValid != nil && *r.Value.SchemaValid {
+			schemaValid++
+		}
+		totalTokensIn += metas[i].TokensIn
+		totalTokensOut += metas[i].TokensOut
+		totalDuration += metas[i].TotalTime
+	}
+
+	outPath := filepath.Join(dir, "results", resultFile(scenario, model, mode))
+	if err := bench.WriteJSON(outPath, results); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("  Wrote %s (%d results, %d schema-valid, %d tok in, %d tok out, %.1fs total)\n",
+		outPath, len(results), schemaValid, totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	fmt.Printf("  %s, %.1f tok/s aggregate\n\n", summary, summary.TokensPerSec(totalTokensOut))
+}
+
+// printCall logs the call parsed for case i.
+func printCall(i, n int, call toolcall.ActualCall, meta types.ModelMetadata) {
+	detail, _ := json.Marshal(call.Parameters)
+	if toolcall.Declined(call.Tool) {
+		detail, _ = json.Marshal(call.Reason + call.Question)
+	}
+	fmt.Printf("  [%d/%d] %s → %s(%s) (%.0fms, %.1f tok/s)\n",
+		i+1, n, call.ID, call.Tool, string(detail),
+		meta.TotalTime.Seconds()*1000, meta.TokensPerSec)
+}
+
+// resultFile names the results of model on scenario. ModeNative results get
+// a ".native" suffix so both modes can be compared side by side.
+func resultFile(scenario, model, mode string) string {
+	name := fmt.Sprintf("%s-%s", scenario, bench.SanitizeModelName(model))
+	if mode == toolcall.ModeNative {
+		name += "." + toolcall.ModeNative
+	}
+	return name + ".json"
+}
+
+// resultModel returns the report label for a result file of scenario, such
+// as "qwen3-4b" or "qwen3-4b [native]".
+func resultModel(path, scenario string) string {
+	name := strings.TrimPrefix(filepath.Base(path), scenario+"-")
+	name = strings.TrimSuffix(name, ".json")
+	if model, ok := strings.CutSuffix(name, "."+toolcall.ModeNative); ok {
+		return types.ModelLabel(model, toolcall.ModeNative)
+	}
+	return name
+}
 
-		totalTokensIn += meta.TokensIn
-		totalTokensOut += meta.TokensOut
-		totalDuration += meta.TotalTime
+// runChains runs the multi-step requests, executing each tool call the
+// model makes against the catalog's fake results.
+func runChains(client provider.Provider, model, dir string, parallel, reasks, maxSteps int) {
+	scenario := toolcall.ChainScenario
+	tools := loadJSON[[]toolcall.ToolDef](filepath.Join(dir, "tools", scenario+".json"))
+	cases := loadJSON[[]toolcall.TestCase](filepath.Join(dir, "testdata", scenario+".json"))
+	systemPrompt := toolcall.BuildChainSystemPrompt(tools)
+	fakes := toolcall.NewFakeTools(tools)
 
-		paramStr, _ := json.Marshal(call.Parameters)
-		fmt.Printf("  [%d/%d] %s → %s(%s) (%.0fms, %.1f tok/s)\n",
-			i+1, len(cases), tc.ID, call.Tool, string(paramStr),
-			meta.TotalTime.Seconds()*1000, meta.TokensPerSec)
+	fmt.Printf("=== Function Calling: %s (%s) — %d requests, max %d steps, parallel=%d ===\n", scenario, model, len(cases), maxSteps, parallel)
 
-		results = append(results, call)
+	runs, summary := runner.Run(context.Background(), cases, parallel, func(ctx context.Context, i int, tc toolcall.TestCase) (toolcall.ChainResult, error) {
+		res, err := toolcall.RunChain(ctx, client, model, systemPrompt, tc.Request, fakes, maxSteps, reasks)
+		res.ID = tc.ID
+		if err != nil {
+			log.Printf("  [%d/%d] %s: ERROR: %v", i+1, len(cases), tc.ID, err)
+			res.Error = err.Error()
+			return res, err
+		}
+
+		called := make([]string, len(res.Steps))
+		for j, s := range res.Steps {
+			called[j] = s.Tool
+		}
+		outcome := fmt.Sprintf("%q", res.Answer)
+		if res.Error != "" {
+			outcome = "ERROR: " + res.Error
+		}
+		fmt.Printf("  [%d/%d] %s → %s → %s (%d calls, %.0fms)\n",
+			i+1, len(cases), tc.ID, strings.Join(called, " → "), outcome,
+			res.Calls, res.Meta.TotalTime.Seconds()*1000)
+		return res, nil
+	})
+
+	results := make([]toolcall.ChainResult, len(runs))
+	var totalTokensIn, totalTokensOut, calls int
+	var totalDuration time.Duration
+	for i, r := range runs {
+		results[i] = r.Value
+		if m := results[i].Meta; m != nil {
+			m.QueueTime = r.Queue
+			totalTokensIn += m.TokensIn
+			totalTokensOut += m.TokensOut
+			totalDuration += m.TotalTime
+		}
+		calls += results[i].Calls
 	}
 
-	outPath := filepath.Join(dir, "results", fmt.Sprintf("%s-%s.json", scenario, sanitizeModelName(model)))
-	writeJSON(outPath, results)
-	fmt.Printf("  Wrote %s (%d results, %d tok in, %d tok out, %.1fs total)\n\n",
-		outPath, len(results), totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	outPath := filepath.Join(dir, "results", fmt.Sprintf("%s-%s.json", scenario, bench.SanitizeModelName(model)))
+	if err := bench.WriteJSON(outPath, results); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("  Wrote %s (%d results, %d model calls, %d tok in, %d tok out, %.1fs total)\n",
+		outPath, len(results), calls, totalTokensIn, totalTokensOut, totalDuration.Seconds())
+	fmt.Printf("  %s, %.1f tok/s aggregate\n\n", summary, summary.TokensPerSec(totalTokensOut))
 }
 
 func scoreResults(dir, scenario string) {
@@ -161,22 +291,27 @@ func scoreResults(dir, scenario string) {
 	if scenario == "all" || scenario == "home" {
 		scoreScenario(dir, "home-automation")
 	}
+	if scenario == "all" || scenario == "chains" {
+		scoreChains(dir)
+	}
 }
 
 func scoreScenario(dir, scenario string) {
-	expected := loadJSON[[]ExpectedCall](filepath.Join(dir, "expected", scenario+".json"))
-	expectedMap := make(map[string]ExpectedCall)
+	expected := loadJSON[[]toolcall.ExpectedCall](filepath.Join(dir, "expected", scenario+".json"))
+	expectedMap := make(map[string]toolcall.ExpectedCall)
 	for _, e := range expected {
 		expectedMap[e.ID] = e
 	}
 
+	validation := validationRates(dir, scenario)
+
 	resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", scenario+"-*.json"))
 	for _, rf := range resultFiles {
-		actual := loadJSON[[]ActualCall](rf)
-		modelName := strings.TrimPrefix(filepath.Base(rf), scenario+"-")
-		modelName = strings.TrimSuffix(modelName, ".json")
+		actual := loadJSON[[]toolcall.ActualCall](rf)
+		modelName := resultModel(rf, scenario)
 
 		var toolCorrect, paramCorrect, bothCorrect, total int
+		var abstention toolcall.Abstention
 
 		for _, a := range actual {
 			e, ok := expectedMap[a.ID]
@@ -184,13 +319,14 @@ func scoreScenario(dir, scenario string) {
 				continue
 			}
 			total++
+			abstention.Add(e.Tool, a.Tool)
 
-			toolMatch := strings.EqualFold(strings.TrimSpace(a.Tool), strings.TrimSpace(e.Tool))
+			toolMatch := toolcall.ToolMatches(e.Tool, a.Tool)
 			if toolMatch {
 				toolCorrect++
 			}
 
-			pMatch := parametersMatch(e.Parameters, a.Parameters)
+			pMatch := toolcall.ParametersMatch(e.Parameters, a.Parameters)
 			if pMatch {
 				paramCorrect++
 			}
@@ -201,36 +337,251 @@ func scoreScenario(dir, scenario string) {
 		}
 
 		fmt.Printf("=== Function Calling Scores: %s / %s ===\n", scenario, modelName)
-		fmt.Printf("  Tool selection:  %.1f%% (%d/%d)\n", pct(toolCorrect, total), toolCorrect, total)
-		fmt.Printf("  Parameters:      %.1f%% (%d/%d)\n", pct(paramCorrect, total), paramCorrect, total)
-		fmt.Printf("  Combined:        %.1f%% (%d/%d)\n\n", pct(bothCorrect, total), bothCorrect, total)
+		fmt.Printf("  Tool selection:  %.1f%% (%d/%d)\n", bench.Pct(toolCorrect, total), toolCorrect, total)
+		fmt.Printf("  Parameters:      %.1f%% (%d/%d)\n", bench.Pct(paramCorrect, total), paramCorrect, total)
+		fmt.Printf("  Combined:        %.1f%% (%d/%d)\n", bench.Pct(bothCorrect, total), bothCorrect, total)
+		printAbstention(abstention)
+		printValidation(validation[modelName])
+	}
+}
+
+// printAbstention prints how well the model declined the requests no tool
+// should handle, for -score.
+func printAbstention(a toolcall.Abstention) {
+	if a.Expected == 0 && a.Declined == 0 {
+		return
 	}
+	fmt.Printf("  Abstention precision: %.1f%% (%d/%d declines were expected)\n", bench.Pct(a.Correct, a.Declined), a.Correct, a.Declined)
+	fmt.Printf("  Abstention recall:    %.1f%% (%d/%d expected declines made)\n", bench.Pct(a.Correct, a.Expected), a.Correct, a.Expected)
+	fmt.Printf("  Forced calls:         %.1f%% (%d/%d expected declines)\n", bench.Pct(a.Forced, a.Expected), a.Forced, a.Expected)
+	fmt.Printf("  Decline kind:         %.1f%% (%d/%d)\n", bench.Pct(a.Kind, a.Correct), a.Kind, a.Correct)
 }
 
-func generateReport(dir string) {
+// printValidation prints the share of calls with each kind of definition
+// violation, for -score.
+func printValidation(r toolcall.ValidationRates) {
+	fmt.Printf("  Hallucinated tool:  %.1f%% (%d/%d calls)\n", bench.Pct(r.UnknownTool, r.Calls), r.UnknownTool, r.Calls)
+	for _, c := range []struct {
+		name string
+		n    int
+	}{
+		{"Missing required", r.MissingRequired},
+		{"Wrong type", r.WrongType},
+		{"Hallucinated param", r.Hallucinated},
+		{"Invalid value", r.InvalidValue},
+	} {
+		fmt.Printf("  %-19s %.1f%% (%d/%d calls to known tools)\n", c.name+":", bench.Pct(c.n, r.KnownCalls), c.n, r.KnownCalls)
+	}
+	fmt.Println()
+}
+
+// validationRates checks every call in the result files of scenario
+// against the scenario's tool catalog, keyed by model label. Each step of
+// a chain counts as a call.
+func validationRates(dir, scenario string) map[string]toolcall.ValidationRates {
+	tools := loadJSON[[]toolcall.ToolDef](filepath.Join(dir, "tools", scenario+".json"))
+	validator, err := toolcall.NewValidator(tools)
+	if err != nil {
+		log.Fatalf("Failed to compile %s tool constraints: %v", scenario, err)
+	}
+
+	rates := make(map[string]toolcall.ValidationRates)
+	resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", scenario+"-*.json"))
+	for _, rf := range resultFiles {
+		var r toolcall.ValidationRates
+		if scenario == toolcall.ChainScenario {
+			for _, c := range loadJSON[[]toolcall.ChainResult](rf) {
+				for _, step := range c.Steps {
+					r.Add(validator.Validate(step.Tool, step.Parameters))
+				}
+			}
+		} else {
+			for _, a := range loadJSON[[]toolcall.ActualCall](rf) {
+				// A reply with no call is a tool-selection miss and a
+				// decline is scored as an abstention; neither is a call
+				// to validate.
+				if a.Tool != "" && !toolcall.Declined(a.Tool) {
+					r.Add(validator.Validate(a.Tool, a.Parameters))
+				}
+			}
+		}
+		rates[resultModel(rf, scenario)] = r
+	}
+	return rates
+}
+
+// abstentionTable renders how well every model declined out-of-scope and
+// ambiguous requests, pooled over the single-call scenarios.
+func abstentionTable(dir string) string {
+	pooled := make(map[string]*toolcall.Abstention)
+	var order []string
+	for _, scenario := range toolcall.Scenarios {
+		expected := loadJSON[[]toolcall.ExpectedCall](filepath.Join(dir, "expected", scenario+".json"))
+		expectedTool := make(map[string]string, len(expected))
+		for _, e := range expected {
+			expectedTool[e.ID] = e.Tool
+		}
+
+		resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", scenario+"-*.json"))
+		for _, rf := range resultFiles {
+			model := resultModel(rf, scenario)
+			if _, ok := pooled[model]; !ok {
+				pooled[model] = &toolcall.Abstention{}
+				order = append(order, model)
+			}
+			for _, a := range loadJSON[[]toolcall.ActualCall](rf) {
+				if tool, ok := expectedTool[a.ID]; ok {
+					pooled[model].Add(tool, a.Tool)
+				}
+			}
+		}
+	}
+	sort.Strings(order)
+
+	var rows []reporting.MetricRow
+	for _, model := range order {
+		if a := pooled[model]; a.Expected > 0 || a.Declined > 0 {
+			rows = append(rows, reporting.MetricRow{Model: model, Values: a.Values()})
+		}
+	}
+	return reporting.GenerateMetrics("Abstention", toolcall.AbstentionColumns, rows)
+}
+
+// validationTable renders the violation rates of every model, pooled over
+// all scenarios.
+func validationTable(dir string) string {
+	pooled := make(map[string]*toolcall.ValidationRates)
+	var order []string
+	for _, scenario := range append(append([]string{}, toolcall.Scenarios...), toolcall.ChainScenario) {
+		for model, r := range validationRates(dir, scenario) {
+			if _, ok := pooled[model]; !ok {
+				pooled[model] = &toolcall.ValidationRates{}
+				order = append(order, model)
+			}
+			pooled[model].Merge(r)
+		}
+	}
+	sort.Strings(order)
+
+	rows := make([]reporting.MetricRow, len(order))
+	for i, model := range order {
+		rows[i] = reporting.MetricRow{Model: model, Values: pooled[model].Rates()}
+	}
+	return reporting.GenerateMetrics("Parameter Validation", toolcall.ValidationColumns, rows)
+}
+
+// chainScores grades every chain result file, keyed by model.
+func chainScores(dir string) (map[string][]toolcall.ChainScore, map[string][]toolcall.ChainResult) {
+	scenario := toolcall.ChainScenario
+	tools := loadJSON[[]toolcall.ToolDef](filepath.Join(dir, "tools", scenario+".json"))
+	fakes := toolcall.NewFakeTools(tools)
+	expected := loadJSON[[]toolcall.ExpectedChain](filepath.Join(dir, "expected", scenario+".json"))
+	expectedMap := make(map[string]toolcall.ExpectedChain)
+	for _, e := range expected {
+		expectedMap[e.ID] = e
+	}
+
+	scores := make(map[string][]toolcall.ChainScore)
+	results := make(map[string][]toolcall.ChainResult)
+	resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", scenario+"-*.json"))
+	for _, rf := range resultFiles {
+		actual := loadJSON[[]toolcall.ChainResult](rf)
+		modelName := resultModel(rf, scenario)
+
+		scores[modelName] = []toolcall.ChainScore{}
+		results[modelName] = actual
+		for _, a := range actual {
+			if e, ok := expectedMap[a.ID]; ok {
+				scores[modelName] = append(scores[modelName], toolcall.ScoreChain(e, a, fakes))
+			}
+		}
+	}
+	return scores, results
+}
+
+func scoreChains(dir string) {
+	scores, _ := chainScores(dir)
+	validation := validationRates(dir, toolcall.ChainScenario)
+	models := make([]string, 0, len(scores))
+	for m := range scores {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+
+	for _, m := range models {
+		var sequence, answer, correct, steps, stepsCorrect, dataflow, dataflowCorrect int
+		for _, s := range scores[m] {
+			if s.Sequence {
+				sequence++
+			}
+			if s.Answer {
+				answer++
+			}
+			if s.Correct() {
+				correct++
+			}
+			steps += s.Steps
+			stepsCorrect += s.StepsCorrect
+			dataflow += s.Dataflow
+			dataflowCorrect += s.DataflowCorrect
+		}
+		total := len(scores[m])
+
+		fmt.Printf("=== Function Calling Scores: %s / %s ===\n", toolcall.ChainScenario, m)
+		fmt.Printf("  Call sequence:   %.1f%% (%d/%d)\n", bench.Pct(sequence, total), sequence, total)
+		fmt.Printf("  Steps:           %.1f%% (%d/%d)\n", bench.Pct(stepsCorrect, steps), stepsCorrect, steps)
+		fmt.Printf("  Dataflow:        %.1f%% (%d/%d)\n", bench.Pct(dataflowCorrect, dataflow), dataflowCorrect, dataflow)
+		fmt.Printf("  Final answer:    %.1f%% (%d/%d)\n", bench.Pct(answer, total), answer, total)
+		fmt.Printf("  Whole chain:     %.1f%% (%d/%d)\n", bench.Pct(correct, total), correct, total)
+		printValidation(validation[m])
+	}
+}
+
+func generateReport(dir string, prices *pricing.Table) {
+	results := prices.Apply(bench.WithModelInfo(dir, benchmarkResults(dir)))
+	fmt.Print(reporting.GenerateReport(results))
+	fmt.Print(reporting.GenerateComparison(results))
+	fmt.Print(abstentionTable(dir))
+	fmt.Print(validationTable(dir))
+}
+
+// benchmarkResults scores every result file against the expected calls.
+func benchmarkResults(dir string) []types.BenchmarkResult {
 	var results []types.BenchmarkResult
 
 	for _, scenario := range []string{"developer", "home-automation"} {
-		expected := loadJSON[[]ExpectedCall](filepath.Join(dir, "expected", scenario+".json"))
-		expectedMap := make(map[string]ExpectedCall)
+		expected := loadJSON[[]toolcall.ExpectedCall](filepath.Join(dir, "expected", scenario+".json"))
+		expectedMap := make(map[string]toolcall.ExpectedCall)
 		for _, e := range expected {
 			expectedMap[e.ID] = e
 		}
 
 		resultFiles, _ := filepath.Glob(filepath.Join(dir, "results", scenario+"-*.json"))
 		for _, rf := range resultFiles {
-			actual := loadJSON[[]ActualCall](rf)
-			modelName := strings.TrimPrefix(filepath.Base(rf), scenario+"-")
-			modelName = strings.TrimSuffix(modelName, ".json")
+			actual := loadJSON[[]toolcall.ActualCall](rf)
+			modelName := resultModel(rf, scenario)
 
-			var toolCorrect, total int
+			var toolCorrect, total, checks, valid int
+			var jsonChecks, jsonRaw, jsonRepaired int
+			var metas []types.ModelMetadata
 			for _, a := range actual {
 				if e, ok := expectedMap[a.ID]; ok {
 					total++
-					if strings.EqualFold(strings.TrimSpace(a.Tool), strings.TrimSpace(e.Tool)) {
+					if toolcall.ToolMatches(e.Tool, a.Tool) {
 						toolCorrect++
 					}
 				}
+				if a.SchemaValid != nil {
+					checks++
+					if *a.SchemaValid {
+						valid++
+					}
+				}
+				c, r, rep := a.JSON.Counts()
+				jsonChecks, jsonRaw, jsonRepaired = jsonChecks+c, jsonRaw+r, jsonRepaired+rep
+				if a.Meta != nil {
+					metas = append(metas, *a.Meta)
+				}
 			}
 
 			quality := 0.0
@@ -238,80 +589,79 @@ func generateReport(dir string) {
 				quality = float64(toolCorrect) / float64(total)
 			}
 
-			results = append(results, types.BenchmarkResult{
-				Example:     fmt.Sprintf("Function Calling (%s)", scenario),
-				Model:       modelName,
-				Quality:     quality,
-				QualityName: "Tool Acc",
-			})
+			results = append(results, withMeta(types.BenchmarkResult{
+				Example:      fmt.Sprintf("Function Calling (%s)", scenario),
+				Model:        modelName,
+				Quality:      quality,
+				QualityName:  "Tool Acc",
+				SchemaChecks: checks,
+				SchemaValid:  valid,
+				JSONChecks:   jsonChecks,
+				JSONRaw:      jsonRaw,
+				JSONRepaired: jsonRepaired,
+			}, metas))
 		}
 	}
 
-	report := reporting.GenerateReport(results)
-	fmt.Print(report)
+	return append(results, chainResults(dir)...)
 }
 
-// parametersMatch checks whether actual parameters satisfy the expected ones.
-// It only checks keys present in expected -- extra keys in actual are ignored.
-// Empty expected parameters means only the tool name matters.
-func parametersMatch(expected, actual map[string]any) bool {
-	if len(expected) == 0 {
-		return true
-	}
-	for key, expVal := range expected {
-		actVal, ok := actual[key]
-		if !ok {
-			return false
+// chainResults scores the chain result files. Quality is the share of
+// chains entirely right; performance columns are per chain, summed over
+// its model calls.
+func chainResults(dir string) []types.BenchmarkResult {
+	scores, actual := chainScores(dir)
+
+	var results []types.BenchmarkResult
+	for model, chains := range actual {
+		var correct, calls int
+		for _, s := range scores[model] {
+			if s.Correct() {
+				correct++
+			}
 		}
-		if !valuesMatch(expVal, actVal) {
-			return false
+		var metas []types.ModelMetadata
+		for _, c := range chains {
+			calls += c.Calls
+			if c.Meta != nil {
+				metas = append(metas, *c.Meta)
+			}
 		}
-	}
-	return true
-}
 
-func valuesMatch(expected, actual any) bool {
-	// Normalize numbers: JSON unmarshals all numbers as float64
-	expStr := fmt.Sprintf("%v", expected)
-	actStr := fmt.Sprintf("%v", actual)
-	return strings.EqualFold(strings.TrimSpace(expStr), strings.TrimSpace(actStr))
+		r := types.BenchmarkResult{
+			Example:     fmt.Sprintf("Function Calling (%s)", toolcall.ChainScenario),
+			Model:       model,
+			QualityName: "Chain Acc",
+		}
+		if n := len(scores[model]); n > 0 {
+			r.Quality = float64(correct) / float64(n)
+		}
+		if len(chains) > 0 {
+			r.Calls = float64(calls) / float64(len(chains))
+		}
+		results = append(results, withMeta(r, metas))
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Model < results[j].Model })
+	return results
 }
 
 // --- Helpers ---
 
 func loadJSON[T any](path string) T {
 	var v T
-	data, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatalf("Failed to read %s: %v", path, err)
-	}
-	if err := json.Unmarshal(data, &v); err != nil {
-		log.Fatalf("Failed to parse %s: %v", path, err)
+	if err := bench.LoadJSON(path, &v); err != nil {
+		log.Fatal(err)
 	}
 	return v
 }
 
-func writeJSON(path string, v any) {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		log.Fatalf("Failed to create directory for %s: %v", path, err)
-	}
-	data, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		log.Fatalf("Failed to marshal JSON: %v", err)
-	}
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		log.Fatalf("Failed to write %s: %v", path, err)
-	}
-}
-
-func sanitizeModelName(model string) string {
-	r := strings.NewReplacer("/", "-", ":", "-", " ", "-")
-	return r.Replace(model)
-}
-
-func pct(n, total int) float64 {
-	if total == 0 {
-		return 0
-	}
-	return float64(n) / float64(total) * 100
+// withMeta fills r's performance columns with the mean of the per-call
+// metadata recorded in a result file.
+func withMeta(r types.BenchmarkResult, metas []types.ModelMetadata) types.BenchmarkResult {
+	m := types.MeanMetadata(metas)
+	r.TokensIn, r.TokensOut = m.TokensIn, m.TokensOut
+	r.TTFT, r.TotalTime = m.TTFT, m.TotalTime
+	r.TokensPerSec, r.InterTokenLatency = m.TokensPerSec, m.InterTokenLatency
+	r.Retries = m.Retries
+	return r
 }
@@ -0,0 +1,264 @@
+package toolcall
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// ChainScenario is the scenario of multi-step requests, which need several
+// tool calls whose results feed later calls before the model can answer.
+const ChainScenario = "chains"
+
+// DefaultMaxSteps caps the tool calls of one chain.
+const DefaultMaxSteps = 6
+
+// FakeResult is a canned result of a tool, returned for calls whose
+// parameters include Parameters (compared as in ParametersMatch).
+type FakeResult struct {
+	Parameters map[string]any `json:"parameters"`
+	Result     map[string]any `json:"result"`
+}
+
+// FakeTools executes tool calls against the Fake results of a catalog, so
+// chains run deterministically without real side effects.
+type FakeTools map[string][]FakeResult
+
+// NewFakeTools indexes the fake results of tools by name.
+func NewFakeTools(tools []ToolDef) FakeTools {
+	f := make(FakeTools, len(tools))
+	for _, t := range tools {
+		f[normalizeTool(t.Name)] = t.Fake
+	}
+	return f
+}
+
+// Call returns the first fake result of tool whose parameters match. Unknown
+// tools and unmatched parameters return an error object for the model to
+// react to, and false.
+func (f FakeTools) Call(tool string, params map[string]any) (map[string]any, bool) {
+	fakes, ok := f[normalizeTool(tool)]
+	if !ok {
+		return map[string]any{"error": fmt.Sprintf("unknown tool %q", tool)}, false
+	}
+	for _, fake := range fakes {
+		if ParametersMatch(fake.Parameters, params) {
+			return fake.Result, true
+		}
+	}
+	return map[string]any{"error": "no result found for these parameters"}, false
+}
+
+// ExpectedChain is the ground truth for a chain: the calls in order and
+// strings the final answer must contain. A parameter value "$N.path" is a
+// value from the result of call N (1-based), which checks that the model
+// passed data from one step to the next.
+type ExpectedChain struct {
+	ID     string         `json:"id"`
+	Calls  []ExpectedCall `json:"calls"`
+	Answer []string       `json:"answer_contains"`
+}
+
+// ChainStep is one tool call the model made and the result it was given.
+type ChainStep struct {
+	Tool       string         `json:"tool"`
+	Parameters map[string]any `json:"parameters"`
+	Result     map[string]any `json:"result"`
+}
+
+// ChainResult is the outcome of running one chain request.
+type ChainResult struct {
+	ID     string      `json:"id"`
+	Steps  []ChainStep `json:"steps"`
+	Answer string      `json:"answer,omitempty"`
+	// Error says why the chain stopped without an answer.
+	Error string `json:"error,omitempty"`
+	// Calls is the number of model calls, including re-asks.
+	Calls int                  `json:"calls"`
+	Meta  *types.ModelMetadata `json:"metadata,omitempty"`
+}
+
+// BuildChainSystemPrompt lists the tool catalog and the two replies the
+// model may give on each turn: a tool call or a final answer.
+func BuildChainSystemPrompt(tools []ToolDef) string {
+	var sb strings.Builder
+	sb.WriteString("You are a function calling assistant. Given a user request, answer it by calling tools one at a time. You will be shown each tool's result before your next reply.\n\n")
+	writeCatalog(&sb, tools)
+
+	sb.WriteString("Respond with JSON only, either a tool call: {\"tool\": \"tool_name\", \"parameters\": {...}}\n")
+	sb.WriteString("or, once the results answer the request: {\"answer\": \"your final answer\"}\n")
+	sb.WriteString("Use values from earlier tool results as parameters where needed; never invent IDs.\n")
+	sb.WriteString("Only include parameters that are relevant to the request. Use the exact tool names shown above.")
+	return sb.String()
+}
+
+// turn is one model reply in a chain: a tool call or a final answer.
+type turn struct {
+	Tool       string         `json:"tool"`
+	Parameters map[string]any `json:"parameters"`
+	Answer     any            `json:"answer"`
+}
+
+// RunChain runs request as a conversation: each tool call the model makes
+// is executed against tools and its result sent back, until the model
+// answers or has made maxSteps calls; with maxSteps below 1 it can only
+// answer directly. Reasks applies to every turn.
+func RunChain(ctx context.Context, client provider.Provider, model, system, request string, tools FakeTools, maxSteps, reasks int) (res ChainResult, err error) {
+	var meta types.ModelMetadata
+	defer func() { res.Meta = &meta }()
+
+	req := ollama.NewChatRequest(model, system, request, true)
+
+	for {
+		reply, err := repair.Complete(ctx, client, req, reasks)
+		meta = types.SumMetadata(meta, reply.Meta)
+		res.Calls += 1 + reply.Reasks
+		if err != nil {
+			return res, err
+		}
+
+		var t turn
+		if err := repair.Unmarshal(reply.Text, &t); err != nil {
+			res.Error = fmt.Sprintf("invalid reply: %v (raw: %s)", err, reply.Raw)
+			return res, nil
+		}
+		if t.Tool == "" {
+			if t.Answer == nil {
+				res.Error = fmt.Sprintf("reply is neither a tool call nor an answer: %s", reply.Raw)
+				return res, nil
+			}
+			res.Answer = answerText(t.Answer)
+			return res, nil
+		}
+		if len(res.Steps) >= maxSteps {
+			res.Error = fmt.Sprintf("no answer after %d tool calls", maxSteps)
+			return res, nil
+		}
+
+		result, _ := tools.Call(t.Tool, t.Parameters)
+		res.Steps = append(res.Steps, ChainStep{Tool: t.Tool, Parameters: t.Parameters, Result: result})
+		data, _ := json.Marshal(result)
+		req.Messages = append(req.Messages,
+			ollama.Message{Role: "assistant", Content: reply.Raw},
+			ollama.Message{Role: "user", Content: fmt.Sprintf("Result of %s: %s\nCall another tool or give the final answer.", t.Tool, data)},
+		)
+	}
+}
+
+// answerText renders an answer the model gave as a string, or as JSON if
+// it gave an object or number instead.
+func answerText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// ChainScore grades a ChainResult against its ExpectedChain.
+type ChainScore struct {
+	// Sequence is true if the model called exactly the expected tools in
+	// the expected order.
+	Sequence bool `json:"sequence"`
+	// StepsCorrect counts expected calls matched, in position, by tool and
+	// parameters.
+	StepsCorrect int `json:"steps_correct"`
+	Steps        int `json:"steps"`
+	// DataflowCorrect counts the "$N.path" parameters the model filled
+	// with the value from the earlier result.
+	DataflowCorrect int  `json:"dataflow_correct"`
+	Dataflow        int  `json:"dataflow"`
+	Answer          bool `json:"answer"`
+}
+
+// Correct reports whether the whole chain was right: sequence, every
+// call's parameters and the final answer.
+func (s ChainScore) Correct() bool {
+	return s.Sequence && s.StepsCorrect == s.Steps && s.Answer
+}
+
+// refPattern matches a "$N.path" reference; path is dot-separated object
+// keys and array indexes, e.g. "$2.orders.0.tracking_number".
+var refPattern = regexp.MustCompile(`^\$(\d+)\.([\w.]+)$`)
+
+// lookup follows a dot-separated path through decoded JSON.
+func lookup(v any, path string) any {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// ScoreChain grades actual against expected. References are resolved
+// against what the expected calls return from tools, so a model that
+// followed a wrong path cannot match them by accident.
+func ScoreChain(expected ExpectedChain, actual ChainResult, tools FakeTools) ChainScore {
+	s := ChainScore{Steps: len(expected.Calls), Sequence: len(actual.Steps) == len(expected.Calls)}
+
+	var results []map[string]any
+	for i, e := range expected.Calls {
+		params := make(map[string]any, len(e.Parameters))
+		refs := make(map[string]bool)
+		for k, v := range e.Parameters {
+			params[k] = v
+			if str, ok := v.(string); ok {
+				if m := refPattern.FindStringSubmatch(str); m != nil {
+					n, _ := strconv.Atoi(m[1])
+					if n >= 1 && n <= len(results) {
+						params[k] = lookup(results[n-1], m[2])
+					}
+					refs[k] = true
+				}
+			}
+		}
+		result, _ := tools.Call(e.Tool, params)
+		results = append(results, result)
+		s.Dataflow += len(refs)
+
+		if i >= len(actual.Steps) {
+			s.Sequence = false
+			continue
+		}
+		a := actual.Steps[i]
+		if !ToolMatches(e.Tool, a.Tool) {
+			s.Sequence = false
+			continue
+		}
+		if ParametersMatch(params, a.Parameters) {
+			s.StepsCorrect++
+		}
+		for k := range refs {
+			if v, ok := a.Parameters[k]; ok && valuesMatch(params[k], v) {
+				s.DataflowCorrect++
+			}
+		}
+	}
+
+	s.Answer = actual.Error == "" && actual.Answer != ""
+	answer := strings.ToLower(actual.Answer)
+	for _, want := range expected.Answer {
+		if !strings.Contains(answer, strings.ToLower(want)) {
+			s.Answer = false
+		}
+	}
+	return s
+}
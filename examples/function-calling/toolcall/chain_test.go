@@ -0,0 +1,151 @@
+package toolcall
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama/ollamatest"
+)
+
+func loadChainFixtures(t *testing.T) (FakeTools, map[string]ExpectedChain) {
+	t.Helper()
+	var tools []ToolDef
+	if err := bench.LoadJSON(filepath.Join("..", "tools", "chains.json"), &tools); err != nil {
+		t.Fatal(err)
+	}
+	var expected []ExpectedChain
+	if err := bench.LoadJSON(filepath.Join("..", "expected", "chains.json"), &expected); err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]ExpectedChain)
+	for _, e := range expected {
+		byID[e.ID] = e
+	}
+	return NewFakeTools(tools), byID
+}
+
+func TestRunChainScores(t *testing.T) {
+	fakes, expected := loadChainFixtures(t)
+
+	tests := []struct {
+		name    string
+		id      string
+		replies []string
+		want    ChainScore
+		correct bool
+	}{
+		{
+			name: "correct",
+			id:   "chain-02",
+			replies: []string{
+				`{"tool": "find_customer", "parameters": {"name": "Alice Chen"}}`,
+				"```json\n{\"tool\": \"get_weather\", \"parameters\": {\"city\": \"Denver\"}}\n```",
+				`{"answer": "It is snowing in Denver, -2°C."}`,
+			},
+			want:    ChainScore{Sequence: true, StepsCorrect: 2, Steps: 2, DataflowCorrect: 1, Dataflow: 1, Answer: true},
+			correct: true,
+		},
+		{
+			name: "invented value",
+			id:   "chain-02",
+			replies: []string{
+				`{"tool": "find_customer", "parameters": {"name": "Alice Chen"}}`,
+				`{"tool": "get_weather", "parameters": {"city": "Seattle"}}`,
+				`{"answer": "Rainy, 11°C."}`,
+			},
+			want: ChainScore{Sequence: true, StepsCorrect: 1, Steps: 2, DataflowCorrect: 0, Dataflow: 1},
+		},
+		{
+			name: "skipped step",
+			id:   "chain-04",
+			replies: []string{
+				`{"tool": "convert_currency", "parameters": {"amount": 120, "from": "USD", "to": "EUR"}}`,
+				`{"answer": 110.4}`,
+			},
+			want: ChainScore{Sequence: false, StepsCorrect: 0, Steps: 2, DataflowCorrect: 0, Dataflow: 1, Answer: true},
+		},
+		{
+			name:    "unparseable",
+			id:      "chain-01",
+			replies: []string{"Let me check the weather for you."},
+			want:    ChainScore{Sequence: false, Steps: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := ollamatest.NewServer()
+			defer srv.Close()
+			for _, r := range tt.replies {
+				srv.Enqueue(ollamatest.Reply{Content: r, OutputTokens: 10})
+			}
+
+			res, err := RunChain(context.Background(), srv.Client(), "qwen3:4b", "system", "request", fakes, DefaultMaxSteps, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Calls != len(tt.replies) || res.Meta.TokensOut != 10*len(tt.replies) {
+				t.Errorf("%d calls, %d tokens out; want %d, %d", res.Calls, res.Meta.TokensOut, len(tt.replies), 10*len(tt.replies))
+			}
+			got := ScoreChain(expected[tt.id], res, fakes)
+			if got != tt.want {
+				t.Errorf("ScoreChain = %+v, want %+v", got, tt.want)
+			}
+			if got.Correct() != tt.correct {
+				t.Errorf("Correct() = %v, want %v", got.Correct(), tt.correct)
+			}
+		})
+	}
+}
+
+// TestRunChainFeedsResults checks that each tool result is sent back to the
+// model before its next turn.
+func TestRunChainFeedsResults(t *testing.T) {
+	fakes, _ := loadChainFixtures(t)
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(
+		ollamatest.Reply{Content: `{"tool": "find_customer", "parameters": {"name": "Bob Martinez"}}`},
+		ollamatest.Reply{Content: `{"answer": "Austin"}`},
+	)
+
+	res, err := RunChain(context.Background(), srv.Client(), "qwen3:4b", "system", "Where does Bob live?", fakes, DefaultMaxSteps, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Answer != "Austin" || len(res.Steps) != 1 || res.Steps[0].Result["city"] != "Austin" {
+		t.Fatalf("result = %+v", res)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("%d requests, want 2", len(reqs))
+	}
+	msgs := reqs[1].Messages
+	last := msgs[len(msgs)-1]
+	if len(msgs) != 4 || msgs[2].Role != "assistant" || last.Role != "user" ||
+		!strings.Contains(last.Content, `Result of find_customer: {"city":"Austin","customer_id":"C-1002"`) {
+		t.Errorf("second turn messages = %+v", msgs)
+	}
+}
+
+func TestRunChainMaxSteps(t *testing.T) {
+	fakes, _ := loadChainFixtures(t)
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Content: `{"tool": "get_weather", "parameters": {"city": "Lisbon"}}`}
+
+	for _, tt := range []struct{ maxSteps, steps int }{{2, 2}, {0, 0}, {-1, 0}} {
+		res, err := RunChain(context.Background(), srv.Client(), "qwen3:4b", "system", "request", fakes, tt.maxSteps, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf("no answer after %d tool calls", tt.maxSteps)
+		if len(res.Steps) != tt.steps || res.Error != want {
+			t.Errorf("maxSteps %d: %d steps, error %q; want %d and %q", tt.maxSteps, len(res.Steps), res.Error, tt.steps, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package toolcall
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/repair"
+)
+
+// Reserved tool names for declining a request. A model answers NoTool when
+// no tool in the catalog can do what is asked, and AskClarification when the
+// request is too ambiguous to pick a tool or fill its required parameters.
+// Expected calls use the same names, so tool selection accuracy already
+// counts a decline as right or wrong.
+const (
+	NoTool           = "no_tool"
+	AskClarification = "ask_clarification"
+)
+
+// Declined reports whether tool is one of the declines.
+func Declined(tool string) bool {
+	return ToolMatches(tool, NoTool) || ToolMatches(tool, AskClarification)
+}
+
+// NativeDecline interprets a ModeNative reply that made no tool call as a
+// decline: AskClarification if its text ends in a question, NoTool
+// otherwise. Tool APIs have no declining form, so the text is all there is.
+// Only prose declines: an empty reply, or one that writes a {"tool": ...}
+// call as text instead of using the API, is a format failure and leaves
+// Tool empty.
+func NativeDecline(content string) ActualCall {
+	text := strings.TrimSpace(content)
+	var call map[string]json.RawMessage
+	if text == "" || repair.Unmarshal(text, &call) == nil && call["tool"] != nil {
+		return ActualCall{}
+	}
+	if strings.HasSuffix(text, "?") {
+		return ActualCall{Tool: AskClarification, Question: text}
+	}
+	return ActualCall{Tool: NoTool, Reason: text}
+}
+
+// Abstention counts how a model's declines line up with the expected ones.
+// A decline of the wrong kind still counts as an abstention; Kind tracks
+// how many correct abstentions also picked the right one.
+type Abstention struct {
+	Expected int `json:"expected"` // requests that should be declined
+	Declined int `json:"declined"` // requests the model declined
+	Correct  int `json:"correct"`  // requests both
+	// Forced counts requests that should be declined where the model
+	// called a tool instead.
+	Forced int `json:"forced"`
+	Kind   int `json:"kind"`
+}
+
+// Add counts one request, given its expected tool and the model's call.
+// A reply with no call at all is neither a tool call nor a decline.
+func (a *Abstention) Add(expected, actual string) {
+	wantDecline, declined := Declined(expected), Declined(actual)
+	if wantDecline {
+		a.Expected++
+	}
+	if declined {
+		a.Declined++
+	}
+	switch {
+	case wantDecline && declined:
+		a.Correct++
+		if ToolMatches(expected, actual) {
+			a.Kind++
+		}
+	case wantDecline && strings.TrimSpace(actual) != "":
+		a.Forced++
+	}
+}
+
+// Merge adds the counts of o to a.
+func (a *Abstention) Merge(o Abstention) {
+	a.Expected += o.Expected
+	a.Declined += o.Declined
+	a.Correct += o.Correct
+	a.Forced += o.Forced
+	a.Kind += o.Kind
+}
+
+// Precision is the share of the model's declines that were expected.
+func (a Abstention) Precision() float64 { return ratio(a.Correct, a.Declined) }
+
+// Recall is the share of expected declines the model made.
+func (a Abstention) Recall() float64 { return ratio(a.Correct, a.Expected) }
+
+// F1 is the harmonic mean of Precision and Recall.
+func (a Abstention) F1() float64 {
+	p, r := a.Precision(), a.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+// ForcedRate is the share of expected declines where the model forced a
+// tool call instead.
+func (a Abstention) ForcedRate() float64 { return ratio(a.Forced, a.Expected) }
+
+// KindAccuracy is the share of correct declines of the right kind.
+func (a Abstention) KindAccuracy() float64 { return ratio(a.Kind, a.Correct) }
+
+// AbstentionColumns names the values of Abstention.Values for a report
+// table.
+var AbstentionColumns = []string{"Precision", "Recall", "F1", "Forced Call", "Kind Acc"}
+
+// Values returns the rates named by AbstentionColumns.
+func (a Abstention) Values() []float64 {
+	return []float64{a.Precision(), a.Recall(), a.F1(), a.ForcedRate(), a.KindAccuracy()}
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,47 @@
+package toolcall
+
+import "testing"
+
+func TestNativeDecline(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    ActualCall
+	}{
+		{"refusal", " I can't order food, there is no tool for that.\n", ActualCall{Tool: NoTool, Reason: "I can't order food, there is no tool for that."}},
+		{"question", "Which room should I turn the lights off in?", ActualCall{Tool: AskClarification, Question: "Which room should I turn the lights off in?"}},
+		{"prose with braces", "There is no tool for {that}.", ActualCall{Tool: NoTool, Reason: "There is no tool for {that}."}},
+		{"empty", "", ActualCall{}},
+		{"whitespace", " \n\t", ActualCall{}},
+		{"call as text", `{"tool": "toggle_light", "parameters": {"room": "kitchen"}}`, ActualCall{}},
+		{"fenced call", "```json\n{\"tool\": \"no_tool\", \"reason\": \"no such device\"}\n```", ActualCall{}},
+		{"call after prose", `Sure! {"tool": "set_timer", "parameters": {"minutes": 5}}`, ActualCall{}},
+	}
+	for _, tt := range tests {
+		got := NativeDecline(tt.content)
+		if got.Tool != tt.want.Tool || got.Reason != tt.want.Reason || got.Question != tt.want.Question {
+			t.Errorf("%s: NativeDecline = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAbstention(t *testing.T) {
+	var a Abstention
+	for _, c := range []struct{ expected, actual string }{
+		{NoTool, NoTool},                         // correct, right kind
+		{AskClarification, NoTool},               // correct, wrong kind
+		{NoTool, "toggle_light"},                 // forced
+		{NoTool, ""},                             // no call at all
+		{"toggle_light", AskClarification},       // unexpected decline
+		{"toggle_light", NativeDecline("").Tool}, // empty native reply
+	} {
+		a.Add(c.expected, c.actual)
+	}
+	want := Abstention{Expected: 4, Declined: 3, Correct: 2, Forced: 1, Kind: 1}
+	if a != want {
+		t.Errorf("Abstention = %+v, want %+v", a, want)
+	}
+	if a.Precision() != 2.0/3 || a.Recall() != 0.5 || a.ForcedRate() != 0.25 || a.KindAccuracy() != 0.5 {
+		t.Errorf("rates = %v", a.Values())
+	}
+}
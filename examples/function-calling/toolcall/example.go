@@ -0,0 +1,88 @@
+package toolcall
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs every tool catalog through the bench interface. A case scores
+// on tool selection alone, matching the example's report; parameter accuracy
+// stays in the example's own score output.
+type Example struct{}
+
+type callCase struct {
+	System   string
+	Schema   *schema.Schema
+	Request  TestCase
+	Expected ExpectedCall
+}
+
+func (Example) Name() string { return "function-calling" }
+
+func (Example) Description() string {
+	return "Pick the right tool and parameters from a natural-language request"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	var cases []bench.Case
+	for _, scenario := range Scenarios {
+		var tools []ToolDef
+		var tests []TestCase
+		var expected []ExpectedCall
+		for path, v := range map[string]any{
+			filepath.Join(dir, "tools", scenario+".json"):    &tools,
+			filepath.Join(dir, "testdata", scenario+".json"): &tests,
+			filepath.Join(dir, "expected", scenario+".json"): &expected,
+		} {
+			if err := bench.LoadJSON(path, v); err != nil {
+				return nil, err
+			}
+		}
+
+		s, err := CallSchema(tools)
+		if err != nil {
+			return nil, fmt.Errorf("build call schema for %s: %w", scenario, err)
+		}
+		system := BuildSystemPrompt(tools)
+
+		expectedByID := make(map[string]ExpectedCall, len(expected))
+		for _, e := range expected {
+			expectedByID[e.ID] = e
+		}
+		for _, tc := range tests {
+			e, ok := expectedByID[tc.ID]
+			if !ok {
+				return nil, fmt.Errorf("no expected call for %s", tc.ID)
+			}
+			cases = append(cases, bench.Case{ID: tc.ID, Scenario: scenario, Data: callCase{system, s, tc, e}})
+		}
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	d := c.Data.(callCase)
+	return ollama.NewChatRequest("", d.System, d.Request.Request, true)
+}
+
+func (Example) Parse(_ bench.Case, raw string) (any, error) {
+	return ParseCall(raw)
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	d := c.Data.(callCase)
+	if ToolMatches(d.Expected.Tool, parsed.(ActualCall).Tool) {
+		return bench.Score{Quality: 1, Metric: "Tool Acc"}
+	}
+	return bench.Score{Metric: "Tool Acc"}
+}
+
+func (Example) Schema(c bench.Case) *schema.Schema {
+	return c.Data.(callCase).Schema
+}
@@ -0,0 +1,50 @@
+package toolcall
+
+import (
+	"github.com/statherm/local-llm-examples/shared/ollama"
+)
+
+// Modes of asking for a tool call. ModePrompt describes the catalog in the
+// system prompt and parses a JSON reply; ModeNative offers it through the
+// chat API's tools field and reads the structured tool calls the model's
+// template produces.
+const (
+	ModePrompt = "prompt"
+	ModeNative = "native"
+)
+
+// NativeSystemPrompt is the system prompt for ModeNative. The catalog and
+// reply format come from the tool definitions, so it only sets the task.
+const NativeSystemPrompt = "You are a function calling assistant. Given a user request, call the most appropriate tool with the correct parameters. Only include parameters that are relevant to the request. " +
+	"If no tool can do what the user asks, say so instead of calling one. If the request is too ambiguous to choose a tool or fill its required parameters, ask a clarifying question."
+
+// NativeTools converts a catalog to the chat API's tool definitions.
+func NativeTools(tools []ToolDef) []ollama.Tool {
+	out := make([]ollama.Tool, len(tools))
+	for i, t := range tools {
+		props := make(map[string]ollama.ToolProperty, len(t.Parameters))
+		for name, p := range t.Parameters {
+			props[name] = ollama.ToolProperty{Type: p.Type, Description: p.Description, Enum: p.Enum, Format: p.Format}
+		}
+		out[i] = ollama.NewTool(t.Name, t.Description, props, t.Required)
+	}
+	return out
+}
+
+// NewNativeRequest builds the ModeNative request for one user request. It
+// sets no JSON format, which would push most templates to answer in text.
+func NewNativeRequest(model string, tools []ollama.Tool, request string) ollama.ChatRequest {
+	req := ollama.NewChatRequest(model, NativeSystemPrompt, request, false)
+	req.Tools = tools
+	return req
+}
+
+// NativeCall returns the first tool call in msg, and false if the model
+// made none (see NativeDecline).
+func NativeCall(msg ollama.Message) (ActualCall, bool) {
+	if len(msg.ToolCalls) == 0 {
+		return ActualCall{}, false
+	}
+	fn := msg.ToolCalls[0].Function
+	return ActualCall{Tool: fn.Name, Parameters: fn.Arguments}, true
+}
@@ -0,0 +1,193 @@
+// Package toolcall holds the function-calling example's data types, system
+// prompt builder and scoring helpers, shared by the example's main and the
+// llmbench CLI.
+package toolcall
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Scenarios names the single-call tool catalogs under tools/, each with
+// matching testdata/ and expected/ files. ChainScenario is run separately.
+var Scenarios = []string{"developer", "home-automation"}
+
+// --- Data types ---
+
+type ToolDef struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Parameters  map[string]ToolParam `json:"parameters"`
+	Required    []string             `json:"required"`
+	// Fake holds canned results for the chains scenario (see FakeTools).
+	Fake []FakeResult `json:"fake,omitempty"`
+}
+
+type ToolParam struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// Enum lists the only values allowed, compared exactly; Format names a
+	// JSON Schema string format (date, date-time, time, email, uri).
+	Enum   []string `json:"enum,omitempty"`
+	Format string   `json:"format,omitempty"`
+}
+
+type TestCase struct {
+	ID      string `json:"id"`
+	Request string `json:"request"`
+}
+
+type ExpectedCall struct {
+	ID string `json:"id"`
+	// Tool is NoTool or AskClarification for a request the model should
+	// decline rather than call a tool for.
+	Tool       string         `json:"tool"`
+	Parameters map[string]any `json:"parameters"`
+}
+
+type ActualCall struct {
+	ID         string         `json:"id"`
+	Tool       string         `json:"tool"`
+	Parameters map[string]any `json:"parameters"`
+	RawOutput  string         `json:"raw_output,omitempty"`
+	// Reason and Question explain a NoTool or AskClarification reply.
+	Reason   string `json:"reason,omitempty"`
+	Question string `json:"question,omitempty"`
+	// SchemaValid records whether the raw output matched CallSchema: a known
+	// tool name and a parameters object.
+	SchemaValid *bool `json:"schema_valid,omitempty"`
+	// JSON records whether the output parsed as returned or needed repair.
+	JSON repair.Outcome `json:"json,omitempty"`
+	// Meta is the call's performance metadata, used by the report and the
+	// multi-model comparison.
+	Meta *types.ModelMetadata `json:"metadata,omitempty"`
+}
+
+// --- System prompt builder ---
+
+// BuildSystemPrompt lists the tool catalog and the expected JSON reply,
+// including the two ways to decline.
+func BuildSystemPrompt(tools []ToolDef) string {
+	var sb strings.Builder
+	sb.WriteString("You are a function calling assistant. Given a user request, select the most appropriate tool and provide the correct parameters.\n\n")
+	writeCatalog(&sb, tools)
+
+	sb.WriteString("Respond with JSON only: {\"tool\": \"tool_name\", \"parameters\": {...}}\n")
+	sb.WriteString("Only include parameters that are relevant to the request. Use the exact tool names shown above.\n")
+	sb.WriteString(fmt.Sprintf("If no tool can do what the user asks, respond {\"tool\": %q, \"reason\": \"...\"}.\n", NoTool))
+	sb.WriteString(fmt.Sprintf("If the request is too ambiguous to choose a tool or fill its required parameters, respond {\"tool\": %q, \"question\": \"...\"}.", AskClarification))
+
+	return sb.String()
+}
+
+// writeCatalog lists tools with their parameters for a system prompt.
+func writeCatalog(sb *strings.Builder, tools []ToolDef) {
+	sb.WriteString("Available tools:\n\n")
+
+	for _, t := range tools {
+		sb.WriteString(fmt.Sprintf("### %s\n%s\n", t.Name, t.Description))
+		if len(t.Parameters) > 0 {
+			sb.WriteString("Parameters:\n")
+			for name, param := range t.Parameters {
+				req := ""
+				for _, r := range t.Required {
+					if r == name {
+						req = " (required)"
+						break
+					}
+				}
+				sb.WriteString(fmt.Sprintf("  - %s (%s): %s%s%s\n", name, param.Type, param.Description, constraints(param), req))
+			}
+		}
+		sb.WriteString("\n")
+	}
+}
+
+// constraints describes the enum and format of p for the system prompt.
+func constraints(p ToolParam) string {
+	var s string
+	if len(p.Enum) > 0 {
+		s += fmt.Sprintf(" [one of: %s]", strings.Join(p.Enum, ", "))
+	}
+	if p.Format != "" {
+		s += fmt.Sprintf(" [format: %s]", p.Format)
+	}
+	return s
+}
+
+// CallSchema builds the JSON Schema for the {"tool", "parameters"} object the
+// system prompt asks for, restricting the tool name to the catalog and the
+// two declines, which carry a reason or question instead of parameters.
+func CallSchema(tools []ToolDef) (*schema.Schema, error) {
+	names := make([]string, len(tools), len(tools)+2)
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	names = append(names, NoTool, AskClarification)
+	data, err := json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"tool":       map[string]any{"type": "string", "enum": names},
+			"parameters": map[string]any{"type": "object"},
+			"reason":     map[string]any{"type": "string"},
+			"question":   map[string]any{"type": "string"},
+		},
+		"required": []string{"tool"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schema.Parse(data)
+}
+
+// ParseCall decodes a {"tool", "parameters"} response, tolerating code
+// fences, reasoning blocks and trailing commas (see shared/repair).
+func ParseCall(resp string) (ActualCall, error) {
+	var call ActualCall
+	if err := repair.Unmarshal(resp, &call); err != nil {
+		return ActualCall{}, err
+	}
+	return call, nil
+}
+
+// ToolMatches reports whether two tool names are equal, ignoring case and
+// surrounding whitespace.
+func ToolMatches(expected, actual string) bool {
+	return strings.EqualFold(strings.TrimSpace(expected), strings.TrimSpace(actual))
+}
+
+// normalizeTool keys a tool name so names that ToolMatches equates look up
+// the same entry.
+func normalizeTool(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ParametersMatch reports whether every expected parameter is present in
+// actual with an equal value. Extra parameters in actual are ignored.
+func ParametersMatch(expected, actual map[string]any) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	for key, expVal := range expected {
+		actVal, ok := actual[key]
+		if !ok {
+			return false
+		}
+		if !valuesMatch(expVal, actVal) {
+			return false
+		}
+	}
+	return true
+}
+
+func valuesMatch(expected, actual any) bool {
+	// Normalize numbers: JSON unmarshals all numbers as float64
+	expStr := fmt.Sprintf("%v", expected)
+	actStr := fmt.Sprintf("%v", actual)
+	return strings.EqualFold(strings.TrimSpace(expStr), strings.TrimSpace(actStr))
+}
@@ -0,0 +1,167 @@
+package toolcall
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+// Validation describes how a call breaks the definition of the tool it
+// names. Unlike ParametersMatch, which compares against the expected call,
+// it needs no ground truth: these are the errors a production caller would
+// reject before running the tool.
+type Validation struct {
+	// UnknownTool is set when the tool is not in the catalog; the parameter
+	// checks are then skipped.
+	UnknownTool     bool     `json:"unknown_tool,omitempty"`
+	MissingRequired []string `json:"missing_required,omitempty"`
+	WrongType       []string `json:"wrong_type,omitempty"`
+	// Hallucinated lists parameters the tool does not declare.
+	Hallucinated []string `json:"hallucinated,omitempty"`
+	// InvalidValue lists enum and format violations as "name: reason".
+	InvalidValue []string `json:"invalid_value,omitempty"`
+}
+
+// OK reports whether the call satisfies its tool's definition.
+func (v Validation) OK() bool {
+	return !v.UnknownTool && len(v.MissingRequired) == 0 && len(v.WrongType) == 0 &&
+		len(v.Hallucinated) == 0 && len(v.InvalidValue) == 0
+}
+
+// Validator checks calls against a tool catalog.
+type Validator struct {
+	tools  map[string]ToolDef
+	params map[string]map[string]*schema.Schema // tool -> parameter -> value constraints
+}
+
+// NewValidator compiles the parameter constraints of tools.
+func NewValidator(tools []ToolDef) (*Validator, error) {
+	v := &Validator{tools: make(map[string]ToolDef), params: make(map[string]map[string]*schema.Schema)}
+	for _, t := range tools {
+		key := normalizeTool(t.Name)
+		v.tools[key] = t
+		v.params[key] = make(map[string]*schema.Schema)
+		for name, p := range t.Parameters {
+			c := map[string]any{}
+			if len(p.Enum) > 0 {
+				c["enum"] = p.Enum
+			}
+			if p.Format != "" {
+				c["format"] = p.Format
+			}
+			data, err := json.Marshal(c)
+			if err != nil {
+				return nil, err
+			}
+			s, err := schema.Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name, name, err)
+			}
+			v.params[key][name] = s
+		}
+	}
+	return v, nil
+}
+
+// Validate checks a call of tool with params. Tool names match as in
+// ToolMatches; parameter names and enum values must match exactly. A
+// parameter of the wrong type is not checked against its constraints.
+func (v *Validator) Validate(tool string, params map[string]any) Validation {
+	key := normalizeTool(tool)
+	def, ok := v.tools[key]
+	if !ok {
+		return Validation{UnknownTool: true}
+	}
+
+	var out Validation
+	for _, name := range def.Required {
+		if _, ok := params[name]; !ok {
+			out.MissingRequired = append(out.MissingRequired, name)
+		}
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, ok := def.Parameters[name]
+		if !ok {
+			out.Hallucinated = append(out.Hallucinated, name)
+			continue
+		}
+		val := params[name]
+		if !schema.TypeMatches(val, p.Type) {
+			out.WrongType = append(out.WrongType, fmt.Sprintf("%s: expected %s, got %s", name, p.Type, schema.TypeOf(val)))
+			continue
+		}
+		for _, violation := range v.params[key][name].Validate(val) {
+			out.InvalidValue = append(out.InvalidValue, name+": "+violation.Message)
+		}
+	}
+	return out
+}
+
+// ValidationRates counts the calls with each kind of problem. Parameter
+// problems are counted over calls to known tools only.
+type ValidationRates struct {
+	Calls           int `json:"calls"`
+	KnownCalls      int `json:"known_calls"`
+	UnknownTool     int `json:"unknown_tool"`
+	MissingRequired int `json:"missing_required"`
+	WrongType       int `json:"wrong_type"`
+	Hallucinated    int `json:"hallucinated"`
+	InvalidValue    int `json:"invalid_value"`
+}
+
+// Add counts one call's validation.
+func (r *ValidationRates) Add(v Validation) {
+	r.Calls++
+	if v.UnknownTool {
+		r.UnknownTool++
+		return
+	}
+	r.KnownCalls++
+	if len(v.MissingRequired) > 0 {
+		r.MissingRequired++
+	}
+	if len(v.WrongType) > 0 {
+		r.WrongType++
+	}
+	if len(v.Hallucinated) > 0 {
+		r.Hallucinated++
+	}
+	if len(v.InvalidValue) > 0 {
+		r.InvalidValue++
+	}
+}
+
+// Merge adds the counts of o to r.
+func (r *ValidationRates) Merge(o ValidationRates) {
+	r.Calls += o.Calls
+	r.KnownCalls += o.KnownCalls
+	r.UnknownTool += o.UnknownTool
+	r.MissingRequired += o.MissingRequired
+	r.WrongType += o.WrongType
+	r.Hallucinated += o.Hallucinated
+	r.InvalidValue += o.InvalidValue
+}
+
+// Rates returns the unknown-tool rate over all calls, then the
+// missing-required, wrong-type, hallucinated-parameter and invalid-value
+// rates over calls to known tools, each in [0, 1].
+func (r ValidationRates) Rates() []float64 {
+	return []float64{
+		ratio(r.UnknownTool, r.Calls),
+		ratio(r.MissingRequired, r.KnownCalls),
+		ratio(r.WrongType, r.KnownCalls),
+		ratio(r.Hallucinated, r.KnownCalls),
+		ratio(r.InvalidValue, r.KnownCalls),
+	}
+}
+
+// ValidationColumns names the values of Rates for a report table.
+var ValidationColumns = []string{"Hallucinated Tool", "Missing Required", "Wrong Type", "Hallucinated Param", "Invalid Value"}
@@ -1,259 +1,516 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
+	"github.com/statherm/local-llm-examples/examples/search-reranking/rerank"
+	"github.com/statherm/local-llm-examples/shared/bench"
 	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
 	"github.com/statherm/local-llm-examples/shared/reporting"
+	"github.com/statherm/local-llm-examples/shared/runner"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+	"github.com/statherm/local-llm-examples/shared/sweep"
 	"github.com/statherm/local-llm-examples/shared/types"
 )
 
-// SearchCandidate is a single search result to be reranked.
-type SearchCandidate struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Snippet string `json:"snippet"`
-}
-
-// SearchQuery is the input: a query plus candidate results.
-type SearchQuery struct {
-	Query      string            `json:"query"`
-	Candidates []SearchCandidate `json:"candidates"`
-}
-
-// GoldRanking is the expected relevance for a candidate.
-type GoldRanking struct {
-	ID        string `json:"id"`
-	Relevance int    `json:"relevance"`
-	Reason    string `json:"reason"`
-}
-
-// GoldStandard is the full ground truth for a query.
-type GoldStandard struct {
-	Query   string        `json:"query"`
-	Ranking []GoldRanking `json:"ranking"`
-}
-
-// RankedResult is the model's output: a candidate ID with a relevance score.
-type RankedResult struct {
-	ID    string  `json:"id"`
-	Score float64 `json:"score"`
-}
-
-// RerankedOutput is the model's full response parsed from JSON.
-type RerankedOutput struct {
-	Rankings []RankedResult `json:"rankings"`
-}
-
 // ScenarioResult stores the outcome for one test scenario.
 type ScenarioResult struct {
-	Scenario string           `json:"scenario"`
-	Model    string           `json:"model"`
-	NDCG     float64          `json:"ndcg"`
-	MRR      float64          `json:"mrr"`
-	Rankings []RankedResult   `json:"rankings"`
-	Meta     types.ModelMetadata `json:"metadata"`
+	Scenario string                `json:"scenario"`
+	Model    string                `json:"model"`
+	NDCG     float64               `json:"ndcg"`
+	MRR      float64               `json:"mrr"`
+	Rankings []rerank.RankedResult `json:"rankings"`
+	Meta     types.ModelMetadata   `json:"metadata"`
+	// Metrics holds every ranking metric; NDCG and MRR repeat two of them.
+	Metrics *rerank.Metrics `json:"metrics,omitempty"`
+	// Strategy is the rerank strategy that produced Rankings; empty means
+	// rerank.StrategyPointwise.
+	Strategy string `json:"strategy,omitempty"`
+	// Calls is the number of model calls the ranking took, Validation what
+	// was wrong with the IDs in the replies, and Coverage the fraction of
+	// candidates the replies ranked (see rerank.ValidateRankings).
+	Calls      int                `json:"calls,omitempty"`
+	Validation *rerank.Validation `json:"validation,omitempty"`
+	Coverage   *float64           `json:"coverage,omitempty"`
+	// Permutations is set when Rankings aggregates several shuffled
+	// candidate orders.
+	Permutations *rerank.PermutationStats `json:"permutations,omitempty"`
+	// SchemaValid records whether the raw output matched rerank.RankingSchema.
+	SchemaValid *bool `json:"schema_valid,omitempty"`
+	// JSON records whether the output parsed as returned or needed repair.
+	JSON repair.Outcome `json:"json,omitempty"`
+	// Error is why the model call failed, after any retries. A failed
+	// scenario has no rankings and scores zero.
+	Error string `json:"error,omitempty"`
 }
 
-const systemPrompt = `You are a search result reranking system. Given a search query and a list of candidate results, score each result's relevance to the query.
+func main() {
+	model := flag.String("model", "qwen3:4b", "Ollama model to use")
+	modelList := flag.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile := flag.String("models-file", "", "File listing models to run and compare, one per line")
+	doScore := flag.Bool("score", false, "Score existing results against gold standard")
+	doReport := flag.Bool("report", false, "Generate benchmark report from results")
+	parallel := flag.Int("parallel", 1, "Number of concurrent model requests")
+	conn := provider.AddFlags(flag.CommandLine)
+	pricingFile := flag.String("pricing", "", "Pricing table for the Cost/Call column (default: pricing.json in this or a parent directory)")
+	reasks := flag.Int("reask", 0, "Times to re-ask the model with the parse error when its JSON cannot be repaired")
+	strategy := flag.String("strategy", rerank.StrategyPointwise, "Reranking strategy: pointwise (model scores each candidate), listwise (model orders a sliding window), pairwise (model compares two at a time) or embedding (cosine similarity of embeddings)")
+	window := flag.Int("window", rerank.DefaultWindow, "Candidates per listwise call")
+	step := flag.Int("step", rerank.DefaultStep, "Candidates the listwise window moves per call")
+	topK := flag.Int("top-k", rerank.DefaultTopK, "Positions pairwise bubble passes sort")
+	embedModel := flag.String("embed-model", rerank.DefaultEmbedModel, "Embedding model for -strategy embedding (replaces -model and -models)")
+	permutations := flag.Int("permutations", 0, "Re-run each query with this many shuffled candidate orders and aggregate the rankings (0 keeps the fixture order)")
+	permSeed := flag.Uint64("perm-seed", 1, "Seed for the -permutations shuffles")
+	aggregate := flag.String("aggregate", rerank.AggregateMean, "How to combine -permutations rankings: mean (mean score) or borda (mean position)")
+	flag.Parse()
 
-For each candidate, assign a relevance score from 0.0 to 1.0:
-- 1.0 = directly and completely answers the query
-- 0.7-0.9 = highly relevant, addresses the core topic
-- 0.4-0.6 = somewhat relevant, related topic but not a direct answer
-- 0.1-0.3 = tangentially related, shares some keywords
-- 0.0 = completely irrelevant
+	exampleDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-Respond with valid JSON in this exact format:
-{"rankings": [{"id": "<candidate-id>", "score": <0.0-1.0>}, ...]}`
+	scenarios, err := rerank.LoadScenarios(exampleDir)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func buildPrompt(query SearchQuery) string {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Query: %s\n\nCandidate results:\n\n", query.Query))
-	for i, c := range query.Candidates {
-		sb.WriteString(fmt.Sprintf("[%d] ID: %s\nTitle: %s\nSnippet: %s\n\n", i+1, c.ID, c.Title, c.Snippet))
+	prices, err := pricing.Load(*pricingFile, exampleDir)
+	if err != nil {
+		log.Fatalf("load pricing: %v", err)
 	}
-	sb.WriteString("Score each candidate's relevance to the query. Return JSON with all candidate IDs and their scores.")
-	return sb.String()
-}
 
-// ndcg computes Normalized Discounted Cumulative Gain.
-// modelRanking is the ordered list of IDs from the model.
-// goldRelevance maps each ID to its gold-standard relevance grade.
-func ndcg(modelRanking []string, goldRelevance map[string]int, k int) float64 {
-	if k <= 0 || len(modelRanking) == 0 {
-		return 0
+	if *doScore {
+		scoreResults(exampleDir, scenarios)
+		return
 	}
-	if k > len(modelRanking) {
-		k = len(modelRanking)
+
+	if *doReport {
+		generateReport(exampleDir, scenarios, prices)
+		return
 	}
 
-	// DCG of model ranking
-	dcg := 0.0
-	for i := 0; i < k; i++ {
-		rel := float64(goldRelevance[modelRanking[i]])
-		dcg += (math.Pow(2, rel) - 1) / math.Log2(float64(i+2))
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		log.Fatalf("resolve models: %v", err)
 	}
 
-	// Ideal DCG: sort by gold relevance descending
-	idealOrder := make([]int, 0, len(goldRelevance))
-	for _, rel := range goldRelevance {
-		idealOrder = append(idealOrder, rel)
+	client, err := conn.New()
+	if err != nil {
+		log.Fatalf("create client: %v", err)
 	}
-	sort.Sort(sort.Reverse(sort.IntSlice(idealOrder)))
 
-	idcg := 0.0
-	for i := 0; i < k && i < len(idealOrder); i++ {
-		rel := float64(idealOrder[i])
-		idcg += (math.Pow(2, rel) - 1) / math.Log2(float64(i+2))
+	switch *strategy {
+	case rerank.StrategyPointwise, rerank.StrategyListwise, rerank.StrategyPairwise:
+	case rerank.StrategyEmbedding:
+		if _, ok := client.(provider.Embedder); !ok {
+			log.Fatalf("the %s provider does not support embeddings", conn.Name)
+		}
+		models = []string{*embedModel}
+	default:
+		log.Fatalf("unknown -strategy %q (want one of %s)", *strategy, strings.Join(rerank.Strategies, ", "))
+	}
+	if *permutations > 0 {
+		if *strategy == rerank.StrategyEmbedding {
+			log.Fatalf("-permutations has no effect with -strategy embedding, which does not depend on candidate order")
+		}
+		if *aggregate != rerank.AggregateMean && *aggregate != rerank.AggregateBorda {
+			log.Fatalf("unknown -aggregate %q (want %s or %s)", *aggregate, rerank.AggregateMean, rerank.AggregateBorda)
+		}
+	}
+	for _, m := range models {
+		if err := bench.Preflight(context.Background(), client, m, conn.Pull, exampleDir); err != nil {
+			log.Fatalf("preflight: %v", err)
+		}
 	}
 
-	if idcg == 0 {
-		return 0
+	queries := make([]rerank.SearchQuery, len(scenarios))
+	for i, sc := range scenarios {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, sc.Input), &queries[i]); err != nil {
+			log.Fatalf("load input: %v", err)
+		}
 	}
-	return dcg / idcg
-}
 
-// mrr computes Mean Reciprocal Rank. It finds the rank of the first
-// highly relevant result (relevance >= threshold) in the model's ranking.
-func mrr(modelRanking []string, goldRelevance map[string]int, threshold int) float64 {
-	for i, id := range modelRanking {
-		if goldRelevance[id] >= threshold {
-			return 1.0 / float64(i+1)
+	for _, model := range models {
+		switch {
+		case *permutations > 0:
+			rank := ranker(client, model, *strategy, *reasks, *window, *step, *topK)
+			runPermutations(rank, model, *strategy, exampleDir, scenarios, queries, *parallel, *permutations, *permSeed, *aggregate)
+		case *strategy == rerank.StrategyEmbedding:
+			runEmbedding(client.(provider.Embedder), model, exampleDir, scenarios, queries, *parallel)
+		case *strategy == rerank.StrategyListwise, *strategy == rerank.StrategyPairwise:
+			rank := ranker(client, model, *strategy, *reasks, *window, *step, *topK)
+			runMultiCall(rank, model, *strategy, exampleDir, scenarios, queries, *parallel)
+		default:
+			runPointwise(client, model, exampleDir, scenarios, queries, *parallel, *reasks)
 		}
 	}
-	return 0
-}
 
-func loadJSON(path string, v interface{}) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read %s: %w", path, err)
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(prices.Apply(sweep.Only(bench.WithModelInfo(exampleDir, benchmarkResults(exampleDir, scenarios)), models, func(m string) string {
+			return sanitizeModelName(types.BaseModel(m))
+		}))))
 	}
-	return json.Unmarshal(data, v)
 }
 
-func main() {
-	model := flag.String("model", "qwen3:4b", "Ollama model to use")
-	doScore := flag.Bool("score", false, "Score existing results against gold standard")
-	doReport := flag.Bool("report", false, "Generate benchmark report from results")
-	flag.Parse()
+// runPointwise asks model for a relevance score per candidate of every query.
+func runPointwise(client provider.Provider, model, exampleDir string, scenarios []rerank.Scenario, queries []rerank.SearchQuery, parallel, reasks int) {
+	// Model calls run concurrently; parsing, scoring and printing happen
+	// afterwards in scenario order so the output stays readable.
+	runs, summary := runner.Run(context.Background(), queries, parallel, func(ctx context.Context, i int, query rerank.SearchQuery) (repair.Reply, error) {
+		req := ollama.NewChatRequest(model, rerank.SystemPrompt, rerank.BuildPrompt(query), true, scenarios[i].MaxTokens)
+		return repair.Complete(ctx, client, req, reasks)
+	})
+
+	for i, sc := range scenarios {
+		fmt.Printf("=== Scenario: %s (model: %s) ===\n", sc.Name, model)
+
+		if runs[i].Err != nil {
+			log.Printf("WARNING: model call failed: %v", runs[i].Err)
+			saveFailure(exampleDir, ScenarioResult{
+				Scenario: sc.Name,
+				Model:    model,
+				Meta:     runs[i].Value.Meta,
+				Calls:    1 + runs[i].Value.Reasks,
+			}, runs[i].Err)
+			continue
+		}
+		reply := runs[i].Value
+		response, meta := reply.Raw, reply.Meta
+		meta.QueueTime = runs[i].Queue
 
-	exampleDir, err := os.Getwd()
-	if err != nil {
-		log.Fatal(err)
+		violations := rerank.RankingSchema.ValidateJSON([]byte(response))
+		valid := len(violations) == 0
+
+		result := ScenarioResult{
+			Scenario:    sc.Name,
+			Model:       model,
+			Meta:        meta,
+			SchemaValid: &valid,
+			JSON:        reply.Outcome,
+			Calls:       1 + reply.Reasks,
+		}
+
+		rankings, err := rerank.ParseRankings(reply.Text)
+		if err != nil {
+			// Saved with zero scores so the scenario still counts
+			// against the model in the report.
+			log.Printf("WARNING: failed to parse model output as JSON: %v", err)
+			log.Printf("Raw response: %s", response)
+			n := len(queries[i].Candidates)
+			result.Validation = &rerank.Validation{Candidates: n, Missing: n, InvalidReplies: 1}
+			saveResult(exampleDir, result)
+			continue
+		}
+		rankings, v := rerank.ValidateRankings(rankings, queries[i].Candidates)
+		result.Rankings, result.Validation = rankings, &v
+
+		fmt.Printf("  Schema:  valid=%v", valid)
+		if !valid {
+			fmt.Printf(" (%s)", violations[0])
+		}
+		fmt.Println()
+		fmt.Printf("  JSON:    %s (re-asks: %d)\n", reply.Outcome, reply.Reasks)
+		finish(exampleDir, sc, result)
 	}
 
-	scenarios := []struct {
-		name     string
-		input    string
-		gold     string
-	}{
-		{"doc_search", "testdata/doc_search.json", "baseline/doc_search_gold.json"},
-		{"code_search", "testdata/code_search.json", "baseline/code_search_gold.json"},
-		{"api_search", "testdata/api_search.json", "baseline/api_search_gold.json"},
+	var totalTokensOut int
+	for _, r := range runs {
+		totalTokensOut += r.Value.Meta.TokensOut
 	}
+	fmt.Printf("%s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(totalTokensOut))
+}
 
-	if *doScore {
-		scoreResults(exampleDir, scenarios)
-		return
+// embedRun is the outcome of embedding one query and its candidates.
+type embedRun struct {
+	Vectors [][]float64
+	Meta    types.ModelMetadata
+}
+
+// runEmbedding ranks the candidates of every query by the cosine similarity
+// of their embeddings to the query's, one /api/embed call per query.
+func runEmbedding(client provider.Embedder, model, exampleDir string, scenarios []rerank.Scenario, queries []rerank.SearchQuery, parallel int) {
+	runs, summary := runner.Run(context.Background(), queries, parallel, func(ctx context.Context, _ int, query rerank.SearchQuery) (embedRun, error) {
+		vectors, meta, err := client.Embed(ctx, model, rerank.EmbeddingInput(query))
+		return embedRun{vectors, meta}, err
+	})
+
+	for i, sc := range scenarios {
+		fmt.Printf("=== Scenario: %s (model: %s, embedding) ===\n", sc.Name, model)
+
+		if runs[i].Err != nil {
+			log.Printf("WARNING: embedding call failed: %v", runs[i].Err)
+			saveFailure(exampleDir, ScenarioResult{
+				Scenario: sc.Name,
+				Model:    model,
+				Meta:     runs[i].Value.Meta,
+				Strategy: rerank.StrategyEmbedding,
+				Calls:    1,
+			}, runs[i].Err)
+			continue
+		}
+		meta := runs[i].Value.Meta
+		meta.QueueTime = runs[i].Queue
+
+		finish(exampleDir, sc, ScenarioResult{
+			Scenario: sc.Name,
+			Model:    model,
+			Rankings: rerank.RankByEmbedding(queries[i], runs[i].Value.Vectors),
+			Meta:     meta,
+			Strategy: rerank.StrategyEmbedding,
+			Calls:    1,
+		})
 	}
+	fmt.Println(summary)
+}
 
-	if *doReport {
-		generateReport(exampleDir)
-		return
+// runMultiCall reranks every query with the listwise or pairwise strategy.
+// Queries run concurrently; the calls within one query are sequential.
+func runMultiCall(rank rankFunc, model, strategy, exampleDir string, scenarios []rerank.Scenario, queries []rerank.SearchQuery, parallel int) {
+	runs, summary := runner.Run(context.Background(), queries, parallel, func(ctx context.Context, i int, query rerank.SearchQuery) (rerank.StrategyRun, error) {
+		return rank(ctx, query, scenarios[i].MaxTokens)
+	})
+
+	for i, sc := range scenarios {
+		fmt.Printf("=== Scenario: %s (model: %s, %s) ===\n", sc.Name, model, strategy)
+
+		if runs[i].Err != nil {
+			log.Printf("WARNING: model call failed after %d calls: %v", runs[i].Value.Calls, runs[i].Err)
+			saveFailure(exampleDir, ScenarioResult{
+				Scenario: sc.Name,
+				Model:    model,
+				Meta:     runs[i].Value.Meta,
+				Strategy: strategy,
+				Calls:    runs[i].Value.Calls,
+			}, runs[i].Err)
+			continue
+		}
+		run := runs[i].Value
+		meta := run.Meta
+		meta.QueueTime = runs[i].Queue
+
+		fmt.Printf("  Calls:   %d\n", run.Calls)
+		finish(exampleDir, sc, ScenarioResult{
+			Scenario:   sc.Name,
+			Model:      model,
+			Rankings:   run.Rankings,
+			Meta:       meta,
+			Strategy:   strategy,
+			Calls:      run.Calls,
+			Validation: &run.Validation,
+		})
 	}
 
-	client := ollama.NewClient()
+	var totalTokensOut int
+	for _, r := range runs {
+		totalTokensOut += r.Value.Meta.TokensOut
+	}
+	fmt.Printf("%s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(totalTokensOut))
+}
 
-	for _, sc := range scenarios {
-		fmt.Printf("=== Scenario: %s (model: %s) ===\n", sc.name, *model)
+// rankFunc reranks one query, whose candidates may have been shuffled.
+type rankFunc func(ctx context.Context, query rerank.SearchQuery, maxTokens int) (rerank.StrategyRun, error)
 
-		var query SearchQuery
-		if err := loadJSON(filepath.Join(exampleDir, sc.input), &query); err != nil {
-			log.Fatalf("load input: %v", err)
+// ranker returns the rankFunc for a model-based strategy. A pointwise reply
+// that cannot be parsed counts as an invalid reply with no rankings.
+func ranker(client provider.Provider, model, strategy string, reasks, window, step, topK int) rankFunc {
+	switch strategy {
+	case rerank.StrategyListwise:
+		return func(ctx context.Context, query rerank.SearchQuery, maxTokens int) (rerank.StrategyRun, error) {
+			return rerank.Listwise(ctx, client, model, query, window, step, maxTokens)
 		}
-
-		prompt := buildPrompt(query)
-		response, meta, err := client.ChatCompletion(*model, systemPrompt, prompt, true)
+	case rerank.StrategyPairwise:
+		return func(ctx context.Context, query rerank.SearchQuery, _ int) (rerank.StrategyRun, error) {
+			return rerank.Pairwise(ctx, client, model, query, topK)
+		}
+	}
+	return func(ctx context.Context, query rerank.SearchQuery, maxTokens int) (rerank.StrategyRun, error) {
+		req := ollama.NewChatRequest(model, rerank.SystemPrompt, rerank.BuildPrompt(query), true, maxTokens)
+		reply, err := repair.Complete(ctx, client, req, reasks)
+		run := rerank.StrategyRun{Calls: 1 + reply.Reasks, Meta: reply.Meta}
 		if err != nil {
-			log.Fatalf("ollama: %v", err)
+			return run, err
 		}
-
-		var output RerankedOutput
-		if err := json.Unmarshal([]byte(response), &output); err != nil {
-			log.Printf("WARNING: failed to parse model output as JSON: %v", err)
-			log.Printf("Raw response: %s", response)
-			continue
+		rankings, err := rerank.ParseRankings(reply.Text)
+		if err != nil {
+			n := len(query.Candidates)
+			run.Validation = rerank.Validation{Candidates: n, Missing: n, InvalidReplies: 1}
+			return run, nil
 		}
+		run.Rankings, run.Validation = rerank.ValidateRankings(rankings, query.Candidates)
+		return run, nil
+	}
+}
 
-		// Sort by score descending
-		sort.Slice(output.Rankings, func(i, j int) bool {
-			return output.Rankings[i].Score > output.Rankings[j].Score
-		})
+// permJob is one shuffled candidate order of a scenario's query.
+type permJob struct {
+	Scenario int
+	Query    rerank.SearchQuery
+}
 
-		result := ScenarioResult{
-			Scenario: sc.name,
-			Model:    *model,
-			Rankings: output.Rankings,
-			Meta:     meta,
+// runPermutations reranks count shuffled orders of every query and
+// aggregates each query's rankings into one result. Its tokens, latency and
+// calls cover all permutations; its Permutations field records how NDCG@10
+// varied between them, which separates position bias from the model's
+// relevance judgment.
+func runPermutations(rank rankFunc, model, strategy, exampleDir string, scenarios []rerank.Scenario, queries []rerank.SearchQuery, parallel, count int, seed uint64, aggregate string) {
+	var jobs []permJob
+	for i, query := range queries {
+		for n := 0; n < count; n++ {
+			jobs = append(jobs, permJob{Scenario: i, Query: rerank.Shuffle(query, seed, n)})
 		}
+	}
+	runs, summary := runner.Run(context.Background(), jobs, parallel, func(ctx context.Context, _ int, job permJob) (rerank.StrategyRun, error) {
+		return rank(ctx, job.Query, scenarios[job.Scenario].MaxTokens)
+	})
+
+	for i, sc := range scenarios {
+		fmt.Printf("=== Scenario: %s (model: %s, %s, %d permutations) ===\n", sc.Name, model, strategy, count)
 
-		// Score against gold standard
-		var gold GoldStandard
-		if err := loadJSON(filepath.Join(exampleDir, sc.gold), &gold); err != nil {
+		var gold rerank.GoldStandard
+		if err := bench.LoadJSON(filepath.Join(exampleDir, sc.Expected), &gold); err != nil {
 			log.Printf("WARNING: could not load gold standard: %v", err)
-		} else {
-			goldRel := make(map[string]int)
-			for _, g := range gold.Ranking {
-				goldRel[g.ID] = g.Relevance
+			continue
+		}
+
+		var (
+			rankings   [][]rerank.RankedResult
+			ndcg, taus []float64
+			failed     int
+			metas      []types.ModelMetadata
+			calls      int
+			validation rerank.Validation
+		)
+		for j, job := range jobs {
+			if job.Scenario != i {
+				continue
 			}
-			modelOrder := make([]string, len(output.Rankings))
-			for i, r := range output.Rankings {
-				modelOrder[i] = r.ID
+			run := runs[j].Value
+			metas = append(metas, run.Meta)
+			calls += run.Calls
+			validation.Add(run.Validation)
+			if runs[j].Err != nil || len(run.Rankings) == 0 {
+				if runs[j].Err != nil {
+					log.Printf("WARNING: permutation failed: %v", runs[j].Err)
+				}
+				failed++
+				continue
 			}
-			result.NDCG = ndcg(modelOrder, goldRel, 10)
-			result.MRR = mrr(modelOrder, goldRel, 3)
+			rankings = append(rankings, run.Rankings)
+			ndcg = append(ndcg, scoring.NDCG(rerank.Order(run.Rankings), gold.Relevance(), 10))
+			taus = append(taus, rerank.PositionTau(job.Query, run.Rankings))
 		}
-
-		fmt.Printf("  NDCG@10: %.3f\n", result.NDCG)
-		fmt.Printf("  MRR:     %.3f\n", result.MRR)
-		fmt.Printf("  Tokens:  %d in / %d out (%.1f tok/s)\n", meta.TokensIn, meta.TokensOut, meta.TokensPerSec)
-		fmt.Printf("  Latency: %s (TTFT: %s)\n", meta.TotalTime, meta.TTFT)
-		fmt.Println("  Top 5 results:")
-		for i := 0; i < 5 && i < len(output.Rankings); i++ {
-			r := output.Rankings[i]
-			fmt.Printf("    %d. %s (score: %.2f)\n", i+1, r.ID, r.Score)
+		stats := rerank.NewPermutationStats(seed, aggregate, ndcg, taus, failed)
+
+		fmt.Printf("  Permutations: %d ok, %d failed; NDCG@10 mean %.3f, std dev %.3f (min %.3f, max %.3f)\n",
+			len(ndcg), failed, stats.Mean, stats.StdDev, stats.Min, stats.Max)
+		fmt.Printf("  Position tau: %.3f\n", stats.PositionTau)
+		if len(rankings) == 0 {
+			log.Printf("WARNING: no permutation produced a ranking")
+			saveFailure(exampleDir, ScenarioResult{
+				Scenario:     sc.Name,
+				Model:        model,
+				Meta:         types.SumMetadata(metas...),
+				Strategy:     strategy,
+				Calls:        calls,
+				Validation:   &validation,
+				Permutations: &stats,
+			}, fmt.Errorf("all %d permutations failed", failed))
+			continue
 		}
-		fmt.Println()
+		fmt.Printf("  Aggregated (%s):\n", aggregate)
+		finish(exampleDir, sc, ScenarioResult{
+			Scenario:     sc.Name,
+			Model:        model,
+			Rankings:     rerank.Aggregate(aggregate, queries[i], rankings),
+			Meta:         types.SumMetadata(metas...),
+			Strategy:     strategy,
+			Calls:        calls,
+			Validation:   &validation,
+			Permutations: &stats,
+		})
+	}
 
-		// Save result
-		resultPath := filepath.Join(exampleDir, "results", fmt.Sprintf("%s_%s.json", sc.name, sanitizeModelName(*model)))
-		resultData, _ := json.MarshalIndent(result, "", "  ")
-		if err := os.WriteFile(resultPath, resultData, 0644); err != nil {
-			log.Printf("WARNING: could not write result: %v", err)
-		}
+	var totalTokensOut int
+	for _, r := range runs {
+		totalTokensOut += r.Value.Meta.TokensOut
+	}
+	fmt.Printf("%s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(totalTokensOut))
+}
+
+// finish scores result against the scenario's gold standard, prints the
+// metrics and top results, and saves it.
+func finish(exampleDir string, sc rerank.Scenario, result ScenarioResult) {
+	var gold rerank.GoldStandard
+	if err := bench.LoadJSON(filepath.Join(exampleDir, sc.Expected), &gold); err != nil {
+		log.Printf("WARNING: could not load gold standard: %v", err)
+	} else {
+		m := rerank.Evaluate(result.Rankings, gold)
+		result.Metrics = &m
+		result.NDCG, result.MRR = m.At(10).NDCG, m.MRR
+	}
+
+	meta := result.Meta
+	if v := result.Validation; v != nil {
+		fmt.Printf("  Replies: %s (coverage %.1f%%)\n", v, v.Coverage()*100)
+	}
+	fmt.Printf("  NDCG@10: %.3f\n", result.NDCG)
+	fmt.Printf("  MRR:     %.3f\n", result.MRR)
+	if m := result.Metrics; m != nil {
+		fmt.Printf("  MAP:     %.3f  (P@5 %.3f, R@10 %.3f)\n", m.AveragePrecision, m.At(5).Precision, m.At(10).Recall)
+		fmt.Printf("  Tau:     %.3f  (Spearman %.3f)\n", m.KendallTau, m.Spearman)
+	}
+	fmt.Printf("  Tokens:  %d in / %d out (%.1f tok/s)\n", meta.TokensIn, meta.TokensOut, meta.TokensPerSec)
+	fmt.Printf("  Latency: %s (TTFT: %s)\n", meta.TotalTime, meta.TTFT)
+	fmt.Println("  Top 5 results:")
+	for i := 0; i < 5 && i < len(result.Rankings); i++ {
+		r := result.Rankings[i]
+		fmt.Printf("    %d. %s (score: %.2f)\n", i+1, r.ID, r.Score)
+	}
+	fmt.Println()
+
+	saveResult(exampleDir, result)
+}
+
+// saveResult writes one scenario result to the results directory. Results
+// of other strategies or of permutation runs get their variant as a suffix
+// so they sit alongside the same model's plain pointwise results.
+func saveResult(exampleDir string, result ScenarioResult) {
+	if v := result.Validation; v != nil {
+		coverage := v.Coverage()
+		result.Coverage = &coverage
+	}
+	name := fmt.Sprintf("%s_%s", result.Scenario, sanitizeModelName(result.Model))
+	if v := result.variant(); v != "" {
+		name += "_" + strings.ReplaceAll(v, " ", "_")
+	}
+	resultPath := filepath.Join(exampleDir, "results", name+".json")
+	if err := bench.WriteJSON(resultPath, result); err != nil {
+		log.Printf("WARNING: could not write result: %v", err)
 	}
 }
 
+// saveFailure saves result for a scenario whose model call failed after any
+// retries, so it still counts against the model in the report.
+func saveFailure(exampleDir string, result ScenarioResult, err error) {
+	result.Error = err.Error()
+	saveResult(exampleDir, result)
+}
+
 func sanitizeModelName(name string) string {
 	r := strings.NewReplacer("/", "_", ":", "_", ".", "_")
 	return r.Replace(name)
 }
 
-func scoreResults(exampleDir string, scenarios []struct {
-	name  string
-	input string
-	gold  string
-}) {
+func scoreResults(exampleDir string, scenarios []rerank.Scenario) {
 	entries, err := os.ReadDir(filepath.Join(exampleDir, "results"))
 	if err != nil {
 		log.Fatalf("read results dir: %v", err)
@@ -265,16 +522,20 @@ func scoreResults(exampleDir string, scenarios []struct {
 		}
 
 		var result ScenarioResult
-		if err := loadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
 			log.Printf("skip %s: %v", entry.Name(), err)
 			continue
 		}
+		if result.Error != "" {
+			fmt.Printf("%s: error=%s\n", entry.Name(), result.Error)
+			continue
+		}
 
 		// Find matching gold standard
 		var goldPath string
 		for _, sc := range scenarios {
-			if sc.name == result.Scenario {
-				goldPath = filepath.Join(exampleDir, sc.gold)
+			if sc.Name == result.Scenario {
+				goldPath = filepath.Join(exampleDir, sc.Expected)
 				break
 			}
 		}
@@ -283,29 +544,35 @@ func scoreResults(exampleDir string, scenarios []struct {
 			continue
 		}
 
-		var gold GoldStandard
-		if err := loadJSON(goldPath, &gold); err != nil {
+		var gold rerank.GoldStandard
+		if err := bench.LoadJSON(goldPath, &gold); err != nil {
 			log.Printf("skip %s: %v", entry.Name(), err)
 			continue
 		}
 
-		goldRel := make(map[string]int)
-		for _, g := range gold.Ranking {
-			goldRel[g.ID] = g.Relevance
-		}
-		modelOrder := make([]string, len(result.Rankings))
-		for i, r := range result.Rankings {
-			modelOrder[i] = r.ID
+		m := rerank.Evaluate(result.Rankings, gold)
+		var atK []string
+		for _, a := range m.AtK {
+			atK = append(atK, fmt.Sprintf("NDCG@%d=%.3f P@%d=%.3f R@%d=%.3f", a.K, a.NDCG, a.K, a.Precision, a.K, a.Recall))
 		}
+		fmt.Printf("%s: %s  MRR=%.3f  AP=%.3f  tau=%.3f  rho=%.3f\n",
+			entry.Name(), strings.Join(atK, "  "), m.MRR, m.AveragePrecision, m.KendallTau, m.Spearman)
+	}
+}
 
-		ndcgScore := ndcg(modelOrder, goldRel, 10)
-		mrrScore := mrr(modelOrder, goldRel, 3)
+func generateReport(exampleDir string, scenarios []rerank.Scenario, prices *pricing.Table) {
+	benchmarks := prices.Apply(bench.WithModelInfo(exampleDir, benchmarkResults(exampleDir, scenarios)))
+	report := reporting.GenerateReport(benchmarks) + reporting.GenerateComparison(benchmarks) + metricTables(exampleDir, scenarios)
+	fmt.Print(report)
 
-		fmt.Printf("%s: NDCG@10=%.3f  MRR=%.3f\n", entry.Name(), ndcgScore, mrrScore)
+	reportPath := filepath.Join(exampleDir, "RESULTS.md")
+	if err := os.WriteFile(reportPath, []byte("# Search Reranking Results\n\n"+report), 0644); err != nil {
+		log.Printf("WARNING: could not write report: %v", err)
 	}
 }
 
-func generateReport(exampleDir string) {
+// benchmarkResults loads every scenario result written by a run.
+func benchmarkResults(exampleDir string, scenarios []rerank.Scenario) []types.BenchmarkResult {
 	entries, err := os.ReadDir(filepath.Join(exampleDir, "results"))
 	if err != nil {
 		log.Fatalf("read results dir: %v", err)
@@ -318,29 +585,138 @@ func generateReport(exampleDir string) {
 		}
 
 		var result ScenarioResult
-		if err := loadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
 			continue
 		}
 
+		sc, _ := rerank.Find(scenarios, result.Scenario)
+		quality := result.NDCG
+		if sc.Scorer == rerank.ScorerMRR {
+			quality = result.MRR
+		}
+
+		jsonChecks, jsonRaw, jsonRepaired := result.JSON.Counts()
+		var checks, valid int
+		if result.SchemaValid != nil {
+			checks = 1
+			if *result.SchemaValid {
+				valid = 1
+			}
+		}
+
 		benchmarks = append(benchmarks, types.BenchmarkResult{
-			Example:      fmt.Sprintf("search-reranking/%s", result.Scenario),
-			Model:        result.Model,
-			Quality:      result.NDCG,
-			QualityName:  "NDCG@10",
-			TokensIn:     result.Meta.TokensIn,
-			TokensOut:    result.Meta.TokensOut,
-			TTFT:         result.Meta.TTFT,
-			TotalTime:    result.Meta.TotalTime,
-			TokensPerSec: result.Meta.TokensPerSec,
-			CostUSD:      0,
+			Example:           fmt.Sprintf("search-reranking/%s", result.Scenario),
+			Model:             result.label(),
+			Quality:           quality,
+			QualityName:       sc.QualityName(),
+			TokensIn:          result.Meta.TokensIn,
+			TokensOut:         result.Meta.TokensOut,
+			TTFT:              result.Meta.TTFT,
+			TotalTime:         result.Meta.TotalTime,
+			TokensPerSec:      result.Meta.TokensPerSec,
+			InterTokenLatency: result.Meta.InterTokenLatency,
+			SchemaChecks:      checks,
+			SchemaValid:       valid,
+			JSONChecks:        jsonChecks,
+			JSONRaw:           jsonRaw,
+			JSONRepaired:      jsonRepaired,
+			Retries:           result.Meta.Retries,
+			Calls:             float64(result.Calls),
+			Error:             result.Error,
 		})
 	}
 
-	report := reporting.GenerateReport(benchmarks)
-	fmt.Print(report)
+	return benchmarks
+}
 
-	reportPath := filepath.Join(exampleDir, "RESULTS.md")
-	if err := os.WriteFile(reportPath, []byte("# Search Reranking Results\n\n"+report), 0644); err != nil {
-		log.Printf("WARNING: could not write report: %v", err)
+// variant describes how a result was produced when that is not a single
+// pointwise run in fixture order, e.g. "listwise" or "pointwise x5 borda".
+func (r ScenarioResult) variant() string {
+	strategy := r.Strategy
+	if strategy == "" {
+		strategy = rerank.StrategyPointwise
+	}
+	if p := r.Permutations; p != nil {
+		return fmt.Sprintf("%s x%d %s", strategy, p.Count, p.Aggregate)
+	}
+	if strategy == rerank.StrategyPointwise {
+		return ""
+	}
+	return strategy
+}
+
+// label names the model a result is reported under. Variants are labeled so
+// one report can compare them for the same model.
+func (r ScenarioResult) label() string {
+	return types.ModelLabel(r.Model, r.variant())
+}
+
+// metricTables renders the per-k curves and rank-wide metrics of every
+// result, averaged per model, the ID validation of model replies and the
+// NDCG@10 spread of permutation runs.
+// Metrics are recomputed from the saved rankings so results from before a
+// gold standard changed stay comparable.
+func metricTables(exampleDir string, scenarios []rerank.Scenario) string {
+	entries, err := os.ReadDir(filepath.Join(exampleDir, "results"))
+	if err != nil {
+		log.Fatalf("read results dir: %v", err)
+	}
+
+	var precision, recall, ndcg, overall, positions, validation []reporting.MetricRow
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		var result ScenarioResult
+		if err := bench.LoadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
+			continue
+		}
+		sc, ok := rerank.Find(scenarios, result.Scenario)
+		if !ok {
+			continue
+		}
+		var gold rerank.GoldStandard
+		if err := bench.LoadJSON(filepath.Join(exampleDir, sc.Expected), &gold); err != nil {
+			continue
+		}
+
+		m := rerank.Evaluate(result.Rankings, gold)
+		p, r, n := reporting.MetricRow{Model: result.label()}, reporting.MetricRow{Model: result.label()}, reporting.MetricRow{Model: result.label()}
+		for _, a := range m.AtK {
+			p.Values = append(p.Values, a.Precision)
+			r.Values = append(r.Values, a.Recall)
+			n.Values = append(n.Values, a.NDCG)
+		}
+		precision, recall, ndcg = append(precision, p), append(recall, r), append(ndcg, n)
+		overall = append(overall, reporting.MetricRow{
+			Model:  result.label(),
+			Values: []float64{m.AveragePrecision, m.MRR, m.KendallTau, m.Spearman},
+		})
+		if v := result.Validation; v != nil {
+			validation = append(validation, reporting.MetricRow{
+				Model:  result.label(),
+				Values: []float64{v.Coverage(), float64(v.Unknown), float64(v.Duplicated), float64(v.Missing), float64(v.InvalidReplies + v.InvalidAnswers)},
+			})
+		}
+		if ps := result.Permutations; ps != nil {
+			positions = append(positions, reporting.MetricRow{
+				Model:  result.label(),
+				Values: []float64{ps.Mean, ps.Variance, ps.StdDev, ps.Min, ps.Max, ps.PositionTau},
+			})
+		}
+	}
+
+	columns := func(metric string) []string {
+		cols := make([]string, len(rerank.Cutoffs))
+		for i, k := range rerank.Cutoffs {
+			cols[i] = fmt.Sprintf("%s@%d", metric, k)
+		}
+		return cols
 	}
+	return reporting.GenerateMetrics("Precision@k", columns("P"), precision) +
+		reporting.GenerateMetrics("Recall@k", columns("R"), recall) +
+		reporting.GenerateMetrics("NDCG@k", columns("NDCG"), ndcg) +
+		reporting.GenerateMetrics("Ranking Metrics", []string{"MAP", "MRR", "Kendall τ", "Spearman ρ"}, overall) +
+		reporting.GenerateMetrics("Output Validation", []string{"Coverage", "Hallucinated IDs", "Duplicate IDs", "Missing IDs", "Invalid Replies"}, validation) +
+		reporting.GenerateMetrics("Position Sensitivity", []string{"NDCG@10 Mean", "Variance", "Std Dev", "Min", "Max", "Position τ"}, positions)
 }
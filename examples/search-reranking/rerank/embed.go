@@ -0,0 +1,53 @@
+package rerank
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultEmbedModel is the embedding model the embedding strategy uses
+// unless told otherwise.
+const DefaultEmbedModel = "nomic-embed-text"
+
+// EmbeddingInput returns the texts to embed for query: the query itself
+// followed by each candidate's title and snippet, in candidate order.
+func EmbeddingInput(query SearchQuery) []string {
+	input := make([]string, 0, len(query.Candidates)+1)
+	input = append(input, query.Query)
+	for _, c := range query.Candidates {
+		input = append(input, c.Title+"\n"+c.Snippet)
+	}
+	return input
+}
+
+// RankByEmbedding scores each candidate by the cosine similarity of its
+// embedding to the query's and returns them best first. vectors are the
+// embeddings of EmbeddingInput(query), in the same order.
+func RankByEmbedding(query SearchQuery, vectors [][]float64) []RankedResult {
+	rankings := make([]RankedResult, len(query.Candidates))
+	for i, c := range query.Candidates {
+		rankings[i] = RankedResult{ID: c.ID, Score: Cosine(vectors[0], vectors[i+1])}
+	}
+	sort.SliceStable(rankings, func(i, j int) bool {
+		return rankings[i].Score > rankings[j].Score
+	})
+	return rankings
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if either is zero
+// or their lengths differ.
+func Cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,73 @@
+package rerank
+
+import (
+	"path/filepath"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs each query scenario through the bench interface as a single
+// case scored against its gold standard by the scorer its manifest entry
+// names.
+type Example struct{}
+
+type queryCase struct {
+	Scenario Scenario
+	Query    SearchQuery
+	Gold     GoldStandard
+}
+
+func (Example) Name() string { return "search-reranking" }
+
+func (Example) Description() string {
+	return "Score search candidates for relevance and rerank them"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	scenarios, err := LoadScenarios(dir)
+	if err != nil {
+		return nil, err
+	}
+	cases := make([]bench.Case, 0, len(scenarios))
+	for _, sc := range scenarios {
+		d := queryCase{Scenario: sc}
+		if err := bench.LoadJSON(filepath.Join(dir, sc.Input), &d.Query); err != nil {
+			return nil, err
+		}
+		if err := bench.LoadJSON(filepath.Join(dir, sc.Expected), &d.Gold); err != nil {
+			return nil, err
+		}
+		cases = append(cases, bench.Case{ID: sc.Name, Scenario: sc.Name, Data: d})
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	d := c.Data.(queryCase)
+	return ollama.NewChatRequest("", SystemPrompt, BuildPrompt(d.Query), true, d.Scenario.MaxTokens)
+}
+
+// Parse decodes the rankings and repairs their IDs with ValidateRankings,
+// so missing candidates rank last instead of being skipped by the metrics.
+func (Example) Parse(c bench.Case, raw string) (any, error) {
+	rankings, err := ParseRankings(raw)
+	if err != nil {
+		return nil, err
+	}
+	rankings, _ = ValidateRankings(rankings, c.Data.(queryCase).Query.Candidates)
+	return rankings, nil
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	d := c.Data.(queryCase)
+	return bench.Score{
+		Quality: d.Scenario.Score(parsed.([]RankedResult), d.Gold),
+		Metric:  d.Scenario.QualityName(),
+	}
+}
+
+func (Example) Schema(bench.Case) *schema.Schema { return RankingSchema }
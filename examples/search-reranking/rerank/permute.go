@@ -0,0 +1,131 @@
+package rerank
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/statherm/local-llm-examples/shared/scoring"
+)
+
+// Aggregation methods combine the rankings of one query's permutations.
+// AggregateMean ranks candidates by their mean score; AggregateBorda by their
+// mean position, which ignores how the model spread its scores.
+const (
+	AggregateMean  = "mean"
+	AggregateBorda = "borda"
+)
+
+// Shuffle returns query with its candidates in a random order determined by
+// seed and n, the permutation's index, so every run of a scenario sees the
+// same orders.
+func Shuffle(query SearchQuery, seed uint64, n int) SearchQuery {
+	rng := rand.New(rand.NewPCG(seed, uint64(n)))
+	shuffled := query
+	shuffled.Candidates = append([]SearchCandidate(nil), query.Candidates...)
+	rng.Shuffle(len(shuffled.Candidates), func(i, j int) {
+		shuffled.Candidates[i], shuffled.Candidates[j] = shuffled.Candidates[j], shuffled.Candidates[i]
+	})
+	return shuffled
+}
+
+// Aggregate combines rankings of the candidates of query, one per
+// permutation, with method. A candidate a ranking leaves out scores 0 in it.
+// With AggregateBorda each ranking awards (n-1-position)/(n-1) for n
+// candidates. Ties keep the order of query.Candidates.
+func Aggregate(method string, query SearchQuery, rankings [][]RankedResult) []RankedResult {
+	n := len(query.Candidates)
+	totals := make(map[string]float64, n)
+	for _, ranking := range rankings {
+		for pos, r := range ranking {
+			if method == AggregateBorda {
+				if n > 1 {
+					totals[r.ID] += float64(n-1-pos) / float64(n-1)
+				}
+			} else {
+				totals[r.ID] += r.Score
+			}
+		}
+	}
+
+	out := make([]RankedResult, n)
+	for i, c := range query.Candidates {
+		score := 0.0
+		if len(rankings) > 0 {
+			score = totals[c.ID] / float64(len(rankings))
+		}
+		out[i] = RankedResult{ID: c.ID, Score: score}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// PositionTau is Kendall's tau between where candidates appeared in the
+// prompt (earlier is higher) and the score the model gave them. A model
+// that judges relevance alone scores near 0 across permutations; a
+// consistently positive tau means it favors whatever is listed first.
+// Candidates the model left out are skipped.
+func PositionTau(query SearchQuery, rankings []RankedResult) float64 {
+	scores := make(map[string]float64, len(rankings))
+	for _, r := range rankings {
+		if !r.Missing {
+			scores[r.ID] = r.Score
+		}
+	}
+	var position, score []float64
+	for i, c := range query.Candidates {
+		s, ok := scores[c.ID]
+		if !ok {
+			continue
+		}
+		position = append(position, float64(len(query.Candidates)-i))
+		score = append(score, s)
+	}
+	return scoring.KendallTau(position, score)
+}
+
+// PermutationStats describes how a query's ranking quality varied across
+// shuffled candidate orders.
+type PermutationStats struct {
+	Count     int    `json:"count"`
+	Seed      uint64 `json:"seed"`
+	Aggregate string `json:"aggregate"`
+	// NDCG is NDCG@10 of each permutation's own ranking; the fields below
+	// summarize it.
+	NDCG     []float64 `json:"ndcg"`
+	Mean     float64   `json:"mean"`
+	Variance float64   `json:"variance"`
+	StdDev   float64   `json:"std_dev"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	// PositionTau is the mean PositionTau over permutations.
+	PositionTau float64 `json:"position_tau"`
+	Failed      int     `json:"failed,omitempty"` // permutations with no ranking
+}
+
+// NewPermutationStats summarizes the per-permutation NDCG scores and
+// position taus.
+func NewPermutationStats(seed uint64, aggregate string, ndcg, taus []float64, failed int) PermutationStats {
+	s := PermutationStats{Count: len(ndcg) + failed, Seed: seed, Aggregate: aggregate, NDCG: ndcg, Failed: failed}
+	if len(ndcg) == 0 {
+		return s
+	}
+	s.Min, s.Max = math.Inf(1), math.Inf(-1)
+	for _, v := range ndcg {
+		s.Mean += v
+		s.Min, s.Max = min(s.Min, v), max(s.Max, v)
+	}
+	s.Mean /= float64(len(ndcg))
+	for _, v := range ndcg {
+		s.Variance += (v - s.Mean) * (v - s.Mean)
+	}
+	s.Variance /= float64(len(ndcg))
+	s.StdDev = math.Sqrt(s.Variance)
+	for _, t := range taus {
+		s.PositionTau += t
+	}
+	if len(taus) > 0 {
+		s.PositionTau /= float64(len(taus))
+	}
+	return s
+}
@@ -0,0 +1,269 @@
+// Package rerank holds the search-reranking example's data types, prompt,
+// output schema and evaluation, shared by the example's main and the
+// llmbench CLI.
+package rerank
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/manifest"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+)
+
+// Scenario is a query from the example's manifest (scenarios.json): Input
+// is the query file and Expected its gold standard, both relative to the
+// example directory.
+type Scenario struct {
+	manifest.Scenario
+}
+
+// Scorer names. ScorerNDCG, the default, is NDCG@10; ScorerMRR is the
+// reciprocal rank of the first result with relevance 3 or more.
+const (
+	ScorerNDCG = "ndcg@10"
+	ScorerMRR  = "mrr"
+)
+
+// Strategies select how candidates are ranked. StrategyPointwise, the
+// default, asks the model for a relevance score per candidate in one call
+// (SystemPrompt). StrategyListwise asks for an ordering of a sliding window
+// of candidates (Listwise) and StrategyPairwise for the better of two
+// candidates at a time (Pairwise). StrategyEmbedding ranks by embedding
+// similarity to the query (RankByEmbedding) without a generative model.
+const (
+	StrategyPointwise = "pointwise"
+	StrategyListwise  = "listwise"
+	StrategyPairwise  = "pairwise"
+	StrategyEmbedding = "embedding"
+)
+
+// Strategies lists the strategy names in the order flags describe them.
+var Strategies = []string{StrategyPointwise, StrategyListwise, StrategyPairwise, StrategyEmbedding}
+
+// LoadScenarios reads the manifest in dir.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	entries, err := manifest.Load(dir, ScorerNDCG, ScorerMRR)
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]Scenario, len(entries))
+	for i, e := range entries {
+		scenarios[i] = Scenario{e}
+	}
+	return scenarios, nil
+}
+
+// Find returns the scenario called name.
+func Find(scenarios []Scenario, name string) (Scenario, bool) {
+	for _, s := range scenarios {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Scenario{}, false
+}
+
+// Score rates rankings against gold with the scenario's scorer.
+func (s Scenario) Score(rankings []RankedResult, gold GoldStandard) float64 {
+	order := Order(rankings)
+	if s.Scorer == ScorerMRR {
+		return scoring.MRR(order, gold.Relevance(), HighlyRelevantGrade)
+	}
+	return scoring.NDCG(order, gold.Relevance(), 10)
+}
+
+// QualityName names the metric Score reports.
+func (s Scenario) QualityName() string {
+	if s.Scorer == ScorerMRR {
+		return "MRR"
+	}
+	return "NDCG@10"
+}
+
+// SearchCandidate is a single search result to be reranked.
+type SearchCandidate struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchQuery is the input: a query plus candidate results.
+type SearchQuery struct {
+	Query      string            `json:"query"`
+	Candidates []SearchCandidate `json:"candidates"`
+}
+
+// GoldRanking is the expected relevance for a candidate.
+type GoldRanking struct {
+	ID        string `json:"id"`
+	Relevance int    `json:"relevance"`
+	Reason    string `json:"reason"`
+}
+
+// GoldStandard is the full ground truth for a query.
+type GoldStandard struct {
+	Query   string        `json:"query"`
+	Ranking []GoldRanking `json:"ranking"`
+}
+
+// RankedResult is the model's output: a candidate ID with a relevance score.
+// Missing marks a candidate the model left out, which ValidateRankings
+// appends with score 0; the score is a placeholder, not the model's.
+type RankedResult struct {
+	ID      string  `json:"id"`
+	Score   float64 `json:"score"`
+	Missing bool    `json:"missing,omitempty"`
+}
+
+// RerankedOutput is the model's full response parsed from JSON.
+type RerankedOutput struct {
+	Rankings []RankedResult `json:"rankings"`
+}
+
+// RankingSchema is the output contract stated in SystemPrompt.
+var RankingSchema = schema.MustParse(`{
+  "type": "object",
+  "properties": {
+    "rankings": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string", "minLength": 1},
+          "score": {"type": "number", "minimum": 0, "maximum": 1}
+        },
+        "required": ["id", "score"]
+      }
+    }
+  },
+  "required": ["rankings"]
+}`)
+
+const SystemPrompt = `You are a search result reranking system. Given a search query and a list of candidate results, score each result's relevance to the query.
+
+For each candidate, assign a relevance score from 0.0 to 1.0:
+- 1.0 = directly and completely answers the query
+- 0.7-0.9 = highly relevant, addresses the core topic
+- 0.4-0.6 = somewhat relevant, related topic but not a direct answer
+- 0.1-0.3 = tangentially related, shares some keywords
+- 0.0 = completely irrelevant
+
+Respond with valid JSON in this exact format:
+{"rankings": [{"id": "<candidate-id>", "score": <0.0-1.0>}, ...]}`
+
+// BuildPrompt lists the query and its candidates for the model.
+func BuildPrompt(query SearchQuery) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Query: %s\n\nCandidate results:\n\n", query.Query))
+	for i, c := range query.Candidates {
+		sb.WriteString(fmt.Sprintf("[%d] ID: %s\nTitle: %s\nSnippet: %s\n\n", i+1, c.ID, c.Title, c.Snippet))
+	}
+	sb.WriteString("Score each candidate's relevance to the query. Return JSON with all candidate IDs and their scores.")
+	return sb.String()
+}
+
+// Grade thresholds for the metrics that need binary relevance. MRR looks
+// for the first highly relevant candidate; precision, recall and average
+// precision count every relevant one.
+const (
+	RelevantGrade       = 2
+	HighlyRelevantGrade = 3
+)
+
+// Cutoffs are the ranks Evaluate computes the per-k metrics at.
+var Cutoffs = []int{1, 3, 5, 10}
+
+// AtK holds the metrics measured over the top K results.
+type AtK struct {
+	K         int     `json:"k"`
+	NDCG      float64 `json:"ndcg"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// Metrics is the full evaluation of one ranking against its gold standard.
+// KendallTau and Spearman correlate the model's scores with the gold
+// relevance grades over the candidates both rate, leaving out any the model
+// did not return.
+type Metrics struct {
+	AtK              []AtK   `json:"at_k"`
+	MRR              float64 `json:"mrr"`
+	AveragePrecision float64 `json:"average_precision"`
+	KendallTau       float64 `json:"kendall_tau"`
+	Spearman         float64 `json:"spearman"`
+}
+
+// Evaluate computes every ranking metric for rankings against gold.
+func Evaluate(rankings []RankedResult, gold GoldStandard) Metrics {
+	order, rel := Order(rankings), gold.Relevance()
+	m := Metrics{
+		AtK:              make([]AtK, len(Cutoffs)),
+		MRR:              scoring.MRR(order, rel, HighlyRelevantGrade),
+		AveragePrecision: scoring.AveragePrecision(order, rel, RelevantGrade),
+	}
+	for i, k := range Cutoffs {
+		m.AtK[i] = AtK{
+			K:         k,
+			NDCG:      scoring.NDCG(order, rel, k),
+			Precision: scoring.PrecisionAtK(order, rel, k, RelevantGrade),
+			Recall:    scoring.RecallAtK(order, rel, k, RelevantGrade),
+		}
+	}
+
+	var scores, grades []float64
+	for _, r := range rankings {
+		if grade, ok := rel[r.ID]; ok && !r.Missing {
+			scores = append(scores, r.Score)
+			grades = append(grades, float64(grade))
+		}
+	}
+	m.KendallTau = scoring.KendallTau(scores, grades)
+	m.Spearman = scoring.Spearman(scores, grades)
+	return m
+}
+
+// At returns the metrics at cutoff k, which must be one of Cutoffs.
+func (m Metrics) At(k int) AtK {
+	for _, a := range m.AtK {
+		if a.K == k {
+			return a
+		}
+	}
+	return AtK{K: k}
+}
+
+// ParseRankings decodes a model response and sorts its rankings by score,
+// highest first.
+func ParseRankings(resp string) ([]RankedResult, error) {
+	var output RerankedOutput
+	if err := repair.Unmarshal(resp, &output); err != nil {
+		return nil, err
+	}
+	sort.Slice(output.Rankings, func(i, j int) bool {
+		return output.Rankings[i].Score > output.Rankings[j].Score
+	})
+	return output.Rankings, nil
+}
+
+// Relevance maps each candidate ID in gold to its relevance grade.
+func (gold GoldStandard) Relevance() map[string]int {
+	rel := make(map[string]int, len(gold.Ranking))
+	for _, g := range gold.Ranking {
+		rel[g.ID] = g.Relevance
+	}
+	return rel
+}
+
+// Order returns the candidate IDs of rankings in order.
+func Order(rankings []RankedResult) []string {
+	ids := make([]string, len(rankings))
+	for i, r := range rankings {
+		ids[i] = r.ID
+	}
+	return ids
+}
@@ -0,0 +1,203 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Defaults for the listwise sliding window and the pairwise bubble passes.
+const (
+	DefaultWindow = 10
+	DefaultStep   = 5
+	DefaultTopK   = 10
+)
+
+// pairwiseMaxTokens caps a pairwise reply, which is a single short object.
+const pairwiseMaxTokens = 64
+
+const ListwiseSystemPrompt = `You are a search result reranking system. Given a search query and a list of candidate results, order the candidates from most to least relevant to the query.
+
+List every candidate ID exactly once. Respond with valid JSON in this exact format:
+{"ranking": ["<most relevant id>", "<next id>", ...]}`
+
+// PermutationSchema is the output contract stated in ListwiseSystemPrompt.
+var PermutationSchema = schema.MustParse(`{
+  "type": "object",
+  "properties": {
+    "ranking": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"type": "string", "minLength": 1}
+    }
+  },
+  "required": ["ranking"]
+}`)
+
+const PairwiseSystemPrompt = `You are a search result reranking system. Given a search query and two candidate results, A and B, decide which one is more relevant to the query.
+
+Respond with valid JSON in this exact format:
+{"better": "A"} or {"better": "B"}`
+
+// StrategyRun is the outcome of reranking one query with a strategy that
+// takes several model calls.
+type StrategyRun struct {
+	// Rankings lists every candidate best first. Scores only encode the
+	// order: 1 for the first candidate down to 1/n for the last.
+	Rankings   []RankedResult
+	Calls      int
+	Meta       types.ModelMetadata // summed over calls
+	Validation Validation
+}
+
+// BuildListwisePrompt lists the query and candidates for a listwise call.
+func BuildListwisePrompt(query string, candidates []SearchCandidate) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Query: %s\n\nCandidate results:\n\n", query))
+	for i, c := range candidates {
+		sb.WriteString(fmt.Sprintf("[%d] ID: %s\nTitle: %s\nSnippet: %s\n\n", i+1, c.ID, c.Title, c.Snippet))
+	}
+	sb.WriteString(fmt.Sprintf("Order all %d candidate IDs from most to least relevant. Return JSON only.", len(candidates)))
+	return sb.String()
+}
+
+// BuildPairwisePrompt asks which of a and b better answers query.
+func BuildPairwisePrompt(query string, a, b SearchCandidate) string {
+	return fmt.Sprintf("Query: %s\n\nCandidate A:\nTitle: %s\nSnippet: %s\n\nCandidate B:\nTitle: %s\nSnippet: %s\n\nWhich candidate is more relevant to the query? Return JSON only.",
+		query, a.Title, a.Snippet, b.Title, b.Snippet)
+}
+
+// ParsePermutation decodes a listwise reply and checks it against the
+// candidates it was asked to order with ValidateRankings, so the result is
+// always a permutation of candidates. A reply that is not JSON leaves the
+// candidates in their order and counts them all as missing.
+func ParsePermutation(resp string, candidates []SearchCandidate) ([]SearchCandidate, Validation, error) {
+	var out struct {
+		Ranking []string `json:"ranking"`
+	}
+	if err := repair.Unmarshal(resp, &out); err != nil {
+		return candidates, Validation{Candidates: len(candidates), Missing: len(candidates), InvalidReplies: 1}, err
+	}
+
+	ranked := make([]RankedResult, len(out.Ranking))
+	for i, id := range out.Ranking {
+		ranked[i] = RankedResult{ID: id}
+	}
+	ranked, v := ValidateRankings(ranked, candidates)
+
+	byID := make(map[string]SearchCandidate, len(candidates))
+	for _, c := range candidates {
+		byID[c.ID] = c
+	}
+	order := make([]SearchCandidate, len(ranked))
+	for i, r := range ranked {
+		order[i] = byID[r.ID]
+	}
+	return order, v, nil
+}
+
+// ParsePairwise decodes a pairwise reply: true if the model preferred B.
+// Anything but A or B (or the candidates' IDs) is an error.
+func ParsePairwise(resp string, a, b SearchCandidate) (bool, error) {
+	var out struct {
+		Better string `json:"better"`
+	}
+	if err := repair.Unmarshal(resp, &out); err != nil {
+		return false, err
+	}
+	switch strings.ToUpper(strings.TrimSpace(out.Better)) {
+	case "A", strings.ToUpper(a.ID):
+		return false, nil
+	case "B", strings.ToUpper(b.ID):
+		return true, nil
+	}
+	return false, fmt.Errorf("answer %q is neither A nor B", out.Better)
+}
+
+// Listwise reranks query's candidates with a window of window candidates
+// that slides from the bottom of the list to the top, step candidates at a
+// time, so relevant candidates can climb from anywhere in the list to the
+// top. A list no longer than window takes a single call.
+func Listwise(ctx context.Context, client provider.Provider, model string, query SearchQuery, window, step, maxTokens int) (StrategyRun, error) {
+	if window < 2 {
+		window = 2
+	}
+	if step < 1 || step >= window {
+		step = window / 2
+	}
+
+	var run StrategyRun
+	order := append([]SearchCandidate(nil), query.Candidates...)
+	for end := len(order); ; end -= step {
+		start := max(end-window, 0)
+		req := ollama.NewChatRequest(model, ListwiseSystemPrompt, BuildListwisePrompt(query.Query, order[start:end]), true, maxTokens)
+		resp, err := client.Complete(ctx, req)
+		run.Calls++
+		run.Meta = types.SumMetadata(run.Meta, resp.Meta)
+		if err != nil {
+			return run, err
+		}
+
+		ranked, v, err := ParsePermutation(resp.Message.Content, order[start:end])
+		run.Validation.Add(v)
+		if err == nil {
+			copy(order[start:end], ranked)
+		}
+		if start == 0 {
+			break
+		}
+	}
+	run.Rankings = rankOrder(order)
+	return run, nil
+}
+
+// Pairwise finds the topK best candidates with topK bubble-sort passes from
+// the bottom of the list, one model call per comparison; the rest are left
+// only partly sorted. That takes about topK*n calls for n candidates. A
+// comparison the model answers invalidly leaves the pair as it was.
+func Pairwise(ctx context.Context, client provider.Provider, model string, query SearchQuery, topK int) (StrategyRun, error) {
+	var run StrategyRun
+	order := append([]SearchCandidate(nil), query.Candidates...)
+	if topK <= 0 || topK > len(order) {
+		topK = len(order)
+	}
+	for pass := 0; pass < topK; pass++ {
+		for j := len(order) - 1; j > pass; j-- {
+			a, b := order[j-1], order[j]
+			req := ollama.NewChatRequest(model, PairwiseSystemPrompt, BuildPairwisePrompt(query.Query, a, b), true, pairwiseMaxTokens)
+			resp, err := client.Complete(ctx, req)
+			run.Calls++
+			run.Meta = types.SumMetadata(run.Meta, resp.Meta)
+			if err != nil {
+				return run, err
+			}
+
+			preferB, err := ParsePairwise(resp.Message.Content, a, b)
+			if err != nil {
+				run.Validation.InvalidAnswers++
+				continue
+			}
+			if preferB {
+				order[j-1], order[j] = b, a
+			}
+		}
+	}
+	run.Rankings = rankOrder(order)
+	return run, nil
+}
+
+// rankOrder turns an ordering into rankings whose scores fall from 1 for
+// the first candidate to 1/n for the last.
+func rankOrder(order []SearchCandidate) []RankedResult {
+	rankings := make([]RankedResult, len(order))
+	for i, c := range order {
+		rankings[i] = RankedResult{ID: c.ID, Score: float64(len(order)-i) / float64(len(order))}
+	}
+	return rankings
+}
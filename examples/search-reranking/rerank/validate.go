@@ -0,0 +1,88 @@
+package rerank
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation counts what was wrong with the IDs in a model's rankings.
+// Replies can leave out candidates (Missing), repeat them (Duplicated),
+// name IDs that were not offered (Unknown) or not be JSON at all
+// (InvalidReplies); pairwise replies can fail to pick A or B
+// (InvalidAnswers). Candidates is the number of candidate IDs the replies
+// were asked to rank, summed over calls.
+type Validation struct {
+	Candidates     int `json:"candidates,omitempty"`
+	Missing        int `json:"missing,omitempty"`
+	Duplicated     int `json:"duplicated,omitempty"`
+	Unknown        int `json:"unknown,omitempty"`
+	InvalidAnswers int `json:"invalid_answers,omitempty"`
+	InvalidReplies int `json:"invalid_replies,omitempty"`
+}
+
+// OK reports whether every reply was valid.
+func (v Validation) OK() bool {
+	return v == Validation{Candidates: v.Candidates}
+}
+
+// Coverage is the fraction of candidates the replies ranked, or 1 if they
+// were not asked to rank any (as in pairwise comparisons).
+func (v Validation) Coverage() float64 {
+	if v.Candidates == 0 {
+		return 1
+	}
+	return float64(v.Candidates-v.Missing) / float64(v.Candidates)
+}
+
+func (v Validation) String() string {
+	if v.OK() {
+		return "ok"
+	}
+	return fmt.Sprintf("%d missing, %d duplicated, %d unknown IDs; %d invalid answers; %d invalid replies",
+		v.Missing, v.Duplicated, v.Unknown, v.InvalidAnswers, v.InvalidReplies)
+}
+
+// Add adds w's counts to v.
+func (v *Validation) Add(w Validation) {
+	v.Candidates += w.Candidates
+	v.Missing += w.Missing
+	v.Duplicated += w.Duplicated
+	v.Unknown += w.Unknown
+	v.InvalidAnswers += w.InvalidAnswers
+	v.InvalidReplies += w.InvalidReplies
+}
+
+// ValidateRankings checks rankings, in the model's order, against the
+// candidates they should rank. IDs that were not offered are hallucinated
+// and dropped, repeats after an ID's first appearance are dropped, and
+// candidates the model left out are appended in their original order with
+// score 0 and Missing set, so the result ranks every candidate exactly once.
+func ValidateRankings(rankings []RankedResult, candidates []SearchCandidate) ([]RankedResult, Validation) {
+	offered := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		offered[c.ID] = true
+	}
+
+	v := Validation{Candidates: len(candidates)}
+	seen := make(map[string]bool, len(candidates))
+	out := make([]RankedResult, 0, len(candidates))
+	for _, r := range rankings {
+		r.ID = strings.TrimSpace(r.ID)
+		switch {
+		case !offered[r.ID]:
+			v.Unknown++
+		case seen[r.ID]:
+			v.Duplicated++
+		default:
+			seen[r.ID] = true
+			out = append(out, r)
+		}
+	}
+	for _, c := range candidates {
+		if !seen[c.ID] {
+			v.Missing++
+			out = append(out, RankedResult{ID: c.ID, Missing: true})
+		}
+	}
+	return out, v
+}
@@ -0,0 +1,71 @@
+package rerank
+
+import (
+	"math"
+	"testing"
+)
+
+var candidates = []SearchCandidate{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+
+func TestValidateRankings(t *testing.T) {
+	got, v := ValidateRankings([]RankedResult{
+		{ID: " c ", Score: 0.9},
+		{ID: "z", Score: 0.8},
+		{ID: "a", Score: 0.7},
+		{ID: "c", Score: 0.2},
+	}, candidates)
+
+	want := []RankedResult{
+		{ID: "c", Score: 0.9},
+		{ID: "a", Score: 0.7},
+		{ID: "b", Missing: true},
+		{ID: "d", Missing: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rankings = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ranking %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if v != (Validation{Candidates: 4, Missing: 2, Duplicated: 1, Unknown: 1}) {
+		t.Errorf("validation = %+v", v)
+	}
+	if v.Coverage() != 0.5 {
+		t.Errorf("coverage = %v, want 0.5", v.Coverage())
+	}
+}
+
+// TestEvaluateSkipsMissing checks that the correlations only cover the
+// candidates the model scored. The model orders b, c and d correctly but
+// leaves out a, the most relevant; counting a's placeholder score of 0
+// against its grade of 3 would make the correlations look worse than the
+// scores the model gave.
+func TestEvaluateSkipsMissing(t *testing.T) {
+	gold := GoldStandard{Ranking: []GoldRanking{
+		{ID: "a", Relevance: 3},
+		{ID: "b", Relevance: 2},
+		{ID: "c", Relevance: 1},
+		{ID: "d", Relevance: 0},
+	}}
+	rankings, _ := ValidateRankings([]RankedResult{
+		{ID: "b", Score: 0.9},
+		{ID: "c", Score: 0.5},
+		{ID: "d", Score: 0.1},
+	}, candidates)
+
+	m := Evaluate(rankings, gold)
+	if m.KendallTau != 1 || math.Abs(m.Spearman-1) > 1e-9 {
+		t.Errorf("tau %v, Spearman %v; want 1 over the scored candidates", m.KendallTau, m.Spearman)
+	}
+	// The ranking metrics still count a, ranked last.
+	if m.MRR != 0.25 {
+		t.Errorf("MRR = %v, want 1/4 with a last", m.MRR)
+	}
+
+	// b, c and d were listed in the order the model scored them.
+	if tau := PositionTau(SearchQuery{Candidates: candidates}, rankings); tau != 1 {
+		t.Errorf("PositionTau = %v, want 1 over b, c and d", tau)
+	}
+}
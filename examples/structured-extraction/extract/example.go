@@ -0,0 +1,66 @@
+package extract
+
+import (
+	"fmt"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs every document through the bench interface in free-JSON
+// mode, scored by field match against the expected extraction.
+type Example struct{}
+
+type documentCase struct {
+	Scenario Scenario
+	Document Document
+	Schema   *schema.Schema
+}
+
+func (Example) Name() string { return "structured-extraction" }
+
+func (Example) Description() string {
+	return "Extract invoices, support tickets and log events into typed JSON"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	scenarios, err := LoadScenarios(dir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []bench.Case
+	for _, s := range scenarios {
+		suite, err := s.Load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		for _, doc := range suite.Documents {
+			cases = append(cases, bench.Case{ID: doc.Name, Scenario: s.Name, Data: documentCase{s, doc, suite.Schema}})
+		}
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	d := c.Data.(documentCase)
+	return d.Scenario.Request("", d.Document.Prompt, nil)
+}
+
+// Parse recovers the JSON in the raw output; field matching decodes it.
+func (Example) Parse(_ bench.Case, raw string) (any, error) {
+	text, _ := repair.JSON(raw)
+	return text, nil
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	quality, _, _, _ := Score(c.Data.(documentCase).Document.Expected, parsed.(string))
+	return bench.Score{Quality: quality, Metric: "field_match"}
+}
+
+func (Example) Schema(c bench.Case) *schema.Schema {
+	return c.Data.(documentCase).Schema
+}
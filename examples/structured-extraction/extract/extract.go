@@ -0,0 +1,138 @@
+// Package extract holds the structured-extraction example's scenarios,
+// request construction and scoring, shared by the example's main and the
+// llmbench CLI.
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/manifest"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Scenario is a document type from the example's manifest
+// (scenarios.json). Input and Expected are directories of .txt inputs and
+// matching .json extractions; Prompt is the template with an {{INPUT}}
+// placeholder and Schema the JSON Schema of the output. Paths are relative
+// to the example directory.
+type Scenario struct {
+	manifest.Scenario
+}
+
+// FieldMatch is the only scorer: the fraction of expected JSON fields the
+// output reproduces.
+const FieldMatch = "field-match"
+
+// defaultMaxTokens caps responses for scenarios without max_tokens.
+const defaultMaxTokens = 1024
+
+// LoadScenarios reads the manifest in dir.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	entries, err := manifest.Load(dir, FieldMatch)
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]Scenario, len(entries))
+	for i, e := range entries {
+		scenarios[i] = Scenario{e}
+	}
+	return scenarios, nil
+}
+
+// Document is one input with its rendered prompt and expected extraction.
+type Document struct {
+	Name     string
+	Prompt   string
+	Expected []byte
+}
+
+// Suite is a scenario's output schema and documents read from disk.
+type Suite struct {
+	// RawSchema is the schema file as written, sent as the request format
+	// in schema-constrained mode.
+	RawSchema json.RawMessage
+	Schema    *schema.Schema
+	Documents []Document
+}
+
+// Load reads the scenario's prompt template, schema and documents from dir.
+func (s Scenario) Load(dir string) (Suite, error) {
+	promptTemplate, err := os.ReadFile(filepath.Join(dir, s.Prompt))
+	if err != nil {
+		return Suite{}, fmt.Errorf("read prompt template: %w", err)
+	}
+
+	schemaData, err := os.ReadFile(filepath.Join(dir, s.Schema))
+	if err != nil {
+		return Suite{}, fmt.Errorf("read schema: %w", err)
+	}
+	outputSchema, err := schema.Parse(schemaData)
+	if err != nil {
+		return Suite{}, fmt.Errorf("%s: %w", s.Schema, err)
+	}
+
+	inputs, err := filepath.Glob(filepath.Join(dir, s.Input, "*.txt"))
+	if err != nil {
+		return Suite{}, fmt.Errorf("glob inputs: %w", err)
+	}
+	if len(inputs) == 0 {
+		return Suite{}, fmt.Errorf("no input files found in %s", s.Input)
+	}
+
+	suite := Suite{RawSchema: schemaData, Schema: outputSchema}
+	for _, inputPath := range inputs {
+		name := strings.TrimSuffix(filepath.Base(inputPath), ".txt")
+		expectedPath := filepath.Join(dir, s.Expected, name+".json")
+
+		inputData, err := os.ReadFile(inputPath)
+		if err != nil {
+			return Suite{}, fmt.Errorf("read input %s: %w", inputPath, err)
+		}
+
+		expectedData, err := os.ReadFile(expectedPath)
+		if err != nil {
+			return Suite{}, fmt.Errorf("read expected %s: %w", expectedPath, err)
+		}
+
+		prompt := strings.ReplaceAll(string(promptTemplate), "{{INPUT}}", string(inputData))
+		suite.Documents = append(suite.Documents, Document{Name: name, Prompt: prompt, Expected: expectedData})
+	}
+	return suite, nil
+}
+
+// Request builds the extraction request for prompt. A nil format asks for
+// free JSON; otherwise format is sent as the JSON Schema Ollama constrains
+// decoding to.
+func (s Scenario) Request(model, prompt string, format json.RawMessage) ollama.ChatRequest {
+	maxTokens := s.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	req := ollama.ChatRequest{
+		Model:    model,
+		Messages: ollama.NewMessages("", prompt),
+		Format:   ollama.JSONFormat,
+		Options:  ollama.Options{NumPredict: maxTokens},
+	}
+	if format != nil {
+		req.Format = format
+	}
+	return req
+}
+
+// Score compares the fields of response against expected and returns the
+// fraction matched along with the per-field detail.
+func Score(expected []byte, response string) (quality float64, matched, total int, details []types.FieldResult) {
+	matched, total, details = scoring.JSONFieldMatch(json.RawMessage(expected), json.RawMessage(response))
+	if total > 0 {
+		quality = float64(matched) / float64(total)
+	}
+	return quality, matched, total, details
+}
@@ -1,43 +1,92 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/examples/structured-extraction/extract"
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
 	"github.com/statherm/local-llm-examples/shared/reporting"
-	"github.com/statherm/local-llm-examples/shared/scoring"
+	"github.com/statherm/local-llm-examples/shared/runner"
+	"github.com/statherm/local-llm-examples/shared/sweep"
 	"github.com/statherm/local-llm-examples/shared/types"
 )
 
 var (
-	model    = flag.String("model", "qwen3:4b", "Ollama model name")
-	scenario = flag.String("scenario", "all", "Scenario to run: invoices, tickets, logs, or all")
+	model       = flag.String("model", "qwen3:4b", "Ollama model name")
+	modelList   = flag.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile  = flag.String("models-file", "", "File listing models to run and compare, one per line")
+	scenario    = flag.String("scenario", "all", "Scenario to run: invoices, tickets, logs, or all")
+	format      = flag.String("format", "json", "Output constraint: json (free JSON), schema (JSON Schema-constrained), or both")
+	parallel    = flag.Int("parallel", 1, "Number of concurrent model requests")
+	conn        = provider.AddFlags(flag.CommandLine)
+	pricingFile = flag.String("pricing", "", "Pricing table for the Cost/Call column (default: pricing.json in this or a parent directory)")
+	reasks      = flag.Int("reask", 0, "Times to re-ask the model with the parse error when its JSON cannot be repaired")
 )
 
-type scenarioConfig struct {
-	Name       string
-	PromptFile string
-	InputDir   string
-	ExpectedDir string
+// Output constraint modes. In schema mode the scenario's JSON Schema is sent
+// as the request format so Ollama constrains decoding to it.
+const (
+	modeJSON   = "json"
+	modeSchema = "schema"
+)
+
+// extractionCase is one input document run in one output mode.
+type extractionCase struct {
+	Name     string
+	Mode     string
+	Prompt   string
+	Expected []byte
+}
+
+// modeStats aggregates quality and schema validity for one output mode so
+// free-JSON and schema-constrained runs can be compared side by side.
+type modeStats struct {
+	Runs      int
+	Failed    int // runs whose model call failed, which have no latency
+	Valid     int
+	Quality   float64
+	TotalTime time.Duration
 }
 
 func main() {
 	flag.Parse()
 
-	scenarios := []scenarioConfig{
-		{Name: "invoices", PromptFile: "prompts/invoice.txt", InputDir: "testdata/invoices", ExpectedDir: "expected/invoices"},
-		{Name: "tickets", PromptFile: "prompts/support-ticket.txt", InputDir: "testdata/tickets", ExpectedDir: "expected/tickets"},
-		{Name: "logs", PromptFile: "prompts/log-event.txt", InputDir: "testdata/logs", ExpectedDir: "expected/logs"},
+	scenarios, err := extract.LoadScenarios(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	prices, err := pricing.Load(*pricingFile, ".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var modes []string
+	switch *format {
+	case modeJSON, modeSchema:
+		modes = []string{*format}
+	case "both":
+		modes = []string{modeJSON, modeSchema}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format: %s\n", *format)
+		os.Exit(1)
 	}
 
 	// Filter to requested scenario.
 	if *scenario != "all" {
-		var filtered []scenarioConfig
+		var filtered []extract.Scenario
 		for _, s := range scenarios {
 			if s.Name == *scenario {
 				filtered = append(filtered, s)
@@ -50,97 +99,197 @@ func main() {
 		scenarios = filtered
 	}
 
-	client := ollama.NewClient()
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	client, err := conn.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range models {
+		if err := bench.Preflight(context.Background(), client, name, conn.Pull, "."); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 	var allResults []types.BenchmarkResult
 
-	for _, sc := range scenarios {
-		fmt.Printf("\n=== Scenario: %s (model: %s) ===\n\n", sc.Name, *model)
+	for _, name := range models {
+		var modelResults []types.BenchmarkResult
+		stats := make(map[string]*modeStats)
+		for _, m := range modes {
+			stats[m] = &modeStats{}
+		}
 
-		results, err := runScenario(client, sc)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "scenario %s failed: %v\n", sc.Name, err)
-			os.Exit(1)
+		for _, sc := range scenarios {
+			fmt.Printf("\n=== Scenario: %s (model: %s, format: %s) ===\n\n", sc.Name, name, *format)
+
+			results, err := runScenario(client, name, sc, modes, stats)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "scenario %s failed: %v\n", sc.Name, err)
+				os.Exit(1)
+			}
+			modelResults = append(modelResults, results...)
 		}
-		allResults = append(allResults, results...)
+
+		fmt.Println()
+		fmt.Print(reporting.GenerateReport(prices.Apply(bench.WithModelInfo(".", modelResults))))
+		fmt.Print(compareModes(name, modes, stats))
+		allResults = append(allResults, modelResults...)
 	}
 
-	fmt.Println()
-	fmt.Print(reporting.GenerateReport(allResults))
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(allResults))
+	}
 }
 
-func runScenario(client *ollama.Client, sc scenarioConfig) ([]types.BenchmarkResult, error) {
-	promptTemplate, err := os.ReadFile(sc.PromptFile)
+func runScenario(client provider.Provider, model string, sc extract.Scenario, modes []string, stats map[string]*modeStats) ([]types.BenchmarkResult, error) {
+	suite, err := sc.Load(".")
 	if err != nil {
-		return nil, fmt.Errorf("read prompt template: %w", err)
+		return nil, err
 	}
 
-	inputs, err := filepath.Glob(filepath.Join(sc.InputDir, "*.txt"))
-	if err != nil {
-		return nil, fmt.Errorf("glob inputs: %w", err)
-	}
-	if len(inputs) == 0 {
-		return nil, fmt.Errorf("no input files found in %s", sc.InputDir)
+	var cases []extractionCase
+	for _, doc := range suite.Documents {
+		for _, mode := range modes {
+			cases = append(cases, extractionCase{Name: doc.Name, Mode: mode, Prompt: doc.Prompt, Expected: doc.Expected})
+		}
 	}
 
-	var results []types.BenchmarkResult
-
-	for _, inputPath := range inputs {
-		name := strings.TrimSuffix(filepath.Base(inputPath), ".txt")
-		expectedPath := filepath.Join(sc.ExpectedDir, name+".json")
-
-		inputData, err := os.ReadFile(inputPath)
-		if err != nil {
-			return nil, fmt.Errorf("read input %s: %w", inputPath, err)
+	runs, summary := runner.Run(context.Background(), cases, *parallel, func(ctx context.Context, _ int, c extractionCase) (types.BenchmarkResult, error) {
+		var format json.RawMessage
+		if c.Mode == modeSchema {
+			format = suite.RawSchema
 		}
+		req := sc.Request(model, c.Prompt, format)
 
-		expectedData, err := os.ReadFile(expectedPath)
+		reply, err := repair.Complete(ctx, client, req, *reasks)
 		if err != nil {
-			return nil, fmt.Errorf("read expected %s: %w", expectedPath, err)
+			err = fmt.Errorf("model call for %s (%s): %w", c.Name, c.Mode, err)
+			return types.BenchmarkResult{
+				Example:     fmt.Sprintf("%s/%s [%s]", sc.Name, c.Name, c.Mode),
+				Model:       model,
+				QualityName: "field_match",
+				Retries:     reply.Meta.Retries,
+				Error:       err.Error(),
+			}, err
 		}
+		meta := reply.Meta
 
-		prompt := strings.ReplaceAll(string(promptTemplate), "{{INPUT}}", string(inputData))
-
-		fmt.Printf("  [%s/%s] calling model... ", sc.Name, name)
-
-		response, meta, err := client.ChatCompletion(*model, "", prompt, true)
-		if err != nil {
-			return nil, fmt.Errorf("model call for %s: %w", name, err)
+		// Keep the recovered output so score.sh can re-score it later.
+		outPath := filepath.Join("results", bench.SanitizeModelName(model), c.Mode, sc.Name, c.Name+".json")
+		if err := writeOutput(outPath, reply.Text); err != nil {
+			return types.BenchmarkResult{}, err
 		}
 
 		// Score: compare JSON fields.
-		matched, total, details := scoring.JSONFieldMatch(
-			json.RawMessage(expectedData),
-			json.RawMessage(response),
-		)
-
-		quality := 0.0
-		if total > 0 {
-			quality = float64(matched) / float64(total)
-		}
+		quality, matched, total, details := extract.Score(c.Expected, reply.Text)
 
-		fmt.Printf("score=%d/%d (%.0f%%) in %.2fs\n", matched, total, quality*100, meta.TotalTime.Seconds())
+		// Validate the raw response against the scenario schema, independent
+		// of whether decoding was constrained or the output needed repair.
+		violations := suite.Schema.ValidateJSON([]byte(reply.Raw))
+		valid := 0
+		if len(violations) == 0 {
+			valid = 1
+		}
 
+		// Build the case's output first so concurrent cases don't interleave.
+		var out strings.Builder
+		fmt.Fprintf(&out, "  [%s/%s %s] score=%d/%d (%.0f%%) schema_valid=%v json=%s in %.2fs\n",
+			sc.Name, c.Name, c.Mode, matched, total, quality*100, len(violations) == 0, reply.Outcome, meta.TotalTime.Seconds())
 		for _, d := range details {
 			status := "OK"
 			if !d.Match {
 				status = "MISS"
 			}
-			fmt.Printf("    %-20s [%s] expected=%-30s actual=%s\n", d.Field, status, d.Expected, d.Actual)
+			fmt.Fprintf(&out, "    %-20s [%s] expected=%-30s actual=%s\n", d.Field, status, d.Expected, d.Actual)
+		}
+		for _, v := range violations {
+			fmt.Fprintf(&out, "    schema: %s\n", v)
 		}
+		fmt.Print(out.String())
+
+		jsonChecks, jsonRaw, jsonRepaired := reply.Outcome.Counts()
+		return types.BenchmarkResult{
+			Example:           fmt.Sprintf("%s/%s [%s]", sc.Name, c.Name, c.Mode),
+			Model:             meta.Model,
+			Quality:           quality,
+			QualityName:       "field_match",
+			TokensIn:          meta.TokensIn,
+			TokensOut:         meta.TokensOut,
+			TTFT:              meta.TTFT,
+			TotalTime:         meta.TotalTime,
+			TokensPerSec:      meta.TokensPerSec,
+			InterTokenLatency: meta.InterTokenLatency,
+			SchemaChecks:      1,
+			SchemaValid:       valid,
+			JSONChecks:        jsonChecks,
+			JSONRaw:           jsonRaw,
+			JSONRepaired:      jsonRepaired,
+			Retries:           meta.Retries,
+		}, nil
+	})
 
-		results = append(results, types.BenchmarkResult{
-			Example:      fmt.Sprintf("%s/%s", sc.Name, name),
-			Model:        meta.Model,
-			Quality:      quality,
-			QualityName:  "field_match",
-			TokensIn:     meta.TokensIn,
-			TokensOut:    meta.TokensOut,
-			TTFT:         meta.TTFT,
-			TotalTime:    meta.TotalTime,
-			TokensPerSec: meta.TokensPerSec,
-			CostUSD:      0,
-		})
+	var results []types.BenchmarkResult
+	var totalTokensOut int
+	for i, r := range runs {
+		// A case that failed even after retries is reported with zero
+		// quality rather than aborting the remaining scenarios.
+		st := stats[cases[i].Mode]
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "  WARNING: %v\n", r.Err)
+			if r.Value.Error == "" {
+				continue
+			}
+			st.Failed++
+		}
+		st.Runs++
+		st.Quality += r.Value.Quality
+		st.TotalTime += r.Value.TotalTime
+		st.Valid += r.Value.SchemaValid
+		totalTokensOut += r.Value.TokensOut
+		results = append(results, r.Value)
 	}
+	fmt.Printf("\n  %s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(totalTokensOut))
 
 	return results, nil
 }
+
+// compareModes renders a Markdown table comparing free-JSON and
+// schema-constrained output on field match, schema validity and latency.
+func compareModes(model string, modes []string, stats map[string]*modeStats) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## Free JSON vs Schema-Constrained (%s)\n\n", model))
+	sb.WriteString("| Format | Runs | Field Match | Schema Valid | Avg Latency |\n")
+	sb.WriteString("|--------|------|-------------|--------------|-------------|\n")
+	for _, m := range modes {
+		st := stats[m]
+		if st.Runs == 0 {
+			continue
+		}
+		n := float64(st.Runs)
+		latency := "-"
+		if timed := st.Runs - st.Failed; timed > 0 {
+			latency = fmt.Sprintf("%.2fs", st.TotalTime.Seconds()/float64(timed))
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %.1f%% | %.1f%% (%d/%d) | %s |\n",
+			m, st.Runs, st.Quality/n*100, float64(st.Valid)/n*100, st.Valid, st.Runs, latency))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// writeOutput saves one raw model response, creating parent directories.
+func writeOutput(path, response string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create results dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(response), 0o644); err != nil {
+		return fmt.Errorf("write result %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+// Package summarize holds the summarization example's scenarios, prompt
+// rendering and scoring, shared by the example's main and the llmbench CLI.
+package summarize
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/statherm/local-llm-examples/shared/manifest"
+	"github.com/statherm/local-llm-examples/shared/schema"
+	"github.com/statherm/local-llm-examples/shared/scoring"
+)
+
+// Scenario is a summarization test case from the example's manifest
+// (scenarios.json). Input, Expected and Prompt are files relative to the
+// example directory.
+type Scenario struct {
+	manifest.Scenario
+}
+
+// Scorers maps the scorer names a manifest may use to their functions.
+// KeywordRecall is the default. The overlap metrics score their F1 (ROUGE)
+// or their single score (BLEU, chrF); see TextScores.
+var Scorers = map[string]func(expected, actual string) float64{
+	KeywordRecall: ScoreTextSummary,
+	ActionMatch:   ScoreMeetingActions,
+	Rouge1:        func(e, a string) float64 { return TextScores(e, a).Rouge1.F },
+	Rouge2:        func(e, a string) float64 { return TextScores(e, a).Rouge2.F },
+	RougeL:        func(e, a string) float64 { return TextScores(e, a).RougeL.F },
+	BLEU:          func(e, a string) float64 { return TextScores(e, a).BLEU },
+	ChrF:          func(e, a string) float64 { return TextScores(e, a).ChrF },
+}
+
+// Scorer names.
+const (
+	KeywordRecall = "keyword-recall"
+	ActionMatch   = "action-match"
+	Rouge1        = "rouge-1"
+	Rouge2        = "rouge-2"
+	RougeL        = "rouge-l"
+	BLEU          = "bleu"
+	ChrF          = "chrf"
+)
+
+// defaultMaxTokens caps responses for scenarios without max_tokens.
+const defaultMaxTokens = 2048
+
+// LoadScenarios reads the manifest in dir.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	entries, err := manifest.Load(dir, KeywordRecall, ActionMatch, Rouge1, Rouge2, RougeL, BLEU, ChrF)
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]Scenario, len(entries))
+	for i, e := range entries {
+		scenarios[i] = Scenario{e}
+	}
+	return scenarios, nil
+}
+
+// Find returns the scenario called name.
+func Find(scenarios []Scenario, name string) (Scenario, bool) {
+	for _, s := range scenarios {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Scenario{}, false
+}
+
+// Fixture is a scenario's input, rendered prompt and expected output.
+type Fixture struct {
+	Input    string
+	Prompt   string
+	Expected string
+}
+
+// Load reads the scenario's files from dir and renders its prompt.
+func (s Scenario) Load(dir string) (Fixture, error) {
+	input, err := os.ReadFile(filepath.Join(dir, s.Input))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("reading input: %w", err)
+	}
+
+	promptTmpl, err := os.ReadFile(filepath.Join(dir, s.Prompt))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("reading prompt: %w", err)
+	}
+
+	prompt, err := RenderPrompt(string(promptTmpl), string(input))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("rendering prompt: %w", err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join(dir, s.Expected))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("reading expected: %w", err)
+	}
+
+	return Fixture{Input: string(input), Prompt: prompt, Expected: string(expected)}, nil
+}
+
+// System returns the scenario's system prompt. JSON-mode scenarios (meeting
+// actions) get one reinforcing array output; the rest have none.
+func (s Scenario) System() string {
+	if s.JSONMode {
+		return "You respond only with valid JSON. When the user asks for action items, you MUST return a JSON array containing ALL items. Do not stop after the first item."
+	}
+	return ""
+}
+
+// MaxTokens returns the scenario's response cap.
+func (s Scenario) MaxTokens() int {
+	if s.Scenario.MaxTokens > 0 {
+		return s.Scenario.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// Score rates actual against expected with the scenario's scorer. A
+// scenario not loaded from a manifest scores with the default.
+func (s Scenario) Score(expected, actual string) float64 {
+	score, ok := Scorers[s.Scorer]
+	if !ok {
+		score = ScoreTextSummary
+	}
+	return score(expected, actual)
+}
+
+// QualityName names the metric Score reports.
+func (s Scenario) QualityName() string {
+	if _, ok := Scorers[s.Scorer]; !ok {
+		return KeywordRecall
+	}
+	return s.Scorer
+}
+
+// Schema returns the scenario's JSON output contract, or nil if it has none.
+// Only action-item extraction has one.
+func (s Scenario) Schema() *schema.Schema {
+	if s.Scorer == ActionMatch {
+		return ActionItemsSchema
+	}
+	return nil
+}
+
+// ActionItemsSchema is the output contract stated in prompts/action-items.txt:
+// a bare array of {owner, action, deadline} objects.
+var ActionItemsSchema = schema.MustParse(`{
+  "type": "array",
+  "minItems": 1,
+  "items": {
+    "type": "object",
+    "properties": {
+      "owner": {"type": "string", "minLength": 1},
+      "action": {"type": "string", "minLength": 1},
+      "deadline": {"type": ["string", "null"]}
+    },
+    "required": ["owner", "action", "deadline"]
+  }
+}`)
+
+// ScoreTextSummary scores a text summary using keyword recall: what fraction of
+// meaningful keywords from the expected summary appear in the actual output.
+// This is more appropriate than token F1 for summarization where phrasing varies
+// but key concepts should be preserved.
+func ScoreTextSummary(expected, actual string) float64 {
+	expTokens := tokenize(expected)
+	actTokens := tokenize(actual)
+
+	// Build set of actual tokens for lookup
+	actSet := make(map[string]bool)
+	for _, t := range actTokens {
+		actSet[t] = true
+	}
+
+	// Filter expected tokens to meaningful keywords (skip stopwords)
+	keywords := filterStopwords(expTokens)
+	if len(keywords) == 0 {
+		return scoring.F1Score(expTokens, actTokens) // fallback
+	}
+
+	// Keyword recall: what fraction of expected keywords appear in actual
+	found := 0
+	for _, kw := range keywords {
+		if actSet[kw] {
+			found++
+		}
+	}
+	recall := float64(found) / float64(len(keywords))
+
+	// Also compute brevity penalty: penalize outputs that are wildly longer
+	lenRatio := float64(len(actTokens)) / float64(len(expTokens))
+	brevity := 1.0
+	if lenRatio > 3.0 {
+		brevity = 3.0 / lenRatio
+	}
+
+	return recall * brevity
+}
+
+// TextMetrics are the reference-overlap metrics of a text summary, reported
+// next to keyword recall so results are comparable with published
+// summarization work. All are in [0, 1].
+type TextMetrics struct {
+	KeywordRecall float64     `json:"keyword_recall"`
+	Rouge1        scoring.PRF `json:"rouge_1"`
+	Rouge2        scoring.PRF `json:"rouge_2"`
+	RougeL        scoring.PRF `json:"rouge_l"`
+	BLEU          float64     `json:"bleu"`
+	ChrF          float64     `json:"chrf"`
+}
+
+// TextScores computes every TextMetrics metric of actual against expected.
+// The word metrics share scoring.Tokenize.
+func TextScores(expected, actual string) TextMetrics {
+	ref, cand := scoring.Tokenize(expected), scoring.Tokenize(actual)
+	return TextMetrics{
+		KeywordRecall: ScoreTextSummary(expected, actual),
+		Rouge1:        scoring.RougeN(ref, cand, 1),
+		Rouge2:        scoring.RougeN(ref, cand, 2),
+		RougeL:        scoring.RougeL(ref, cand),
+		BLEU:          scoring.BLEU(ref, cand),
+		ChrF:          scoring.ChrF(expected, actual),
+	}
+}
+
+// HasTextMetrics reports whether the scenario produces a text summary that
+// TextScores applies to, rather than JSON action items.
+func (s Scenario) HasTextMetrics() bool {
+	return s.Scorer != ActionMatch
+}
+
+// Common English stopwords to skip when computing keyword recall.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "was": true,
+	"were": true, "be": true, "been": true, "being": true, "have": true,
+	"has": true, "had": true, "do": true, "does": true, "did": true,
+	"will": true, "would": true, "could": true, "should": true, "may": true,
+	"might": true, "shall": true, "can": true, "to": true, "of": true,
+	"in": true, "for": true, "on": true, "with": true, "at": true,
+	"by": true, "from": true, "as": true, "into": true, "through": true,
+	"during": true, "before": true, "after": true, "above": true, "below": true,
+	"between": true, "out": true, "off": true, "over": true, "under": true,
+	"again": true, "further": true, "then": true, "once": true, "and": true,
+	"but": true, "or": true, "nor": true, "not": true, "so": true, "yet": true,
+	"both": true, "either": true, "neither": true, "each": true, "every": true,
+	"all": true, "any": true, "few": true, "more": true, "most": true,
+	"other": true, "some": true, "such": true, "no": true, "only": true,
+	"own": true, "same": true, "than": true, "too": true, "very": true,
+	"just": true, "because": true, "if": true, "when": true, "where": true,
+	"how": true, "what": true, "which": true, "who": true, "whom": true,
+	"this": true, "that": true, "these": true, "those": true, "it": true,
+	"its": true, "i": true, "me": true, "my": true, "we": true, "our": true,
+	"you": true, "your": true, "he": true, "him": true, "his": true,
+	"she": true, "her": true, "they": true, "them": true, "their": true,
+}
+
+func filterStopwords(tokens []string) []string {
+	var result []string
+	for _, t := range tokens {
+		if !stopwords[t] && len(t) > 2 {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// ScoreMeetingActions scores action item extraction by comparing JSON arrays.
+// Handles models that wrap the array in an object (e.g. {"actionItems": [...]})
+// or return a single object instead of an array.
+func ScoreMeetingActions(expected, actual string) float64 {
+	var expItems []actionItem
+	if err := json.Unmarshal([]byte(expected), &expItems); err != nil {
+		return 0
+	}
+
+	actItems := parseActionItems(actual)
+	if len(expItems) == 0 || len(actItems) == 0 {
+		return 0
+	}
+
+	// Score by matching owners and checking action text overlap
+	matched := 0
+	for _, exp := range expItems {
+		for _, act := range actItems {
+			if scoring.ExactMatch(exp.Owner, act.Owner) {
+				expWords := tokenize(exp.Action)
+				actWords := tokenize(act.Action)
+				f1 := scoring.F1Score(expWords, actWords)
+				if f1 > 0.3 {
+					matched++
+					break
+				}
+			}
+		}
+	}
+
+	return float64(matched) / float64(len(expItems))
+}
+
+type actionItem struct {
+	Owner    string  `json:"owner"`
+	Action   string  `json:"action"`
+	Deadline *string `json:"deadline"`
+}
+
+// parseActionItems tries to extract action items from various JSON formats:
+// 1. Direct array: [{"owner": ...}, ...]
+// 2. Wrapper object: {"actionItems": [{"owner": ...}, ...]}
+// 3. Single object: {"owner": ...}
+func parseActionItems(s string) []actionItem {
+	// Try direct array
+	var items []actionItem
+	if err := json.Unmarshal([]byte(s), &items); err == nil {
+		return items
+	}
+
+	// Try wrapper object — find the first array value
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &wrapper); err == nil {
+		for _, v := range wrapper {
+			var arr []actionItem
+			if err := json.Unmarshal(v, &arr); err == nil && len(arr) > 0 {
+				return arr
+			}
+		}
+	}
+
+	// Try single object
+	var single actionItem
+	if err := json.Unmarshal([]byte(s), &single); err == nil && single.Owner != "" {
+		return []actionItem{single}
+	}
+
+	return nil
+}
+
+// tokenize splits text into lowercase word tokens.
+func tokenize(s string) []string {
+	words := strings.Fields(strings.ToLower(s))
+	var tokens []string
+	for _, w := range words {
+		// Strip common punctuation
+		w = strings.Trim(w, ".,;:!?\"'()[]{}#*-")
+		if w != "" {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// RenderPrompt executes a prompt template with input as {{.Input}}.
+func RenderPrompt(tmpl, input string) (string, error) {
+	t, err := template.New("prompt").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	err = t.Execute(&sb, struct{ Input string }{Input: input})
+	return sb.String(), err
+}
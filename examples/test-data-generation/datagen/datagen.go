@@ -0,0 +1,426 @@
+// Package datagen holds the test-data-generation example's schema and
+// constraint types, prompt and record validation, shared by the example's
+// main and the llmbench CLI.
+package datagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/manifest"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	jsonschema "github.com/statherm/local-llm-examples/shared/schema"
+)
+
+// Scenario is a generation task from the example's manifest
+// (scenarios.json): Input is the generation schema and Expected the
+// constraints the records must satisfy, both relative to the example
+// directory.
+type Scenario struct {
+	manifest.Scenario
+}
+
+// Compliance is the only scorer: the weighted mix of schema compliance,
+// rule compliance and uniqueness reported as ScoreDetail.Overall.
+const Compliance = "compliance"
+
+// defaultMaxTokens caps responses for scenarios without max_tokens; a batch
+// of records needs more room than a single answer.
+const defaultMaxTokens = 4096
+
+// LoadScenarios reads the manifest in dir.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	entries, err := manifest.Load(dir, Compliance)
+	if err != nil {
+		return nil, err
+	}
+	scenarios := make([]Scenario, len(entries))
+	for i, e := range entries {
+		scenarios[i] = Scenario{e}
+	}
+	return scenarios, nil
+}
+
+// MaxTokens returns the scenario's response cap.
+func (s Scenario) MaxTokens() int {
+	if s.Scenario.MaxTokens > 0 {
+		return s.Scenario.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// Schema describes the structure of data to generate.
+type Schema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Count       int             `json:"count"`
+	Fields      []FieldDef      `json:"fields"`
+	Example     json.RawMessage `json:"example"`
+}
+
+// FieldDef describes a single field in the schema.
+type FieldDef struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// Constraints defines validation rules for generated data.
+type Constraints struct {
+	Schema             string           `json:"schema"`
+	Rules              []Rule           `json:"rules"`
+	DistributionChecks []DistCheck      `json:"distribution_checks"`
+	CrossFieldRules    []CrossFieldRule `json:"cross_field_rules"`
+}
+
+// Rule is a single validation rule for a field.
+// Min/Max use interface{} because they can be numeric (for "range") or
+// string (for "date_range").
+type Rule struct {
+	Field        string      `json:"field"`
+	RuleType     string      `json:"rule"`
+	Min          interface{} `json:"min,omitempty"`
+	Max          interface{} `json:"max,omitempty"`
+	Exact        *int        `json:"exact,omitempty"`
+	Regex        string      `json:"regex,omitempty"`
+	Values       []string    `json:"values,omitempty"`
+	ExpectedType string      `json:"expected_type,omitempty"`
+}
+
+// DistCheck describes a distribution check.
+type DistCheck struct {
+	Field       string   `json:"field"`
+	Check       string   `json:"check"`
+	Values      []string `json:"values,omitempty"`
+	Value       *bool    `json:"value,omitempty"`
+	TargetRatio float64  `json:"target_ratio,omitempty"`
+	Tolerance   float64  `json:"tolerance,omitempty"`
+	Min         int      `json:"min,omitempty"`
+}
+
+// CrossFieldRule defines a relationship between fields.
+type CrossFieldRule struct {
+	RuleType  string      `json:"rule"`
+	IfField   string      `json:"if_field"`
+	IfValue   interface{} `json:"if_value"`
+	ThenField string      `json:"then_field"`
+	ThenValue interface{} `json:"then_value"`
+}
+
+// ScoreDetail breaks down the compliance score.
+type ScoreDetail struct {
+	SchemaCompliance float64 `json:"schema_compliance"`
+	RuleCompliance   float64 `json:"rule_compliance"`
+	Uniqueness       float64 `json:"uniqueness"`
+	Overall          float64 `json:"overall"`
+	// ValidRecords counts records that satisfy the JSON Schema derived from
+	// the schema fields and constraint rules (see RecordSchema).
+	ValidRecords int      `json:"valid_records"`
+	Violations   []string `json:"violations,omitempty"`
+}
+
+// SystemPrompt asks for a {"records": [...]} object.
+const SystemPrompt = `You are a test data generator. Given a schema definition with field types and constraints, generate realistic synthetic data records.
+
+Requirements:
+- Generate exactly the number of records requested
+- Every field in the schema must be present in every record
+- Values must be realistic and diverse — not placeholder text like "John Doe" repeated
+- Follow all type constraints and value descriptions exactly
+- IDs must be unique across records
+- Dates must be valid ISO 8601 format
+
+Respond with valid JSON in this exact format:
+{"records": [<array of objects matching the schema>]}`
+
+// BuildPrompt describes schema and asks for schema.Count records.
+func BuildPrompt(schema Schema) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Generate %d realistic %s records.\n\n", schema.Count, schema.Name))
+	sb.WriteString(fmt.Sprintf("Description: %s\n\n", schema.Description))
+	sb.WriteString("Schema:\n")
+	for _, f := range schema.Fields {
+		sb.WriteString(fmt.Sprintf("  - %s (%s): %s\n", f.Name, f.Type, f.Description))
+	}
+	sb.WriteString(fmt.Sprintf("\nExample record:\n%s\n", string(schema.Example)))
+	sb.WriteString(fmt.Sprintf("\nGenerate exactly %d records. Each record must have all fields. Return JSON.", schema.Count))
+	return sb.String()
+}
+
+// ValidateRecords checks generated records against schema and constraints.
+func ValidateRecords(records []map[string]interface{}, schema Schema, constraints Constraints) ScoreDetail {
+	var violations []string
+	totalFieldChecks := 0
+	passedFieldChecks := 0
+	totalRuleChecks := 0
+	passedRuleChecks := 0
+
+	// Check record count
+	if len(records) != schema.Count {
+		violations = append(violations, fmt.Sprintf("expected %d records, got %d", schema.Count, len(records)))
+	}
+
+	// Schema compliance: every field must exist with correct type
+	for i, rec := range records {
+		for _, field := range schema.Fields {
+			totalFieldChecks++
+			val, ok := rec[field.Name]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("record %d: missing field %q", i, field.Name))
+				continue
+			}
+			if val == nil {
+				violations = append(violations, fmt.Sprintf("record %d: field %q is null", i, field.Name))
+				continue
+			}
+
+			// Basic type check
+			typeOk := jsonschema.TypeMatches(val, field.Type)
+			if !typeOk {
+				violations = append(violations, fmt.Sprintf("record %d: field %q has wrong type (expected %s)", i, field.Name, field.Type))
+				continue
+			}
+			passedFieldChecks++
+		}
+	}
+
+	// Rule compliance
+	for _, rule := range constraints.Rules {
+		for i, rec := range records {
+			val, ok := rec[rule.Field]
+			if !ok {
+				continue
+			}
+			totalRuleChecks++
+			if checkRule(val, rule) {
+				passedRuleChecks++
+			} else {
+				violations = append(violations, fmt.Sprintf("record %d: field %q violates rule %q", i, rule.Field, rule.RuleType))
+			}
+		}
+	}
+
+	// Uniqueness check
+	uniqueFields := make(map[string]bool)
+	for _, rule := range constraints.Rules {
+		if rule.RuleType == "unique" {
+			uniqueFields[rule.Field] = true
+		}
+	}
+
+	uniqueScore := 1.0
+	for field := range uniqueFields {
+		seen := make(map[string]bool)
+		dupes := 0
+		for _, rec := range records {
+			val, ok := rec[field]
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("%v", val)
+			if seen[key] {
+				dupes++
+			}
+			seen[key] = true
+		}
+		if len(records) > 0 && dupes > 0 {
+			uniqueScore *= 1.0 - float64(dupes)/float64(len(records))
+		}
+	}
+
+	schemaCompliance := 0.0
+	if totalFieldChecks > 0 {
+		schemaCompliance = float64(passedFieldChecks) / float64(totalFieldChecks)
+	}
+	ruleCompliance := 0.0
+	if totalRuleChecks > 0 {
+		ruleCompliance = float64(passedRuleChecks) / float64(totalRuleChecks)
+	}
+
+	overall := (schemaCompliance*0.4 + ruleCompliance*0.4 + uniqueScore*0.2)
+
+	validRecords := 0
+	if rs, err := RecordSchema(schema, constraints); err != nil {
+		violations = append(violations, fmt.Sprintf("derive JSON Schema: %v", err))
+	} else {
+		for _, rec := range records {
+			if rs.Valid(map[string]any(rec)) {
+				validRecords++
+			}
+		}
+	}
+
+	return ScoreDetail{
+		SchemaCompliance: schemaCompliance,
+		RuleCompliance:   ruleCompliance,
+		Uniqueness:       uniqueScore,
+		Overall:          overall,
+		ValidRecords:     validRecords,
+		Violations:       violations,
+	}
+}
+
+// RecordSchema translates a generation schema and its per-field constraint
+// rules into a JSON Schema for a single record, so each generated record can
+// be checked for schema validity as a whole. Rules without a JSON Schema
+// equivalent (unique, date_range, cross-field rules) are left to
+// ValidateRecords.
+func RecordSchema(schema Schema, constraints Constraints) (*jsonschema.Schema, error) {
+	props := make(map[string]map[string]any)
+	var required []string
+	for _, f := range schema.Fields {
+		props[f.Name] = map[string]any{"type": f.Type}
+		required = append(required, f.Name)
+	}
+
+	for _, rule := range constraints.Rules {
+		p, ok := props[rule.Field]
+		if !ok {
+			p = make(map[string]any)
+			props[rule.Field] = p
+		}
+		switch rule.RuleType {
+		case "range":
+			if rule.Min != nil {
+				p["minimum"] = rule.Min
+			}
+			if rule.Max != nil {
+				p["maximum"] = rule.Max
+			}
+		case "length":
+			if rule.Exact != nil {
+				p["minLength"] = *rule.Exact
+				p["maxLength"] = *rule.Exact
+			}
+		case "pattern":
+			p["pattern"] = rule.Regex
+		case "enum":
+			p["enum"] = rule.Values
+		case "type":
+			p["type"] = rule.ExpectedType
+		case "array_length":
+			if rule.Min != nil {
+				p["minItems"] = rule.Min
+			}
+			if rule.Max != nil {
+				p["maxItems"] = rule.Max
+			}
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.Parse(data)
+}
+
+func checkRule(val interface{}, rule Rule) bool {
+	switch rule.RuleType {
+	case "range":
+		f, ok := toFloat(val)
+		if !ok {
+			return false
+		}
+		if min, ok := toFloat(rule.Min); ok && f < min {
+			return false
+		}
+		if max, ok := toFloat(rule.Max); ok && f > max {
+			return false
+		}
+		return true
+
+	case "length":
+		s, ok := val.(string)
+		if !ok {
+			return false
+		}
+		if rule.Exact != nil && len(s) != *rule.Exact {
+			return false
+		}
+		return true
+
+	case "pattern":
+		s, ok := val.(string)
+		if !ok {
+			return false
+		}
+		matched, err := regexp.MatchString(rule.Regex, s)
+		return err == nil && matched
+
+	case "enum":
+		s := fmt.Sprintf("%v", val)
+		for _, v := range rule.Values {
+			if strings.EqualFold(s, v) {
+				return true
+			}
+		}
+		return false
+
+	case "type":
+		return jsonschema.TypeMatches(val, rule.ExpectedType)
+
+	case "array_length":
+		arr, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		if min, ok := toFloat(rule.Min); ok && len(arr) < int(min) {
+			return false
+		}
+		if max, ok := toFloat(rule.Max); ok && len(arr) > int(max) {
+			return false
+		}
+		return true
+
+	case "unique":
+		// Handled separately in ValidateRecords
+		return true
+
+	case "date_range":
+		// Just check that it's a valid-looking date string
+		s, ok := val.(string)
+		if !ok {
+			return false
+		}
+		matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}$`, s)
+		return matched
+
+	default:
+		return true
+	}
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	if val == nil {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// ParseRecords decodes a {"records": [...]} response. A batch cut off by the
+// token cap keeps the records that were complete.
+func ParseRecords(resp string) ([]map[string]interface{}, error) {
+	var output struct {
+		Records []map[string]interface{} `json:"records"`
+	}
+	if err := repair.Unmarshal(resp, &output); err != nil {
+		return nil, err
+	}
+	return output.Records, nil
+}
@@ -0,0 +1,67 @@
+package datagen
+
+import (
+	"path/filepath"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs each generation schema through the bench interface as a
+// single case scored by overall compliance. Schema validity is counted per
+// generated record, as in the example's report.
+type Example struct{}
+
+type schemaCase struct {
+	Scenario    Scenario
+	Schema      Schema
+	Constraints Constraints
+}
+
+func (Example) Name() string { return "test-data-generation" }
+
+func (Example) Description() string {
+	return "Generate synthetic records that satisfy a schema and constraint rules"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	scenarios, err := LoadScenarios(dir)
+	if err != nil {
+		return nil, err
+	}
+	cases := make([]bench.Case, 0, len(scenarios))
+	for _, sc := range scenarios {
+		d := schemaCase{Scenario: sc}
+		if err := bench.LoadJSON(filepath.Join(dir, sc.Input), &d.Schema); err != nil {
+			return nil, err
+		}
+		if err := bench.LoadJSON(filepath.Join(dir, sc.Expected), &d.Constraints); err != nil {
+			return nil, err
+		}
+		cases = append(cases, bench.Case{ID: sc.Name, Scenario: sc.Name, Data: d})
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	d := c.Data.(schemaCase)
+	return ollama.NewChatRequest("", SystemPrompt, BuildPrompt(d.Schema), true, d.Scenario.MaxTokens())
+}
+
+func (Example) Parse(_ bench.Case, raw string) (any, error) {
+	return ParseRecords(raw)
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	d := c.Data.(schemaCase)
+	records := parsed.([]map[string]interface{})
+	score := ValidateRecords(records, d.Schema, d.Constraints)
+	return bench.Score{
+		Quality:      score.Overall,
+		Metric:       "Compliance",
+		SchemaChecks: len(records),
+		SchemaValid:  score.ValidRecords,
+	}
+}
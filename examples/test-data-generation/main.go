@@ -1,352 +1,51 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
+	"github.com/statherm/local-llm-examples/examples/test-data-generation/datagen"
+	"github.com/statherm/local-llm-examples/shared/bench"
 	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/pricing"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
 	"github.com/statherm/local-llm-examples/shared/reporting"
+	"github.com/statherm/local-llm-examples/shared/runner"
+	"github.com/statherm/local-llm-examples/shared/sweep"
 	"github.com/statherm/local-llm-examples/shared/types"
 )
 
-// Schema describes the structure of data to generate.
-type Schema struct {
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	Count       int           `json:"count"`
-	Fields      []FieldDef    `json:"fields"`
-	Example     json.RawMessage `json:"example"`
-}
-
-// FieldDef describes a single field in the schema.
-type FieldDef struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Description string `json:"description"`
-}
-
-// Constraints defines validation rules for generated data.
-type Constraints struct {
-	Schema             string           `json:"schema"`
-	Rules              []Rule           `json:"rules"`
-	DistributionChecks []DistCheck      `json:"distribution_checks"`
-	CrossFieldRules    []CrossFieldRule `json:"cross_field_rules"`
-}
-
-// Rule is a single validation rule for a field.
-// Min/Max use interface{} because they can be numeric (for "range") or
-// string (for "date_range").
-type Rule struct {
-	Field        string      `json:"field"`
-	RuleType     string      `json:"rule"`
-	Min          interface{} `json:"min,omitempty"`
-	Max          interface{} `json:"max,omitempty"`
-	Exact        *int        `json:"exact,omitempty"`
-	Regex        string      `json:"regex,omitempty"`
-	Values       []string    `json:"values,omitempty"`
-	ExpectedType string      `json:"expected_type,omitempty"`
-}
-
-// DistCheck describes a distribution check.
-type DistCheck struct {
-	Field       string   `json:"field"`
-	Check       string   `json:"check"`
-	Values      []string `json:"values,omitempty"`
-	Value       *bool    `json:"value,omitempty"`
-	TargetRatio float64  `json:"target_ratio,omitempty"`
-	Tolerance   float64  `json:"tolerance,omitempty"`
-	Min         int      `json:"min,omitempty"`
-}
-
-// CrossFieldRule defines a relationship between fields.
-type CrossFieldRule struct {
-	RuleType  string      `json:"rule"`
-	IfField   string      `json:"if_field"`
-	IfValue   interface{} `json:"if_value"`
-	ThenField string      `json:"then_field"`
-	ThenValue interface{} `json:"then_value"`
-}
-
 // ScenarioResult stores the output for one schema.
 type ScenarioResult struct {
-	Schema     string                   `json:"schema"`
-	Model      string                   `json:"model"`
-	Records    []map[string]interface{} `json:"records"`
-	Score      ScoreDetail              `json:"score"`
-	Meta       types.ModelMetadata      `json:"metadata"`
-}
-
-// ScoreDetail breaks down the compliance score.
-type ScoreDetail struct {
-	SchemaCompliance float64 `json:"schema_compliance"`
-	RuleCompliance   float64 `json:"rule_compliance"`
-	Uniqueness       float64 `json:"uniqueness"`
-	Overall          float64 `json:"overall"`
-	Violations       []string `json:"violations,omitempty"`
-}
-
-const systemPrompt = `You are a test data generator. Given a schema definition with field types and constraints, generate realistic synthetic data records.
-
-Requirements:
-- Generate exactly the number of records requested
-- Every field in the schema must be present in every record
-- Values must be realistic and diverse — not placeholder text like "John Doe" repeated
-- Follow all type constraints and value descriptions exactly
-- IDs must be unique across records
-- Dates must be valid ISO 8601 format
-
-Respond with valid JSON in this exact format:
-{"records": [<array of objects matching the schema>]}`
-
-func buildPrompt(schema Schema) string {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Generate %d realistic %s records.\n\n", schema.Count, schema.Name))
-	sb.WriteString(fmt.Sprintf("Description: %s\n\n", schema.Description))
-	sb.WriteString("Schema:\n")
-	for _, f := range schema.Fields {
-		sb.WriteString(fmt.Sprintf("  - %s (%s): %s\n", f.Name, f.Type, f.Description))
-	}
-	sb.WriteString(fmt.Sprintf("\nExample record:\n%s\n", string(schema.Example)))
-	sb.WriteString(fmt.Sprintf("\nGenerate exactly %d records. Each record must have all fields. Return JSON.", schema.Count))
-	return sb.String()
-}
-
-// validateRecords checks generated records against schema and constraints.
-func validateRecords(records []map[string]interface{}, schema Schema, constraints Constraints) ScoreDetail {
-	var violations []string
-	totalFieldChecks := 0
-	passedFieldChecks := 0
-	totalRuleChecks := 0
-	passedRuleChecks := 0
-
-	// Check record count
-	if len(records) != schema.Count {
-		violations = append(violations, fmt.Sprintf("expected %d records, got %d", schema.Count, len(records)))
-	}
-
-	// Schema compliance: every field must exist with correct type
-	for i, rec := range records {
-		for _, field := range schema.Fields {
-			totalFieldChecks++
-			val, ok := rec[field.Name]
-			if !ok {
-				violations = append(violations, fmt.Sprintf("record %d: missing field %q", i, field.Name))
-				continue
-			}
-			if val == nil {
-				violations = append(violations, fmt.Sprintf("record %d: field %q is null", i, field.Name))
-				continue
-			}
-
-			// Basic type check
-			typeOk := checkType(val, field.Type)
-			if !typeOk {
-				violations = append(violations, fmt.Sprintf("record %d: field %q has wrong type (expected %s)", i, field.Name, field.Type))
-				continue
-			}
-			passedFieldChecks++
-		}
-	}
-
-	// Rule compliance
-	for _, rule := range constraints.Rules {
-		for i, rec := range records {
-			val, ok := rec[rule.Field]
-			if !ok {
-				continue
-			}
-			totalRuleChecks++
-			if checkRule(val, rule) {
-				passedRuleChecks++
-			} else {
-				violations = append(violations, fmt.Sprintf("record %d: field %q violates rule %q", i, rule.Field, rule.RuleType))
-			}
-		}
-	}
-
-	// Uniqueness check
-	uniqueFields := make(map[string]bool)
-	for _, rule := range constraints.Rules {
-		if rule.RuleType == "unique" {
-			uniqueFields[rule.Field] = true
-		}
-	}
-
-	uniqueScore := 1.0
-	for field := range uniqueFields {
-		seen := make(map[string]bool)
-		dupes := 0
-		for _, rec := range records {
-			val, ok := rec[field]
-			if !ok {
-				continue
-			}
-			key := fmt.Sprintf("%v", val)
-			if seen[key] {
-				dupes++
-			}
-			seen[key] = true
-		}
-		if len(records) > 0 && dupes > 0 {
-			uniqueScore *= 1.0 - float64(dupes)/float64(len(records))
-		}
-	}
-
-	schemaCompliance := 0.0
-	if totalFieldChecks > 0 {
-		schemaCompliance = float64(passedFieldChecks) / float64(totalFieldChecks)
-	}
-	ruleCompliance := 0.0
-	if totalRuleChecks > 0 {
-		ruleCompliance = float64(passedRuleChecks) / float64(totalRuleChecks)
-	}
-
-	overall := (schemaCompliance*0.4 + ruleCompliance*0.4 + uniqueScore*0.2)
-
-	return ScoreDetail{
-		SchemaCompliance: schemaCompliance,
-		RuleCompliance:   ruleCompliance,
-		Uniqueness:       uniqueScore,
-		Overall:          overall,
-		Violations:       violations,
-	}
-}
-
-func checkType(val interface{}, expectedType string) bool {
-	switch expectedType {
-	case "string":
-		_, ok := val.(string)
-		return ok
-	case "integer":
-		f, ok := val.(float64)
-		if !ok {
-			return false
-		}
-		return f == float64(int64(f))
-	case "number":
-		_, ok := val.(float64)
-		return ok
-	case "boolean":
-		_, ok := val.(bool)
-		return ok
-	case "array":
-		_, ok := val.([]interface{})
-		return ok
-	default:
-		return true
-	}
-}
-
-func checkRule(val interface{}, rule Rule) bool {
-	switch rule.RuleType {
-	case "range":
-		f, ok := toFloat(val)
-		if !ok {
-			return false
-		}
-		if min, ok := toFloat(rule.Min); ok && f < min {
-			return false
-		}
-		if max, ok := toFloat(rule.Max); ok && f > max {
-			return false
-		}
-		return true
-
-	case "length":
-		s, ok := val.(string)
-		if !ok {
-			return false
-		}
-		if rule.Exact != nil && len(s) != *rule.Exact {
-			return false
-		}
-		return true
-
-	case "pattern":
-		s, ok := val.(string)
-		if !ok {
-			return false
-		}
-		matched, err := regexp.MatchString(rule.Regex, s)
-		return err == nil && matched
-
-	case "enum":
-		s := fmt.Sprintf("%v", val)
-		for _, v := range rule.Values {
-			if strings.EqualFold(s, v) {
-				return true
-			}
-		}
-		return false
-
-	case "type":
-		return checkType(val, rule.ExpectedType)
-
-	case "array_length":
-		arr, ok := val.([]interface{})
-		if !ok {
-			return false
-		}
-		if min, ok := toFloat(rule.Min); ok && len(arr) < int(min) {
-			return false
-		}
-		if max, ok := toFloat(rule.Max); ok && len(arr) > int(max) {
-			return false
-		}
-		return true
-
-	case "unique":
-		// Handled separately in validateRecords
-		return true
-
-	case "date_range":
-		// Just check that it's a valid-looking date string
-		s, ok := val.(string)
-		if !ok {
-			return false
-		}
-		matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}$`, s)
-		return matched
-
-	default:
-		return true
-	}
-}
-
-func toFloat(val interface{}) (float64, bool) {
-	if val == nil {
-		return 0, false
-	}
-	switch v := val.(type) {
-	case float64:
-		return v, true
-	case int:
-		return float64(v), true
-	case int64:
-		return float64(v), true
-	default:
-		return 0, false
-	}
-}
-
-func loadJSON(path string, v interface{}) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read %s: %w", path, err)
-	}
-	return json.Unmarshal(data, v)
+	Schema  string                   `json:"schema"`
+	Model   string                   `json:"model"`
+	Records []map[string]interface{} `json:"records"`
+	Score   datagen.ScoreDetail      `json:"score"`
+	Meta    types.ModelMetadata      `json:"metadata"`
+	// JSON records whether the output parsed as returned or needed repair,
+	// e.g. closing a batch the token cap cut off.
+	JSON repair.Outcome `json:"json,omitempty"`
+	// Error is why the model call failed, after any retries. A failed
+	// batch has no records and scores zero.
+	Error string `json:"error,omitempty"`
 }
 
 func main() {
 	model := flag.String("model", "qwen3:4b", "Ollama model to use")
+	modelList := flag.String("models", "", "Comma-separated models to run and compare (overrides -model)")
+	modelsFile := flag.String("models-file", "", "File listing models to run and compare, one per line")
 	doScore := flag.Bool("score", false, "Score existing results against constraints")
 	doReport := flag.Bool("report", false, "Generate benchmark report from results")
+	parallel := flag.Int("parallel", 1, "Number of concurrent model requests")
+	conn := provider.AddFlags(flag.CommandLine)
+	pricingFile := flag.String("pricing", "", "Pricing table for the Cost/Call column (default: pricing.json in this or a parent directory)")
+	reasks := flag.Int("reask", 0, "Times to re-ask the model with the parse error when its JSON cannot be repaired")
 	flag.Parse()
 
 	exampleDir, err := os.Getwd()
@@ -354,14 +53,14 @@ func main() {
 		log.Fatal(err)
 	}
 
-	scenarios := []struct {
-		name       string
-		schema     string
-		constraint string
-	}{
-		{"user_profiles", "schemas/user_profiles.json", "constraints/user_profiles.json"},
-		{"transactions", "schemas/transactions.json", "constraints/transactions.json"},
-		{"api_responses", "schemas/api_responses.json", "constraints/api_responses.json"},
+	scenarios, err := datagen.LoadScenarios(exampleDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	prices, err := pricing.Load(*pricingFile, exampleDir)
+	if err != nil {
+		log.Fatalf("load pricing: %v", err)
 	}
 
 	if *doScore {
@@ -370,78 +69,128 @@ func main() {
 	}
 
 	if *doReport {
-		generateReport(exampleDir)
+		generateReport(exampleDir, prices)
 		return
 	}
 
-	client := ollama.NewClient()
+	models, err := sweep.Models(*model, *modelList, *modelsFile)
+	if err != nil {
+		log.Fatalf("resolve models: %v", err)
+	}
 
-	for _, sc := range scenarios {
-		fmt.Printf("=== Scenario: %s (model: %s) ===\n", sc.name, *model)
+	client, err := conn.New()
+	if err != nil {
+		log.Fatalf("create client: %v", err)
+	}
+	for _, m := range models {
+		if err := bench.Preflight(context.Background(), client, m, conn.Pull, exampleDir); err != nil {
+			log.Fatalf("preflight: %v", err)
+		}
+	}
 
-		var schema Schema
-		if err := loadJSON(filepath.Join(exampleDir, sc.schema), &schema); err != nil {
+	schemas := make([]datagen.Schema, len(scenarios))
+	allConstraints := make([]datagen.Constraints, len(scenarios))
+	for i, sc := range scenarios {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, sc.Input), &schemas[i]); err != nil {
 			log.Fatalf("load schema: %v", err)
 		}
-
-		var constraints Constraints
-		if err := loadJSON(filepath.Join(exampleDir, sc.constraint), &constraints); err != nil {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, sc.Expected), &allConstraints[i]); err != nil {
 			log.Fatalf("load constraints: %v", err)
 		}
+	}
 
-		prompt := buildPrompt(schema)
-		response, meta, err := client.ChatCompletion(*model, systemPrompt, prompt, true, 4096)
-		if err != nil {
-			log.Fatalf("ollama: %v", err)
-		}
-
-		var output struct {
-			Records []map[string]interface{} `json:"records"`
-		}
-		if err := json.Unmarshal([]byte(response), &output); err != nil {
-			log.Printf("WARNING: failed to parse model output as JSON: %v", err)
-			log.Printf("Raw response: %s", response)
-			continue
-		}
-
-		score := validateRecords(output.Records, schema, constraints)
-
-		result := ScenarioResult{
-			Schema:  sc.name,
-			Model:   *model,
-			Records: output.Records,
-			Score:   score,
-			Meta:    meta,
-		}
+	for _, model := range models {
+		// Model calls run concurrently; validation and printing happen
+		// afterwards in scenario order so the output stays readable.
+		runs, summary := runner.Run(context.Background(), schemas, *parallel, func(ctx context.Context, i int, schema datagen.Schema) (repair.Reply, error) {
+			req := ollama.NewChatRequest(model, datagen.SystemPrompt, datagen.BuildPrompt(schema), true, scenarios[i].MaxTokens())
+			return repair.Complete(ctx, client, req, *reasks)
+		})
 
-		fmt.Printf("  Records generated: %d / %d\n", len(output.Records), schema.Count)
-		fmt.Printf("  Schema compliance: %.1f%%\n", score.SchemaCompliance*100)
-		fmt.Printf("  Rule compliance:   %.1f%%\n", score.RuleCompliance*100)
-		fmt.Printf("  Uniqueness:        %.1f%%\n", score.Uniqueness*100)
-		fmt.Printf("  Overall score:     %.1f%%\n", score.Overall*100)
-		fmt.Printf("  Tokens: %d in / %d out (%.1f tok/s)\n", meta.TokensIn, meta.TokensOut, meta.TokensPerSec)
-		fmt.Printf("  Latency: %s (TTFT: %s)\n", meta.TotalTime, meta.TTFT)
-		if len(score.Violations) > 0 {
-			fmt.Printf("  Violations (%d):\n", len(score.Violations))
-			limit := len(score.Violations)
-			if limit > 5 {
-				limit = 5
+		for i, sc := range scenarios {
+			fmt.Printf("=== Scenario: %s (model: %s) ===\n", sc.Name, model)
+
+			schema, constraints := schemas[i], allConstraints[i]
+			if runs[i].Err != nil {
+				// Saved with its retries so the batch still counts against
+				// the model in the report.
+				log.Printf("WARNING: model call failed: %v", runs[i].Err)
+				saveResult(exampleDir, sc.Name, model, ScenarioResult{
+					Schema: sc.Name,
+					Model:  model,
+					Meta:   runs[i].Value.Meta,
+					Error:  runs[i].Err.Error(),
+				})
+				continue
 			}
-			for _, v := range score.Violations[:limit] {
-				fmt.Printf("    - %s\n", v)
+			reply := runs[i].Value
+			response, meta := reply.Raw, reply.Meta
+			meta.QueueTime = runs[i].Queue
+
+			result := ScenarioResult{
+				Schema: sc.Name,
+				Model:  model,
+				Meta:   meta,
+				JSON:   reply.Outcome,
 			}
-			if len(score.Violations) > 5 {
-				fmt.Printf("    ... and %d more\n", len(score.Violations)-5)
+
+			records, err := datagen.ParseRecords(reply.Text)
+			if err != nil {
+				// Saved with a zero score so the batch still counts against
+				// the model in the report.
+				log.Printf("WARNING: failed to parse model output as JSON: %v", err)
+				log.Printf("Raw response: %s", response)
+				saveResult(exampleDir, sc.Name, model, result)
+				continue
 			}
+
+			score := datagen.ValidateRecords(records, schema, constraints)
+			result.Records, result.Score = records, score
+
+			fmt.Printf("  Records generated: %d / %d\n", len(records), schema.Count)
+			fmt.Printf("  Schema compliance: %.1f%%\n", score.SchemaCompliance*100)
+			fmt.Printf("  Rule compliance:   %.1f%%\n", score.RuleCompliance*100)
+			fmt.Printf("  Uniqueness:        %.1f%%\n", score.Uniqueness*100)
+			fmt.Printf("  Schema-valid:      %d / %d records\n", score.ValidRecords, len(records))
+			fmt.Printf("  Overall score:     %.1f%%\n", score.Overall*100)
+			fmt.Printf("  JSON:              %s (re-asks: %d)\n", reply.Outcome, reply.Reasks)
+			fmt.Printf("  Tokens: %d in / %d out (%.1f tok/s)\n", meta.TokensIn, meta.TokensOut, meta.TokensPerSec)
+			fmt.Printf("  Latency: %s (TTFT: %s)\n", meta.TotalTime, meta.TTFT)
+			if len(score.Violations) > 0 {
+				fmt.Printf("  Violations (%d):\n", len(score.Violations))
+				limit := len(score.Violations)
+				if limit > 5 {
+					limit = 5
+				}
+				for _, v := range score.Violations[:limit] {
+					fmt.Printf("    - %s\n", v)
+				}
+				if len(score.Violations) > 5 {
+					fmt.Printf("    ... and %d more\n", len(score.Violations)-5)
+				}
+			}
+			fmt.Println()
+
+			saveResult(exampleDir, sc.Name, model, result)
 		}
-		fmt.Println()
 
-		// Save result
-		resultPath := filepath.Join(exampleDir, "results", fmt.Sprintf("%s_%s.json", sc.name, sanitizeModelName(*model)))
-		resultData, _ := json.MarshalIndent(result, "", "  ")
-		if err := os.WriteFile(resultPath, resultData, 0644); err != nil {
-			log.Printf("WARNING: could not write result: %v", err)
+		var totalTokensOut int
+		for _, r := range runs {
+			totalTokensOut += r.Value.Meta.TokensOut
 		}
+		fmt.Printf("%s, %.1f tok/s aggregate\n", summary, summary.TokensPerSec(totalTokensOut))
+	}
+
+	if len(models) > 1 {
+		fmt.Print(reporting.GenerateComparison(prices.Apply(sweep.Only(bench.WithModelInfo(exampleDir, benchmarkResults(exampleDir)), models, sanitizeModelName))))
+	}
+}
+
+// saveResult writes one schema result to the results directory.
+func saveResult(exampleDir, schema, model string, result ScenarioResult) {
+	resultPath := filepath.Join(exampleDir, "results", fmt.Sprintf("%s_%s.json", schema, sanitizeModelName(model)))
+	if err := bench.WriteJSON(resultPath, result); err != nil {
+		log.Printf("WARNING: could not write result: %v", err)
 	}
 }
 
@@ -450,11 +199,7 @@ func sanitizeModelName(name string) string {
 	return r.Replace(name)
 }
 
-func scoreResults(exampleDir string, scenarios []struct {
-	name       string
-	schema     string
-	constraint string
-}) {
+func scoreResults(exampleDir string, scenarios []datagen.Scenario) {
 	entries, err := os.ReadDir(filepath.Join(exampleDir, "results"))
 	if err != nil {
 		log.Fatalf("read results dir: %v", err)
@@ -466,17 +211,21 @@ func scoreResults(exampleDir string, scenarios []struct {
 		}
 
 		var result ScenarioResult
-		if err := loadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
 			log.Printf("skip %s: %v", entry.Name(), err)
 			continue
 		}
+		if result.Error != "" {
+			fmt.Printf("%s: error=%s\n", entry.Name(), result.Error)
+			continue
+		}
 
 		// Find matching schema and constraints
 		var schemaPath, constraintPath string
 		for _, sc := range scenarios {
-			if sc.name == result.Schema {
-				schemaPath = filepath.Join(exampleDir, sc.schema)
-				constraintPath = filepath.Join(exampleDir, sc.constraint)
+			if sc.Name == result.Schema {
+				schemaPath = filepath.Join(exampleDir, sc.Input)
+				constraintPath = filepath.Join(exampleDir, sc.Expected)
 				break
 			}
 		}
@@ -485,25 +234,37 @@ func scoreResults(exampleDir string, scenarios []struct {
 			continue
 		}
 
-		var schema Schema
-		if err := loadJSON(schemaPath, &schema); err != nil {
+		var schema datagen.Schema
+		if err := bench.LoadJSON(schemaPath, &schema); err != nil {
 			log.Printf("skip %s: %v", entry.Name(), err)
 			continue
 		}
-		var constraints Constraints
-		if err := loadJSON(constraintPath, &constraints); err != nil {
+		var constraints datagen.Constraints
+		if err := bench.LoadJSON(constraintPath, &constraints); err != nil {
 			log.Printf("skip %s: %v", entry.Name(), err)
 			continue
 		}
 
-		score := validateRecords(result.Records, schema, constraints)
-		fmt.Printf("%s: schema=%.0f%%  rules=%.0f%%  unique=%.0f%%  overall=%.0f%%\n",
+		score := datagen.ValidateRecords(result.Records, schema, constraints)
+		fmt.Printf("%s: schema=%.0f%%  rules=%.0f%%  unique=%.0f%%  overall=%.0f%%  valid_records=%d/%d\n",
 			entry.Name(), score.SchemaCompliance*100, score.RuleCompliance*100,
-			score.Uniqueness*100, score.Overall*100)
+			score.Uniqueness*100, score.Overall*100, score.ValidRecords, len(result.Records))
+	}
+}
+
+func generateReport(exampleDir string, prices *pricing.Table) {
+	benchmarks := prices.Apply(bench.WithModelInfo(exampleDir, benchmarkResults(exampleDir)))
+	report := reporting.GenerateReport(benchmarks) + reporting.GenerateComparison(benchmarks)
+	fmt.Print(report)
+
+	reportPath := filepath.Join(exampleDir, "RESULTS.md")
+	if err := os.WriteFile(reportPath, []byte("# Test Data Generation Results\n\n"+report), 0644); err != nil {
+		log.Printf("WARNING: could not write report: %v", err)
 	}
 }
 
-func generateReport(exampleDir string) {
+// benchmarkResults loads every schema result written by a run.
+func benchmarkResults(exampleDir string) []types.BenchmarkResult {
 	entries, err := os.ReadDir(filepath.Join(exampleDir, "results"))
 	if err != nil {
 		log.Fatalf("read results dir: %v", err)
@@ -516,29 +277,31 @@ func generateReport(exampleDir string) {
 		}
 
 		var result ScenarioResult
-		if err := loadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
+		if err := bench.LoadJSON(filepath.Join(exampleDir, "results", entry.Name()), &result); err != nil {
 			continue
 		}
 
+		jsonChecks, jsonRaw, jsonRepaired := result.JSON.Counts()
 		benchmarks = append(benchmarks, types.BenchmarkResult{
-			Example:      fmt.Sprintf("test-data-gen/%s", result.Schema),
-			Model:        result.Model,
-			Quality:      result.Score.Overall,
-			QualityName:  "Compliance",
-			TokensIn:     result.Meta.TokensIn,
-			TokensOut:    result.Meta.TokensOut,
-			TTFT:         result.Meta.TTFT,
-			TotalTime:    result.Meta.TotalTime,
-			TokensPerSec: result.Meta.TokensPerSec,
-			CostUSD:      0,
+			Example:           fmt.Sprintf("test-data-gen/%s", result.Schema),
+			Model:             result.Model,
+			Quality:           result.Score.Overall,
+			QualityName:       "Compliance",
+			TokensIn:          result.Meta.TokensIn,
+			TokensOut:         result.Meta.TokensOut,
+			TTFT:              result.Meta.TTFT,
+			TotalTime:         result.Meta.TotalTime,
+			TokensPerSec:      result.Meta.TokensPerSec,
+			InterTokenLatency: result.Meta.InterTokenLatency,
+			SchemaChecks:      len(result.Records),
+			SchemaValid:       result.Score.ValidRecords,
+			JSONChecks:        jsonChecks,
+			JSONRaw:           jsonRaw,
+			JSONRepaired:      jsonRepaired,
+			Retries:           result.Meta.Retries,
+			Error:             result.Error,
 		})
 	}
 
-	report := reporting.GenerateReport(benchmarks)
-	fmt.Print(report)
-
-	reportPath := filepath.Join(exampleDir, "RESULTS.md")
-	if err := os.WriteFile(reportPath, []byte("# Test Data Generation Results\n\n"+report), 0644); err != nil {
-		log.Printf("WARNING: could not write report: %v", err)
-	}
+	return benchmarks
 }
@@ -0,0 +1,114 @@
+package gatekeep
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/statherm/local-llm-examples/shared/bench"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+func init() { bench.Register(Example{}) }
+
+// Example runs prompt injection and PII detection through the bench
+// interface. Both score the binary verdict (safe, contains_pii), matching the
+// example's report.
+type Example struct{}
+
+type promptCase struct {
+	Input    PromptInput
+	Expected PromptLabel
+}
+
+type piiCase struct {
+	Input    PIIInput
+	Expected PIILabel
+}
+
+func (Example) Name() string { return "validation-gatekeeping" }
+
+func (Example) Description() string {
+	return "Flag prompt injection and PII before input reaches a downstream system"
+}
+
+func (Example) Load(dir string) ([]bench.Case, error) {
+	var prompts []PromptInput
+	var promptLabels []PromptLabel
+	var texts []PIIInput
+	var piiLabels []PIILabel
+	for path, v := range map[string]any{
+		filepath.Join(dir, "testdata", "prompts.json"): &prompts,
+		filepath.Join(dir, "expected", "prompts.json"): &promptLabels,
+		filepath.Join(dir, "testdata", "pii.json"):     &texts,
+		filepath.Join(dir, "expected", "pii.json"):     &piiLabels,
+	} {
+		if err := bench.LoadJSON(path, v); err != nil {
+			return nil, err
+		}
+	}
+
+	expectedPrompts := make(map[string]PromptLabel, len(promptLabels))
+	for _, l := range promptLabels {
+		expectedPrompts[l.ID] = l
+	}
+	expectedPII := make(map[string]PIILabel, len(piiLabels))
+	for _, l := range piiLabels {
+		expectedPII[l.ID] = l
+	}
+
+	var cases []bench.Case
+	for _, in := range prompts {
+		e, ok := expectedPrompts[in.ID]
+		if !ok {
+			return nil, fmt.Errorf("no expected label for %s", in.ID)
+		}
+		cases = append(cases, bench.Case{ID: in.ID, Scenario: "prompts", Data: promptCase{in, e}})
+	}
+	for _, in := range texts {
+		e, ok := expectedPII[in.ID]
+		if !ok {
+			return nil, fmt.Errorf("no expected label for %s", in.ID)
+		}
+		cases = append(cases, bench.Case{ID: in.ID, Scenario: "pii", Data: piiCase{in, e}})
+	}
+	return cases, nil
+}
+
+func (Example) Prompt(c bench.Case) ollama.ChatRequest {
+	switch d := c.Data.(type) {
+	case promptCase:
+		return ollama.NewChatRequest("", PromptInjectionSystem, d.Input.Text, true)
+	case piiCase:
+		return ollama.NewChatRequest("", PIIDetectionSystem, d.Input.Text, true)
+	}
+	panic(fmt.Sprintf("gatekeep: unexpected case data %T", c.Data))
+}
+
+func (Example) Parse(c bench.Case, raw string) (any, error) {
+	if _, ok := c.Data.(promptCase); ok {
+		return ParsePromptLabel(raw)
+	}
+	return ParsePIILabel(raw)
+}
+
+func (Example) Score(c bench.Case, parsed any) bench.Score {
+	var ok bool
+	switch d := c.Data.(type) {
+	case promptCase:
+		ok = parsed.(PromptLabel).Safe == d.Expected.Safe
+	case piiCase:
+		ok = parsed.(PIILabel).ContainsPII == d.Expected.ContainsPII
+	}
+	if ok {
+		return bench.Score{Quality: 1, Metric: "Accuracy"}
+	}
+	return bench.Score{Metric: "Accuracy"}
+}
+
+func (Example) Schema(c bench.Case) *schema.Schema {
+	if _, ok := c.Data.(promptCase); ok {
+		return PromptLabelSchema
+	}
+	return PIILabelSchema
+}
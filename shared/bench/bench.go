@@ -0,0 +1,87 @@
+// Package bench defines the interface every example implements so one CLI
+// (cmd/llmbench) can run, score and report on all of them.
+//
+// An example loads its cases from its directory under examples/, builds the
+// model request for each case, parses the raw model output and scores it.
+// The engine in this package owns everything in between: sending requests
+// concurrently, saving raw outputs, and turning scores into
+// types.BenchmarkResult rows for the shared reports. Examples register
+// themselves from an init function, so importing an example's package makes
+// it available to the CLI.
+package bench
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/schema"
+)
+
+// Case is one input an example sends to a model.
+type Case struct {
+	ID       string
+	Scenario string
+	// Data carries the example's own input and expected output; only the
+	// example that loaded the case interprets it.
+	Data any
+}
+
+// Score is the quality of one parsed output, from 0 to 1.
+type Score struct {
+	Quality float64
+	Metric  string // name of the quality metric, e.g. "Tool Acc"
+	// SchemaChecks and SchemaValid count outputs validated against a JSON
+	// Schema by the example itself (e.g. one per generated record).
+	SchemaChecks int
+	SchemaValid  int
+}
+
+// Example is a benchmarkable task.
+type Example interface {
+	// Name is the example's directory name under examples/.
+	Name() string
+	Description() string
+	// Load reads the example's cases from its directory.
+	Load(dir string) ([]Case, error)
+	// Prompt builds the request for a case. The model is filled in by the
+	// caller.
+	Prompt(c Case) ollama.ChatRequest
+	// Parse decodes a raw model response.
+	Parse(c Case, raw string) (any, error)
+	// Score rates a parsed response against the case's expected output.
+	Score(c Case, parsed any) Score
+}
+
+// Validator is implemented by examples whose output has a JSON Schema
+// contract. The raw response of every case is validated against it.
+type Validator interface {
+	Schema(c Case) *schema.Schema
+}
+
+var registry = map[string]Example{}
+
+// Register makes an example available by name. It panics if the name is
+// already registered.
+func Register(ex Example) {
+	if _, dup := registry[ex.Name()]; dup {
+		panic(fmt.Sprintf("bench: example %q registered twice", ex.Name()))
+	}
+	registry[ex.Name()] = ex
+}
+
+// Lookup returns the registered example with the given name.
+func Lookup(name string) (Example, bool) {
+	ex, ok := registry[name]
+	return ex, ok
+}
+
+// All returns every registered example sorted by name.
+func All() []Example {
+	out := make([]Example, 0, len(registry))
+	for _, ex := range registry {
+		out = append(out, ex)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
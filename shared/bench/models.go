@@ -0,0 +1,54 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// ModelInfoPath returns where the preflight details of model are saved for
+// the example in dir.
+func ModelInfoPath(dir, model string) string {
+	return filepath.Join(dir, "results", "models", SanitizeModelName(model)+".json")
+}
+
+// Preflight checks that model is available with provider.Preflight, pulling
+// it first if pull is set, and saves what the provider reports about it to
+// ModelInfoPath in each of dirs so reports can show it.
+func Preflight(ctx context.Context, client provider.Provider, model string, pull bool, dirs ...string) error {
+	info, err := provider.Preflight(ctx, client, model, pull, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if info == (types.ModelInfo{Name: model}) {
+		return nil // nothing reported beyond the name
+	}
+	fmt.Printf("Model %s: %s\n", model, info.Summary())
+	for _, dir := range dirs {
+		if err := WriteJSON(ModelInfoPath(dir, model), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithModelInfo attaches the details saved by Preflight in dir to every
+// result whose model has them. Variant labels (see types.ModelLabel) are
+// ignored.
+func WithModelInfo(dir string, results []types.BenchmarkResult) []types.BenchmarkResult {
+	for i := range results {
+		path := ModelInfoPath(dir, types.BaseModel(results[i].Model))
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		var info types.ModelInfo
+		if err := LoadJSON(path, &info); err == nil {
+			results[i].ModelInfo = &info
+		}
+	}
+	return results
+}
@@ -0,0 +1,214 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/repair"
+	"github.com/statherm/local-llm-examples/shared/runner"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Output is the saved model response for one case.
+type Output struct {
+	ID       string              `json:"id"`
+	Scenario string              `json:"scenario"`
+	Raw      string              `json:"raw"`
+	Error    string              `json:"error,omitempty"`
+	Meta     types.ModelMetadata `json:"metadata"`
+	// JSON records whether a JSON-mode response needed repair, and Reasks
+	// how many follow-up turns it took. Both are empty for text output.
+	JSON   repair.Outcome `json:"json,omitempty"`
+	Reasks int            `json:"reasks,omitempty"`
+}
+
+// RunFile holds every output of one example run against one model.
+type RunFile struct {
+	Example string   `json:"example"`
+	Model   string   `json:"model"`
+	Outputs []Output `json:"outputs"`
+}
+
+// ResultsDir is where run files for the example in dir are kept, apart from
+// the files the example's own main writes.
+func ResultsDir(dir string) string {
+	return filepath.Join(dir, "results", "llmbench")
+}
+
+// RunPath returns the run file for model.
+func RunPath(dir, model string) string {
+	return filepath.Join(ResultsDir(dir), SanitizeModelName(model)+".json")
+}
+
+// Run sends every case of ex to model with up to parallel requests in
+// flight and saves the outputs to RunPath. Failed calls are recorded in the
+// output rather than aborting the run. JSON-mode responses that cannot be
+// recovered by shared/repair are re-asked up to reasks times.
+func Run(ctx context.Context, client provider.Provider, ex Example, dir, model string, parallel, reasks int) (RunFile, runner.Summary, error) {
+	cases, err := ex.Load(dir)
+	if err != nil {
+		return RunFile{}, runner.Summary{}, fmt.Errorf("%s: load cases: %w", ex.Name(), err)
+	}
+
+	results, summary := runner.Run(ctx, cases, parallel, func(ctx context.Context, _ int, c Case) (Output, error) {
+		req := ex.Prompt(c)
+		req.Model = model
+		out := Output{ID: c.ID, Scenario: c.Scenario}
+		if len(req.Format) == 0 {
+			resp, err := client.Complete(ctx, req)
+			if err != nil {
+				// Keep the retry count so a flaky run shows up in the report.
+				out.Error, out.Meta = err.Error(), resp.Meta
+				return out, err
+			}
+			out.Raw, out.Meta = resp.Message.Content, resp.Meta
+			return out, nil
+		}
+
+		reply, err := repair.Complete(ctx, client, req, reasks)
+		out.Meta, out.Reasks = reply.Meta, reply.Reasks
+		if err != nil {
+			out.Error = err.Error()
+			return out, err
+		}
+		out.Raw, out.JSON = reply.Raw, reply.Outcome
+		return out, nil
+	})
+
+	run := RunFile{Example: ex.Name(), Model: model, Outputs: make([]Output, len(results))}
+	for i, r := range results {
+		run.Outputs[i] = r.Value
+		if r.Err != nil && run.Outputs[i].Error == "" {
+			run.Outputs[i] = Output{ID: cases[i].ID, Scenario: cases[i].Scenario, Error: r.Err.Error()}
+		}
+		run.Outputs[i].Meta.QueueTime = r.Queue
+	}
+
+	if err := WriteJSON(RunPath(dir, model), run); err != nil {
+		return run, summary, err
+	}
+	return run, summary, nil
+}
+
+// LoadRuns reads every saved run for the example in dir.
+func LoadRuns(dir string) ([]RunFile, error) {
+	entries, err := os.ReadDir(ResultsDir(dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []RunFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		var run RunFile
+		if err := LoadJSON(filepath.Join(ResultsDir(dir), e.Name()), &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// CaseResult is one scored output.
+type CaseResult struct {
+	Output
+	Score
+	// Failed explains why the output scored zero without being compared:
+	// the call failed, the case no longer exists, or parsing failed.
+	Failed string
+}
+
+// ScoreRun parses and scores every output in run. Outputs are matched to
+// cases by scenario and ID, so a run stays scorable after cases are
+// reordered.
+func ScoreRun(ex Example, cases []Case, run RunFile) []CaseResult {
+	byKey := make(map[string]Case, len(cases))
+	for _, c := range cases {
+		byKey[c.Scenario+"/"+c.ID] = c
+	}
+	validator, _ := ex.(Validator)
+
+	results := make([]CaseResult, len(run.Outputs))
+	for i, out := range run.Outputs {
+		r := CaseResult{Output: out}
+		c, ok := byKey[out.Scenario+"/"+out.ID]
+		switch {
+		case out.Error != "":
+			r.Failed = "call failed: " + out.Error
+		case !ok:
+			r.Failed = "unknown case"
+		default:
+			if validator != nil {
+				if s := validator.Schema(c); s != nil {
+					r.SchemaChecks++
+					if len(s.ValidateJSON([]byte(out.Raw))) == 0 {
+						r.SchemaValid++
+					}
+				}
+			}
+			parsed, err := ex.Parse(c, out.Raw)
+			if err != nil {
+				r.Failed = "parse: " + err.Error()
+				break
+			}
+			score := ex.Score(c, parsed)
+			score.SchemaChecks += r.SchemaChecks
+			score.SchemaValid += r.SchemaValid
+			r.Score = score
+		}
+		results[i] = r
+	}
+	return results
+}
+
+// Summarize turns scored outputs into one BenchmarkResult per scenario,
+// averaging quality and performance over the scenario's cases. Failed cases
+// count as zero quality.
+func Summarize(ex Example, model string, results []CaseResult) []types.BenchmarkResult {
+	var order []string
+	byScenario := make(map[string][]CaseResult)
+	for _, r := range results {
+		if _, ok := byScenario[r.Scenario]; !ok {
+			order = append(order, r.Scenario)
+		}
+		byScenario[r.Scenario] = append(byScenario[r.Scenario], r)
+	}
+
+	var out []types.BenchmarkResult
+	for _, sc := range order {
+		rs := byScenario[sc]
+		row := types.BenchmarkResult{Example: ex.Name() + "/" + sc, Model: model}
+		metas := make([]types.ModelMetadata, 0, len(rs))
+		for _, r := range rs {
+			row.Quality += r.Quality
+			row.SchemaChecks += r.SchemaChecks
+			row.SchemaValid += r.SchemaValid
+			checks, raw, repaired := r.JSON.Counts()
+			row.JSONChecks += checks
+			row.JSONRaw += raw
+			row.JSONRepaired += repaired
+			if row.QualityName == "" {
+				row.QualityName = r.Metric
+			}
+			metas = append(metas, r.Meta)
+		}
+		row.Quality /= float64(len(rs))
+
+		m := types.MeanMetadata(metas)
+		row.TokensIn, row.TokensOut = m.TokensIn, m.TokensOut
+		row.TTFT, row.TotalTime = m.TTFT, m.TotalTime
+		row.TokensPerSec, row.InterTokenLatency = m.TokensPerSec, m.InterTokenLatency
+		row.Retries = m.Retries
+		out = append(out, row)
+	}
+	return out
+}
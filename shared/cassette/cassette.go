@@ -0,0 +1,253 @@
+// Package cassette records model server traffic and replays it, so examples
+// can be re-scored and re-reported without a model server and regression
+// tests for scorers can run offline.
+//
+// A Transport wraps a client's http.Client transport. In Record mode every
+// request is passed through and the response is saved to a directory of
+// cassette files; in Replay mode responses are served from those files and
+// nothing reaches the network. Interactions are keyed by a hash of the
+// request method, path and body, so a replay matches regardless of the
+// server address. Identical requests (repeated runs at a fixed seed) are
+// numbered in the order they were made and replayed in the same order.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects what a Transport does with a request.
+type Mode string
+
+const (
+	// Record sends requests to the server and saves the responses.
+	Record Mode = "record"
+	// Replay answers requests from saved responses only.
+	Replay Mode = "replay"
+)
+
+// Interaction is one saved request/response pair, stored as
+// <key>-<n>.json in the cassette directory.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the part of an http.Request a cassette keeps.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is the part of an http.Response a cassette keeps. Streamed
+// responses are saved once the client has read them and replayed as a
+// single body, so timings measured on a replay are meaningless.
+type Response struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Transport is an http.RoundTripper that records or replays interactions
+// in Dir. It is safe for concurrent use.
+type Transport struct {
+	Dir  string
+	Mode Mode
+	// Next sends requests in Record mode. Nil means
+	// http.DefaultTransport.
+	Next http.RoundTripper
+
+	mu   sync.Mutex
+	seen map[string]int // requests per key so far
+}
+
+// New returns a Transport for dir. In Record mode dir is created if needed.
+func New(dir string, mode Mode) (*Transport, error) {
+	switch mode {
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create cassette dir: %w", err)
+		}
+	case Replay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("open cassette dir: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	return &Transport{Dir: dir, Mode: mode, seen: make(map[string]int)}, nil
+}
+
+// Key returns the hash a request is stored under.
+func Key(method, path string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	key := Key(req.Method, req.URL.Path, body)
+	n := t.next(key)
+
+	if t.Mode == Replay {
+		return t.replay(req, key, n)
+	}
+	return t.record(req, key, n, body)
+}
+
+// next returns the sequence number of this request among those with the
+// same key.
+func (t *Transport) next(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seen == nil {
+		t.seen = make(map[string]int)
+	}
+	t.seen[key]++
+	return t.seen[key]
+}
+
+func (t *Transport) path(key string, n int) string {
+	return filepath.Join(t.Dir, fmt.Sprintf("%s-%d.json", key, n))
+}
+
+// replay serves the n-th recording for key, or the last one if the
+// cassette holds fewer. A request that was never recorded gets a 404 so
+// clients fail fast instead of retrying.
+func (t *Transport) replay(req *http.Request, key string, n int) (*http.Response, error) {
+	var data []byte
+	var err error
+	for ; n >= 1; n-- {
+		data, err = os.ReadFile(t.path(key, n))
+		if err == nil || !os.IsNotExist(err) {
+			break
+		}
+	}
+	if n < 1 {
+		msg := fmt.Sprintf("cassette: no recorded response for %s %s (key %s) in %s", req.Method, req.URL.Path, key, t.Dir)
+		return response(req, Response{StatusCode: http.StatusNotFound, ContentType: "text/plain", Body: msg}), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+
+	var it Interaction
+	if err := json.Unmarshal(data, &it); err != nil {
+		return nil, fmt.Errorf("cassette: parse %s: %w", t.path(key, n), err)
+	}
+	return response(req, it.Response), nil
+}
+
+// record sends req and arranges for the response to be saved under key.
+// The body is handed to the client as it arrives, so streamed timings are
+// measured as without a cassette, and saved when the client reaches its end
+// or closes it. Error responses are saved too, so a replay fails the same
+// way the recording did.
+func (t *Transport) record(req *http.Request, key string, n int, body []byte) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &recorder{
+		body: resp.Body,
+		path: t.path(key, n),
+		it: Interaction{
+			Request: Request{Method: req.Method, Path: req.URL.Path, Body: string(body)},
+			Response: Response{
+				StatusCode:  resp.StatusCode,
+				ContentType: resp.Header.Get("Content-Type"),
+			},
+		},
+	}
+	return resp, nil
+}
+
+// recorder tees a response body and writes the interaction to path once
+// the body is exhausted or closed. A body closed early is saved as far as
+// the client read it, which is all the client saw.
+type recorder struct {
+	body  io.ReadCloser
+	buf   bytes.Buffer
+	path  string
+	it    Interaction
+	saved bool
+}
+
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.buf.Write(p[:n])
+	if err == io.EOF {
+		if serr := r.save(); serr != nil {
+			return n, serr
+		}
+	}
+	return n, err
+}
+
+func (r *recorder) Close() error {
+	err := r.body.Close()
+	if serr := r.save(); serr != nil {
+		return serr
+	}
+	return err
+}
+
+func (r *recorder) save() error {
+	if r.saved {
+		return nil
+	}
+	r.saved = true
+	r.it.Response.Body = r.buf.String()
+	data, err := json.MarshalIndent(r.it, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: marshal: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+func response(req *http.Request, r Response) *http.Response {
+	header := make(http.Header)
+	if r.ContentType != "" {
+		header.Set("Content-Type", r.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
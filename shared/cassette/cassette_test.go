@@ -0,0 +1,162 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/ollama/ollamatest"
+)
+
+// client returns an ollama client for srv whose requests go through t.
+func client(srv *ollamatest.Server, t *Transport) *ollama.Client {
+	c := srv.Client()
+	t.Next = c.HTTPClient.Transport
+	c.HTTPClient = &http.Client{Transport: t}
+	return c
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Content: `{"category": "bug", "priority": "high"}`, OutputTokens: 7}
+
+	dir := t.TempDir()
+	rec, err := New(dir, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := ollama.NewChatRequest("qwen3:4b", "system", "prompt", true)
+	want, err := client(srv, rec).Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay needs no server: point the client somewhere unreachable.
+	rep, err := New(dir, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := ollama.NewClient()
+	c.BaseURL = "http://127.0.0.1:1"
+	c.HTTPClient.Transport = rep
+	got, err := c.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Message.Content != want.Message.Content || got.Meta.TokensOut != 7 {
+		t.Errorf("replayed %q with %d tokens, want %q with 7", got.Message.Content, got.Meta.TokensOut, want.Message.Content)
+	}
+}
+
+// TestRecordStreams checks that recording does not hold back a streamed
+// body: the first token must arrive long before the last.
+func TestRecordStreams(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	const gap = 40 * time.Millisecond
+	srv.Default = ollamatest.Reply{Chunks: []string{"a", "b", "c", "d", "e"}, ChunkDelay: gap}
+
+	dir := t.TempDir()
+	rec, err := New(dir, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client(srv, rec)
+	c.Stream = true
+	resp, err := c.Complete(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "abcde" {
+		t.Errorf("content = %q, want abcde", resp.Message.Content)
+	}
+	if resp.Meta.TTFT >= gap {
+		t.Errorf("TTFT = %v, want under the %v chunk gap", resp.Meta.TTFT, gap)
+	}
+	if resp.Meta.InterTokenLatency < gap/2 {
+		t.Errorf("InterTokenLatency = %v, want about %v", resp.Meta.InterTokenLatency, gap)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d files, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), `\"done\":true`); n != 1 {
+		t.Errorf("recording has %d done messages, want the whole stream with 1", n)
+	}
+}
+
+// TestRecordErrors checks that error responses are saved and replayed in
+// order, so a replay retries exactly as the recording did.
+func TestRecordErrors(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(ollamatest.Reply{Status: http.StatusServiceUnavailable, Error: "loading"})
+	srv.Default = ollamatest.Reply{Content: "ok"}
+
+	dir := t.TempDir()
+	rec, err := New(dir, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := ollama.NewChatRequest("qwen3:4b", "", "go", false)
+	if _, err := client(srv, rec).Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := New(dir, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := srv.Client()
+	c.HTTPClient = &http.Client{Transport: rep}
+	resp, err := c.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "ok" || resp.Meta.Retries != 1 {
+		t.Errorf("replayed %q after %d retries, want ok after 1", resp.Message.Content, resp.Meta.Retries)
+	}
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	rep, err := New(filepath.Join("testdata", "chat"), Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := ollama.NewClient()
+	c.HTTPClient.Transport = rep
+
+	// The recorded request, then the same request with one byte changed.
+	if _, err := c.Complete(context.Background(), ollama.NewChatRequest("qwen3:4b", "Reply in one word.", "Ping?", false)); err != nil {
+		t.Fatalf("recorded request: %v", err)
+	}
+	resp, err := c.Complete(context.Background(), ollama.NewChatRequest("qwen3:4b", "Reply in one word.", "Ping!", false))
+	var se *ollama.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Fatalf("unrecorded request: err = %v, want a 404", err)
+	}
+	if !strings.Contains(se.Body, "no recorded response") {
+		t.Errorf("error body = %q, want it to name the missing recording", se.Body)
+	}
+	if resp.Meta.Retries != 0 {
+		t.Errorf("Retries = %d, want a missing recording to fail without retrying", resp.Meta.Retries)
+	}
+}
+
+func TestNewReplayMissingDir(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing"), Replay); err == nil {
+		t.Error("New(missing dir, Replay) succeeded, want error")
+	}
+}
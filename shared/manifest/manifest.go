@@ -0,0 +1,96 @@
+// Package manifest loads an example's scenarios from a JSON file in the
+// example directory, so new fixtures can be added without editing Go.
+//
+// A manifest is a JSON array of scenarios:
+//
+//	[
+//	  {
+//	    "name": "log-summary-crash",
+//	    "category": "log",
+//	    "input": "testdata/logs/003-crash-loop.log",
+//	    "expected": "expected/log-003.txt",
+//	    "prompt": "prompts/log-summary.txt",
+//	    "scorer": "keyword-recall"
+//	  }
+//	]
+//
+// Paths are relative to the example directory. Each example documents what
+// input and expected point at (a file, or a directory of files) and which
+// scorers it offers.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the manifest's name inside an example directory.
+const FileName = "scenarios.json"
+
+// Scenario is one manifest entry.
+type Scenario struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+	Input    string `json:"input"`
+	Expected string `json:"expected"`
+	Prompt   string `json:"prompt,omitempty"`
+	// Schema is a JSON Schema file describing the output, for examples
+	// that validate against one.
+	Schema   string `json:"schema,omitempty"`
+	JSONMode bool   `json:"json_mode,omitempty"`
+	// MaxTokens caps the response length; 0 uses the example's default.
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Scorer names the scoring function. Empty selects the example's
+	// default.
+	Scorer string `json:"scorer,omitempty"`
+}
+
+// Load reads the manifest in dir. scorers lists the scorer names the
+// example implements; the first is the default given to scenarios that name
+// none. Load rejects unknown scorers, duplicate names and entries without a
+// name or input so mistakes surface before any model is called.
+func Load(dir string, scorers ...string) ([]Scenario, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var scenarios []Scenario
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(scenarios) == 0 {
+		return nil, fmt.Errorf("%s: no scenarios", path)
+	}
+
+	known := make(map[string]bool, len(scorers))
+	for _, s := range scorers {
+		known[s] = true
+	}
+	seen := make(map[string]bool, len(scenarios))
+	for i := range scenarios {
+		s := &scenarios[i]
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("%s: scenario %d has no name", path, i+1)
+		case seen[s.Name]:
+			return nil, fmt.Errorf("%s: duplicate scenario %q", path, s.Name)
+		case s.Input == "":
+			return nil, fmt.Errorf("%s: scenario %q has no input", path, s.Name)
+		}
+		seen[s.Name] = true
+
+		if s.Scorer == "" && len(scorers) > 0 {
+			s.Scorer = scorers[0]
+		}
+		if len(scorers) > 0 && !known[s.Scorer] {
+			return nil, fmt.Errorf("%s: scenario %q: unknown scorer %q (want one of %s)",
+				path, s.Name, s.Scorer, strings.Join(scorers, ", "))
+		}
+	}
+	return scenarios, nil
+}
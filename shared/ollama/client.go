@@ -1,11 +1,14 @@
 package ollama
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/statherm/local-llm-examples/shared/types"
@@ -13,10 +16,27 @@ import (
 
 const defaultBaseURL = "http://localhost:11434"
 
+// defaultJSONMaxTokens caps output in JSON mode when the caller sets no limit.
+// Many small models (qwen2.5:3b, phi3:mini, mistral:7b) generate thousands of
+// tokens of repeated JSON or chain-of-thought in JSON mode without a cap.
+// 1024 tokens handles complex structured extraction (invoices with line
+// items, etc.) while still preventing runaway generation.
+const defaultJSONMaxTokens = 1024
+
+// JSONFormat asks Ollama for free-form JSON output. A JSON Schema document
+// may be used as a ChatRequest.Format instead to constrain the structure.
+var JSONFormat = json.RawMessage(`"json"`)
+
 // Client communicates with a local Ollama instance via its REST API.
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+	// Stream makes ChatCompletion consume the response as a token stream so
+	// TTFT and inter-token latency are measured on the wire.
+	Stream bool
+	// Retry controls how Complete and ChatCompletion retry transient
+	// failures. Chat and ChatStream always make a single attempt.
+	Retry RetryPolicy
 }
 
 // NewClient returns a Client pointing at the default Ollama address.
@@ -26,114 +46,305 @@ func NewClient() *Client {
 		HTTPClient: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
+		Retry: DefaultRetryPolicy(),
 	}
 }
 
-// chatRequest is the JSON body sent to /api/chat.
-type chatRequest struct {
-	Model    string            `json:"model"`
-	Messages []chatMessage     `json:"messages"`
-	Stream   bool              `json:"stream"`
-	Format   json.RawMessage   `json:"format,omitempty"`
-	Options  map[string]any    `json:"options,omitempty"`
+// Message is a single turn in a chat conversation.
+type Message struct {
+	Role    string `json:"role"` // system, user, assistant, or tool
+	Content string `json:"content"`
+	// ToolCalls are the calls an assistant message made through the native
+	// tool API (see ChatRequest.Tools).
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
-type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// Options are the model runtime and sampling parameters accepted by Ollama.
+// Zero values and nil pointers are omitted so the model's defaults apply;
+// pointers are used where zero is a meaningful setting (temperature 0, seed 0).
+type Options struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          int      `json:"top_k,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	NumPredict    int      `json:"num_predict,omitempty"` // max output tokens
+	NumCtx        int      `json:"num_ctx,omitempty"`     // context window size
+	Stop          []string `json:"stop,omitempty"`
 }
 
-// chatResponse is the JSON body returned by /api/chat (non-streaming).
-type chatResponse struct {
-	Model           string      `json:"model"`
-	Message         chatMessage `json:"message"`
-	TotalDuration   int64       `json:"total_duration"`   // nanoseconds
-	LoadDuration    int64       `json:"load_duration"`    // nanoseconds
-	PromptEvalCount int         `json:"prompt_eval_count"`
-	EvalCount       int         `json:"eval_count"`
-	EvalDuration    int64       `json:"eval_duration"` // nanoseconds
+// ChatRequest describes a single call to /api/chat.
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	// Format is either JSONFormat or a JSON Schema document. Empty means
+	// unconstrained text.
+	Format json.RawMessage `json:"format,omitempty"`
+	// Tools offers functions the model may call natively. Leave Format
+	// empty with tools: JSON mode makes most templates answer in text.
+	Tools   []Tool  `json:"tools,omitempty"`
+	Options Options `json:"options,omitzero"`
+	// KeepAlive controls how long the model stays loaded after the request,
+	// as a Go-style duration ("5m", "1h") or "0" to unload immediately.
+	KeepAlive string `json:"keep_alive,omitempty"`
 }
 
-// ChatCompletion sends a chat request to Ollama and returns the response text
-// along with performance metadata. If jsonMode is true, the model is asked to
-// return valid JSON with a default output cap of 1024 tokens.
-// An optional maxTokens parameter overrides the default cap (e.g. for large
-// generation tasks that need more output room).
-func (c *Client) ChatCompletion(model, system, prompt string, jsonMode bool, maxTokens ...int) (string, types.ModelMetadata, error) {
-	msgs := []chatMessage{}
-	if system != "" {
-		msgs = append(msgs, chatMessage{Role: "system", Content: system})
-	}
-	msgs = append(msgs, chatMessage{Role: "user", Content: prompt})
+// ChatResponse is the result of a completed chat request.
+type ChatResponse struct {
+	Message    Message             `json:"message"`
+	DoneReason string              `json:"done_reason,omitempty"` // stop, length, ...
+	Meta       types.ModelMetadata `json:"metadata"`
+}
 
-	req := chatRequest{
-		Model:    model,
-		Messages: msgs,
-		Stream:   false,
+// Float returns a pointer to v, for use with optional Options fields.
+func Float(v float64) *float64 { return &v }
+
+// Int returns a pointer to v, for use with optional Options fields.
+func Int(v int) *int { return &v }
+
+// wireRequest is the JSON body sent to /api/chat.
+type wireRequest struct {
+	ChatRequest
+	Stream bool `json:"stream"`
+}
+
+// wireResponse is the JSON body returned by /api/chat. When streaming, each
+// NDJSON line is a wireResponse carrying a content fragment; the final line
+// has Done set and carries the timing statistics.
+type wireResponse struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason"`
+	Error           string  `json:"error"`
+	TotalDuration   int64   `json:"total_duration"` // nanoseconds
+	LoadDuration    int64   `json:"load_duration"`  // nanoseconds
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	EvalDuration    int64   `json:"eval_duration"` // nanoseconds
+}
+
+// metadata converts the server-side statistics into ModelMetadata. TTFT is
+// estimated as everything except generation (model load plus prompt eval);
+// streaming callers overwrite it with the measured wall-clock value.
+func (r wireResponse) metadata(totalTime time.Duration) types.ModelMetadata {
+	var tokPerSec float64
+	if r.EvalDuration > 0 {
+		tokPerSec = float64(r.EvalCount) / (float64(r.EvalDuration) / 1e9)
 	}
-	if jsonMode {
-		req.Format = json.RawMessage(`"json"`)
-		// Cap output tokens to prevent repetition loops. Many small models
-		// (qwen2.5:3b, phi3:mini, mistral:7b) generate thousands of tokens
-		// of repeated JSON or chain-of-thought in JSON mode without this.
-		// 1024 tokens handles complex structured extraction (invoices with
-		// line items, etc.) while still preventing runaway generation.
-		cap := 1024
-		if len(maxTokens) > 0 && maxTokens[0] > 0 {
-			cap = maxTokens[0]
-		}
-		req.Options = map[string]any{"num_predict": cap}
+	return types.ModelMetadata{
+		Model:        r.Model,
+		TokensIn:     r.PromptEvalCount,
+		TokensOut:    r.EvalCount,
+		TTFT:         time.Duration(r.TotalDuration-r.EvalDuration) * time.Nanosecond,
+		TotalTime:    totalTime,
+		TokensPerSec: tokPerSec,
+		LoadDuration: time.Duration(r.LoadDuration) * time.Nanosecond,
 	}
+}
+
+// Chat sends a chat request to Ollama and returns the assistant message along
+// with performance metadata. The request is bound to ctx, so callers can
+// cancel a hung call or apply a per-request deadline.
+//
+// Chat waits for the complete response, so Meta.TTFT is the server-side
+// estimate; use ChatStream for the latency a user actually waits for.
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+	start := time.Now()
 
-	body, err := json.Marshal(req)
+	resp, err := c.post(ctx, req, false)
 	if err != nil {
-		return "", types.ModelMetadata{}, fmt.Errorf("marshal request: %w", err)
+		return ChatResponse{}, err
 	}
+	defer resp.Body.Close()
 
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChatResponse{}, fmt.Errorf("read response: %w", err)
+	}
+
+	var chatResp wireResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return ChatResponse{}, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return ChatResponse{
+		Message:    chatResp.Message,
+		DoneReason: chatResp.DoneReason,
+		Meta:       chatResp.metadata(time.Since(start)),
+	}, nil
+}
+
+// ChatStream sends a chat request with streaming enabled and calls fn with
+// each content fragment as it arrives. fn may be nil when only the timing is
+// wanted; returning an error from fn aborts the stream.
+//
+// The returned metadata measures TTFT as wall-clock time from sending the
+// request to receiving the first non-empty fragment, and InterTokenLatency
+// as the mean gap between subsequent fragments.
+func (c *Client) ChatStream(ctx context.Context, req ChatRequest, fn func(token string) error) (ChatResponse, error) {
 	start := time.Now()
 
-	httpReq, err := http.NewRequest("POST", c.BaseURL+"/api/chat", bytes.NewReader(body))
+	resp, err := c.post(ctx, req, true)
 	if err != nil {
-		return "", types.ModelMetadata{}, fmt.Errorf("create request: %w", err)
+		return ChatResponse{}, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
+	defer resp.Body.Close()
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	var (
+		content     strings.Builder
+		toolCalls   []ToolCall
+		final       wireResponse
+		role        string
+		first, last time.Time
+		fragments   int
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk wireResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return ChatResponse{}, fmt.Errorf("unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return ChatResponse{}, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+		if chunk.Message.Role != "" {
+			role = chunk.Message.Role
+		}
+		// Ollama sends tool calls whole, in a chunk of their own.
+		toolCalls = append(toolCalls, chunk.Message.ToolCalls...)
+
+		if tok := chunk.Message.Content; tok != "" {
+			now := time.Now()
+			if fragments == 0 {
+				first = now
+			}
+			last = now
+			fragments++
+			content.WriteString(tok)
+			if fn != nil {
+				if err := fn(tok); err != nil {
+					return ChatResponse{}, err
+				}
+			}
+		}
+
+		if chunk.Done {
+			final = chunk
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ChatResponse{}, fmt.Errorf("read stream: %w", err)
+	}
+	if !final.Done {
+		return ChatResponse{}, fmt.Errorf("stream ended before done message: %w", io.ErrUnexpectedEOF)
+	}
+
+	meta := final.metadata(time.Since(start))
+	meta.Streamed = true
+	if fragments > 0 {
+		meta.TTFT = first.Sub(start)
+	}
+	if fragments > 1 {
+		meta.InterTokenLatency = last.Sub(first) / time.Duration(fragments-1)
+	}
+
+	return ChatResponse{
+		Message:    Message{Role: role, Content: content.String(), ToolCalls: toolCalls},
+		DoneReason: final.DoneReason,
+		Meta:       meta,
+	}, nil
+}
+
+// post sends req to /api/chat and returns the response once a 200 status has
+// been received. The caller must close the response body. Any other status is
+// returned as a *StatusError.
+func (c *Client) post(ctx context.Context, req ChatRequest, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(wireRequest{ChatRequest: req, Stream: stream})
 	if err != nil {
-		return "", types.ModelMetadata{}, fmt.Errorf("ollama request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/chat", bytes.NewReader(body))
 	if err != nil {
-		return "", types.ModelMetadata{}, fmt.Errorf("read response: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	if resp.StatusCode != http.StatusOK {
-		return "", types.ModelMetadata{}, fmt.Errorf("ollama returned %d: %s", resp.StatusCode, string(respBody))
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request: %w", err)
 	}
 
-	var chatResp chatResponse
-	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return "", types.ModelMetadata{}, fmt.Errorf("unmarshal response: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
+	return resp, nil
+}
 
-	totalTime := time.Since(start)
-	ttft := time.Duration(chatResp.TotalDuration-chatResp.EvalDuration) * time.Nanosecond
+// Complete sends req via ChatStream when c.Stream is set and via Chat
+// otherwise, discarding the individual stream fragments. Transient failures
+// are retried according to c.Retry, and the number of retries a successful
+// call needed is recorded in Meta.Retries.
+func (c *Client) Complete(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+	return c.Retry.Do(ctx, func(ctx context.Context) (ChatResponse, error) {
+		if c.Stream {
+			return c.ChatStream(ctx, req, nil)
+		}
+		return c.Chat(ctx, req)
+	})
+}
 
-	var tokPerSec float64
-	if chatResp.EvalDuration > 0 {
-		tokPerSec = float64(chatResp.EvalCount) / (float64(chatResp.EvalDuration) / 1e9)
+// ChatCompletion sends a single-turn chat request to Ollama and returns the
+// response text along with performance metadata. If jsonMode is true, the
+// model is asked to return valid JSON with a default output cap of 1024 tokens.
+// An optional maxTokens parameter overrides the default cap (e.g. for large
+// generation tasks that need more output room). On error the metadata carries
+// only the number of retries attempted.
+//
+// ChatCompletion is a thin wrapper around Complete; use Chat or ChatStream
+// directly for multi-turn conversations, sampling options, or cancellation.
+func (c *Client) ChatCompletion(model, system, prompt string, jsonMode bool, maxTokens ...int) (string, types.ModelMetadata, error) {
+	resp, err := c.Complete(context.Background(), NewChatRequest(model, system, prompt, jsonMode, maxTokens...))
+	if err != nil {
+		return "", resp.Meta, err
 	}
+	return resp.Message.Content, resp.Meta, nil
+}
 
-	meta := types.ModelMetadata{
-		Model:        chatResp.Model,
-		TokensIn:     chatResp.PromptEvalCount,
-		TokensOut:    chatResp.EvalCount,
-		TTFT:         ttft,
-		TotalTime:    totalTime,
-		TokensPerSec: tokPerSec,
+// NewChatRequest builds the single-turn request ChatCompletion sends, for
+// callers that need a context or want to adjust options before sending.
+func NewChatRequest(model, system, prompt string, jsonMode bool, maxTokens ...int) ChatRequest {
+	req := ChatRequest{
+		Model:    model,
+		Messages: NewMessages(system, prompt),
+	}
+	if jsonMode {
+		req.Format = JSONFormat
+		req.Options.NumPredict = defaultJSONMaxTokens
+		if len(maxTokens) > 0 && maxTokens[0] > 0 {
+			req.Options.NumPredict = maxTokens[0]
+		}
 	}
+	return req
+}
 
-	return chatResp.Message.Content, meta, nil
+// NewMessages builds the message list for a single-turn request: an optional
+// system message followed by the user prompt.
+func NewMessages(system, prompt string) []Message {
+	msgs := []Message{}
+	if system != "" {
+		msgs = append(msgs, Message{Role: "system", Content: system})
+	}
+	return append(msgs, Message{Role: "user", Content: prompt})
 }
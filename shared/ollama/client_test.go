@@ -0,0 +1,199 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/ollama/ollamatest"
+)
+
+func TestChatCompletionMetadata(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{
+		Content:        `{"label": "bug"}`,
+		PromptTokens:   42,
+		OutputTokens:   20,
+		LoadDuration:   10 * time.Millisecond,
+		PromptDuration: 30 * time.Millisecond,
+		EvalDuration:   500 * time.Millisecond,
+	}
+
+	text, meta, err := srv.Client().ChatCompletion("qwen3:4b", "system", "prompt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != `{"label": "bug"}` {
+		t.Errorf("text = %q", text)
+	}
+	if meta.Model != "qwen3:4b" || meta.TokensIn != 42 || meta.TokensOut != 20 {
+		t.Errorf("model %q, tokens %d in / %d out; want qwen3:4b, 42 / 20", meta.Model, meta.TokensIn, meta.TokensOut)
+	}
+	// Unstreamed, TTFT is the server's estimate: load plus prompt eval.
+	if meta.TTFT != 40*time.Millisecond {
+		t.Errorf("TTFT = %v, want 40ms", meta.TTFT)
+	}
+	if meta.TokensPerSec != 40 {
+		t.Errorf("TokensPerSec = %v, want 20 tokens / 0.5s = 40", meta.TokensPerSec)
+	}
+	if meta.LoadDuration != 10*time.Millisecond || meta.Streamed || meta.InterTokenLatency != 0 {
+		t.Errorf("LoadDuration %v, Streamed %v, ITL %v; want 10ms, false, 0", meta.LoadDuration, meta.Streamed, meta.InterTokenLatency)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("%d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if string(req.Format) != `"json"` || req.Options.NumPredict != 1024 {
+		t.Errorf("format %s, num_predict %d; want \"json\", 1024", req.Format, req.Options.NumPredict)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[1].Content != "prompt" {
+		t.Errorf("messages = %+v", req.Messages)
+	}
+	if srv.Streamed()[0] {
+		t.Error("request asked for streaming without Client.Stream")
+	}
+}
+
+func TestChatStreamLatency(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	const delay, gap = 60 * time.Millisecond, 30 * time.Millisecond
+	srv.Default = ollamatest.Reply{
+		Chunks:       []string{"one ", "two ", "three ", "four"},
+		Delay:        delay,
+		ChunkDelay:   gap,
+		EvalDuration: time.Second,
+	}
+
+	var tokens []string
+	resp, err := srv.Client().ChatStream(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "count", false), func(tok string) error {
+		tokens = append(tokens, tok)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "one two three four" || len(tokens) != 4 {
+		t.Errorf("content %q from %d tokens, want 4", resp.Message.Content, len(tokens))
+	}
+
+	meta := resp.Meta
+	if !meta.Streamed {
+		t.Error("Streamed = false")
+	}
+	// Wall-clock TTFT covers the injected delay but not the chunk gaps.
+	if meta.TTFT < delay || meta.TTFT >= delay+gap {
+		t.Errorf("TTFT = %v, want in [%v, %v)", meta.TTFT, delay, delay+gap)
+	}
+	if meta.InterTokenLatency < gap || meta.InterTokenLatency >= 2*gap {
+		t.Errorf("InterTokenLatency = %v, want in [%v, %v)", meta.InterTokenLatency, gap, 2*gap)
+	}
+	if meta.TotalTime < delay+3*gap {
+		t.Errorf("TotalTime = %v, want at least %v", meta.TotalTime, delay+3*gap)
+	}
+	if meta.TokensOut != 4 || meta.TokensPerSec != 4 {
+		t.Errorf("TokensOut %d, TokensPerSec %v; want 4 chunks over 1s", meta.TokensOut, meta.TokensPerSec)
+	}
+}
+
+func TestCompleteStreams(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Content: "a b c", ChunkDelay: 10 * time.Millisecond}
+
+	c := srv.Client()
+	c.Stream = true
+	text, meta, err := c.ChatCompletion("qwen3:4b", "", "go", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "a b c" || !meta.Streamed || !srv.Streamed()[0] {
+		t.Errorf("text %q, Streamed %v, sent stream %v; want a b c streamed", text, meta.Streamed, srv.Streamed()[0])
+	}
+	if meta.InterTokenLatency < 10*time.Millisecond {
+		t.Errorf("InterTokenLatency = %v, want at least the 10ms chunk gap", meta.InterTokenLatency)
+	}
+}
+
+func TestChatStreamFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply ollamatest.Reply
+		want  string
+	}{
+		{"mid-stream error", ollamatest.Reply{Content: "partial", Error: "out of memory"}, "ollama stream error: out of memory"},
+		{"truncated", ollamatest.Reply{Content: "partial", Truncate: true}, "stream ended before done message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := ollamatest.NewServer()
+			defer srv.Close()
+			srv.Default = tt.reply
+			_, err := srv.Client().ChatStream(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// A truncated stream is retryable.
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Truncate: true}
+	_, err := srv.Client().ChatStream(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false), nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !ollama.Retryable(err) {
+		t.Errorf("truncated stream: err = %v, want a retryable io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestChatStreamAbort(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Content: "a b c d"}
+
+	stop := errors.New("stop")
+	var n int
+	_, err := srv.Client().ChatStream(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false), func(string) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("err = %v after %d tokens, want stop after 1", err, n)
+	}
+}
+
+func TestToolCalls(t *testing.T) {
+	call := ollama.ToolCall{Function: ollama.ToolCallFunction{Name: "get_weather", Arguments: map[string]any{"city": "Lisbon"}}}
+	for _, stream := range []bool{false, true} {
+		srv := ollamatest.NewServer()
+		srv.Default = ollamatest.Reply{ToolCalls: []ollama.ToolCall{call}}
+		c := srv.Client()
+		c.Stream = stream
+
+		req := ollama.NewChatRequest("qwen3:4b", "", "Weather in Lisbon?", false)
+		req.Tools = []ollama.Tool{ollama.NewTool("get_weather", "Get the weather", map[string]ollama.ToolProperty{
+			"city": {Type: "string", Description: "City name"},
+		}, []string{"city"})}
+		resp, err := c.Complete(context.Background(), req)
+		srv.Close()
+		if err != nil {
+			t.Fatalf("stream=%v: %v", stream, err)
+		}
+		got, _ := json.Marshal(resp.Message.ToolCalls)
+		want, _ := json.Marshal([]ollama.ToolCall{call})
+		if string(got) != string(want) {
+			t.Errorf("stream=%v: tool calls %s, want %s", stream, got, want)
+		}
+		if sent := srv.Requests()[0].Tools; len(sent) != 1 || sent[0].Function.Name != "get_weather" {
+			t.Errorf("stream=%v: sent tools %+v", stream, sent)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Embed returns one embedding per input from /api/embed, in input order,
+// retrying transient failures according to c.Retry. The metadata carries
+// the input token count and call time; embeddings produce no output tokens.
+func (c *Client) Embed(ctx context.Context, model string, input []string) ([][]float64, types.ModelMetadata, error) {
+	var vectors [][]float64
+	resp, err := c.Retry.Do(ctx, func(ctx context.Context) (ChatResponse, error) {
+		v, meta, err := c.embed(ctx, model, input)
+		vectors = v
+		return ChatResponse{Meta: meta}, err
+	})
+	return vectors, resp.Meta, err
+}
+
+func (c *Client) embed(ctx context.Context, model string, input []string) ([][]float64, types.ModelMetadata, error) {
+	start := time.Now()
+
+	var out struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float64 `json:"embeddings"`
+		LoadDuration    int64       `json:"load_duration"` // nanoseconds
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	req := map[string]any{"model": model, "input": input}
+	if err := c.call(ctx, c.HTTPClient, "POST", "/api/embed", req, &out); err != nil {
+		return nil, types.ModelMetadata{}, err
+	}
+	if len(out.Embeddings) != len(input) {
+		return nil, types.ModelMetadata{}, fmt.Errorf("embed: got %d embeddings for %d inputs", len(out.Embeddings), len(input))
+	}
+
+	meta := types.ModelMetadata{
+		Model:        model,
+		TokensIn:     out.PromptEvalCount,
+		TotalTime:    time.Since(start),
+		LoadDuration: time.Duration(out.LoadDuration) * time.Nanosecond,
+	}
+	return out.Embeddings, meta, nil
+}
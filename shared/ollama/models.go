@@ -0,0 +1,246 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// modelDetails is the "details" object of /api/tags and /api/show.
+type modelDetails struct {
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// ListModels returns the models installed on the server, from /api/tags.
+func (c *Client) ListModels(ctx context.Context) ([]types.ModelInfo, error) {
+	var out struct {
+		Models []struct {
+			Name    string       `json:"name"`
+			Size    int64        `json:"size"`
+			Details modelDetails `json:"details"`
+		} `json:"models"`
+	}
+	if err := c.call(ctx, c.HTTPClient, "GET", "/api/tags", nil, &out); err != nil {
+		return nil, err
+	}
+
+	models := make([]types.ModelInfo, len(out.Models))
+	for i, m := range out.Models {
+		models[i] = types.ModelInfo{
+			Name:          m.Name,
+			Family:        m.Details.Family,
+			ParameterSize: m.Details.ParameterSize,
+			Quantization:  m.Details.QuantizationLevel,
+			SizeBytes:     m.Size,
+		}
+	}
+	return models, nil
+}
+
+// ShowModel returns the details of an installed model from /api/show. Size
+// is not reported by /api/show and is left zero. A model that is not
+// installed fails with a 404 *StatusError.
+func (c *Client) ShowModel(ctx context.Context, model string) (types.ModelInfo, error) {
+	var out struct {
+		Details   modelDetails   `json:"details"`
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	if err := c.call(ctx, c.HTTPClient, "POST", "/api/show", map[string]string{"model": model}, &out); err != nil {
+		return types.ModelInfo{}, err
+	}
+
+	info := types.ModelInfo{
+		Name:          model,
+		Family:        out.Details.Family,
+		ParameterSize: out.Details.ParameterSize,
+		Quantization:  out.Details.QuantizationLevel,
+	}
+	// The context length is keyed by architecture, e.g. "qwen3.context_length".
+	for key, v := range out.ModelInfo {
+		if n, ok := v.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			info.ContextLength = int(n)
+		}
+	}
+	return info, nil
+}
+
+// PullProgress is one status update from /api/pull. Total and Completed are
+// bytes of the layer being downloaded, and zero for other steps.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PullModel downloads model, calling fn (which may be nil) with each
+// progress update. Downloads can take far longer than HTTPClient's timeout,
+// so the pull is bounded only by ctx.
+func (c *Client) PullModel(ctx context.Context, model string, fn func(PullProgress)) error {
+	hc := *c.HTTPClient
+	hc.Timeout = 0
+
+	resp, err := c.send(ctx, &hc, "POST", "/api/pull", map[string]any{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var last PullProgress
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var p PullProgress
+		if err := json.Unmarshal(line, &p); err != nil {
+			return fmt.Errorf("unmarshal pull progress: %w", err)
+		}
+		if p.Error != "" {
+			return fmt.Errorf("pull %s: %s", model, p.Error)
+		}
+		if fn != nil {
+			fn(p)
+		}
+		last = p
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read pull progress: %w", err)
+	}
+	if last.Status != "success" {
+		return fmt.Errorf("pull %s ended with %q: %w", model, last.Status, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// Preflight checks that model is installed before a run, so a typo or a
+// missing pull fails with the list of installed models instead of a 404 on
+// the first call. With pull set a missing model is downloaded, with progress
+// written to w. The returned info combines /api/tags and /api/show.
+func (c *Client) Preflight(ctx context.Context, model string, pull bool, w io.Writer) (types.ModelInfo, error) {
+	installed, err := c.ListModels(ctx)
+	if err != nil {
+		return types.ModelInfo{}, fmt.Errorf("list models at %s: %w", c.BaseURL, err)
+	}
+	info, ok := findModel(installed, model)
+	if !ok {
+		if !pull {
+			names := make([]string, len(installed))
+			for i, m := range installed {
+				names[i] = m.Name
+			}
+			sort.Strings(names)
+			return types.ModelInfo{}, fmt.Errorf("model %q is not installed (installed: %s); run \"ollama pull %s\" or pass -pull",
+				model, strings.Join(names, ", "), model)
+		}
+		if err := c.PullModel(ctx, model, progressPrinter(w, model)); err != nil {
+			return types.ModelInfo{}, err
+		}
+		if installed, err = c.ListModels(ctx); err != nil {
+			return types.ModelInfo{}, fmt.Errorf("list models at %s: %w", c.BaseURL, err)
+		}
+		info, _ = findModel(installed, model)
+	}
+
+	shown, err := c.ShowModel(ctx, model)
+	if err != nil {
+		return types.ModelInfo{}, fmt.Errorf("show %s: %w", model, err)
+	}
+	shown.Name, shown.SizeBytes = model, info.SizeBytes
+	return shown, nil
+}
+
+// findModel looks model up in installed. A name without a tag matches the
+// ":latest" tag, as it does in Ollama.
+func findModel(installed []types.ModelInfo, model string) (types.ModelInfo, bool) {
+	want := model
+	if !strings.Contains(want, ":") {
+		want += ":latest"
+	}
+	for _, m := range installed {
+		if m.Name == model || m.Name == want {
+			return m, true
+		}
+	}
+	return types.ModelInfo{}, false
+}
+
+// progressPrinter writes a line to w for every new pull step and for every
+// tenth of a download.
+func progressPrinter(w io.Writer, model string) func(PullProgress) {
+	if w == nil {
+		return nil
+	}
+	var status string
+	var decile int64 = -1
+	return func(p PullProgress) {
+		if p.Status != status {
+			status, decile = p.Status, -1
+			if p.Total == 0 {
+				fmt.Fprintf(w, "pull %s: %s\n", model, p.Status)
+			}
+		}
+		if p.Total > 0 {
+			if d := p.Completed * 10 / p.Total; d != decile {
+				decile = d
+				fmt.Fprintf(w, "pull %s: %s %d%% of %.1f MB\n", model, p.Status, d*10, float64(p.Total)/1e6)
+			}
+		}
+	}
+}
+
+// call sends body (if any) as JSON and decodes the response into out.
+func (c *Client) call(ctx context.Context, hc *http.Client, method, path string, body, out any) error {
+	resp, err := c.send(ctx, hc, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unmarshal %s response: %w", path, err)
+	}
+	return nil
+}
+
+// send is post for the endpoints other than /api/chat.
+func (c *Client) send(ctx context.Context, hc *http.Client, method, path string, body any) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, r)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := hc.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return resp, nil
+}
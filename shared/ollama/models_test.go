@@ -0,0 +1,157 @@
+package ollama_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/ollama/ollamatest"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+var installed = []ollamatest.Model{
+	{Name: "qwen3:4b", Family: "qwen3", ParameterSize: "4.0B", QuantizationLevel: "Q4_K_M", Size: 2_600_000_000, ContextLength: 40960},
+	{Name: "llama3.2:latest", Family: "llama", ParameterSize: "3.2B", QuantizationLevel: "Q8_0", Size: 3_400_000_000, ContextLength: 131072},
+}
+
+func TestListModels(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Models = installed
+
+	models, err := srv.Client().ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.ModelInfo{
+		{Name: "qwen3:4b", Family: "qwen3", ParameterSize: "4.0B", Quantization: "Q4_K_M", SizeBytes: 2_600_000_000},
+		{Name: "llama3.2:latest", Family: "llama", ParameterSize: "3.2B", Quantization: "Q8_0", SizeBytes: 3_400_000_000},
+	}
+	if len(models) != len(want) {
+		t.Fatalf("got %d models, want %d", len(models), len(want))
+	}
+	for i := range want {
+		if models[i] != want[i] {
+			t.Errorf("model %d = %+v, want %+v", i, models[i], want[i])
+		}
+	}
+}
+
+func TestShowModel(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Models = installed
+
+	info, err := srv.Client().ShowModel(context.Background(), "qwen3:4b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.ModelInfo{Name: "qwen3:4b", Family: "qwen3", ParameterSize: "4.0B", Quantization: "Q4_K_M", ContextLength: 40960}
+	if info != want {
+		t.Errorf("ShowModel = %+v, want %+v", info, want)
+	}
+
+	_, err = srv.Client().ShowModel(context.Background(), "mistral:7b")
+	var se *ollama.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("missing model: err = %v, want a 404 StatusError", err)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		pull      bool
+		pullError string
+		want      types.ModelInfo
+		wantErr   string
+		wantLog   string
+	}{
+		{
+			name:  "installed",
+			model: "qwen3:4b",
+			want:  types.ModelInfo{Name: "qwen3:4b", Family: "qwen3", ParameterSize: "4.0B", Quantization: "Q4_K_M", SizeBytes: 2_600_000_000, ContextLength: 40960},
+		},
+		{
+			name:  "untagged name matches latest",
+			model: "llama3.2",
+			want:  types.ModelInfo{Name: "llama3.2", Family: "llama", ParameterSize: "3.2B", Quantization: "Q8_0", SizeBytes: 3_400_000_000, ContextLength: 131072},
+		},
+		{
+			name:    "missing",
+			model:   "mistral:7b",
+			wantErr: `model "mistral:7b" is not installed (installed: llama3.2:latest, qwen3:4b)`,
+		},
+		{
+			name:    "pulled",
+			model:   "mistral:7b",
+			pull:    true,
+			want:    types.ModelInfo{Name: "mistral:7b", SizeBytes: 4 << 20},
+			wantLog: "pull mistral:7b: success",
+		},
+		{
+			name:      "pull fails",
+			model:     "mistral:7b",
+			pull:      true,
+			pullError: "file does not exist",
+			wantErr:   "pull mistral:7b: file does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := ollamatest.NewServer()
+			defer srv.Close()
+			srv.Models = append([]ollamatest.Model(nil), installed...)
+			srv.PullError = tt.pullError
+
+			var log strings.Builder
+			info, err := srv.Client().Preflight(context.Background(), tt.model, tt.pull, &log)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info != tt.want {
+				t.Errorf("info = %+v, want %+v", info, tt.want)
+			}
+			if !strings.Contains(log.String(), tt.wantLog) {
+				t.Errorf("pull log %q does not contain %q", log.String(), tt.wantLog)
+			}
+			if tt.pull && !strings.Contains(log.String(), "pulling layer 100%") {
+				t.Errorf("pull log %q has no download progress", log.String())
+			}
+		})
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+
+	input := []string{"reset my password", "password reset steps", "quarterly revenue"}
+	vectors, meta, err := srv.Client().Embed(context.Background(), "nomic-embed-text", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 || meta.TokensIn != 8 {
+		t.Fatalf("%d vectors, %d tokens in; want 3, 8", len(vectors), meta.TokensIn)
+	}
+	dot := func(a, b []float64) float64 {
+		var s float64
+		for i := range a {
+			s += a[i] * b[i]
+		}
+		return s
+	}
+	if dot(vectors[0], vectors[1]) <= dot(vectors[0], vectors[2]) {
+		t.Error("texts sharing words are not more similar than unrelated ones")
+	}
+}
@@ -0,0 +1,520 @@
+// Package ollamatest provides a scriptable stand-in for the Ollama REST API,
+// so clients, retry behaviour and example parse/score paths can be exercised
+// without a GPU or network.
+//
+// A Server answers /api/chat, /api/tags, /api/show, /api/pull and
+// /api/embed. Chat
+// replies come from a queue filled with Enqueue, then from the Chat hook,
+// then from Default. Each Reply can inject latency, fail with a status code,
+// stream in chosen chunks, or cut the stream short:
+//
+//	srv := ollamatest.NewServer()
+//	defer srv.Close()
+//	srv.Enqueue(
+//		ollamatest.Reply{Status: http.StatusServiceUnavailable},
+//		ollamatest.Reply{Content: `{"label":"bug"}`, OutputTokens: 5, EvalDuration: 100 * time.Millisecond},
+//	)
+//	text, meta, err := srv.Client().ChatCompletion("qwen3:4b", "", "classify", true)
+package ollamatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+)
+
+// Reply scripts one response to /api/chat.
+type Reply struct {
+	Content string
+	// ToolCalls are native tool calls in the reply, streamed in a chunk of
+	// their own after Content as Ollama does.
+	ToolCalls []ollama.ToolCall
+	// Chunks are the fragments of a streamed reply. Nil streams Content one
+	// word (with its trailing space) at a time.
+	Chunks []string
+	// Status, when neither 0 nor 200, fails the request with that status
+	// and an {"error": Error} body.
+	Status int
+	// Error is the error message. With a 0 or 200 Status it is sent after
+	// the chunks of a streamed reply, as Ollama reports a mid-stream
+	// failure, and fails an unstreamed one with a 500.
+	Error string
+	// Truncate ends a streamed reply before the done message.
+	Truncate bool
+
+	// Delay is waited before the response starts; ChunkDelay between
+	// streamed chunks. Both end early if the client goes away.
+	Delay      time.Duration
+	ChunkDelay time.Duration
+
+	// Server-side statistics reported in the final message. OutputTokens
+	// defaults to the number of chunks. total_duration is the sum of the
+	// three durations, so the client's TTFT estimate is
+	// LoadDuration + PromptDuration.
+	PromptTokens   int
+	OutputTokens   int
+	LoadDuration   time.Duration
+	PromptDuration time.Duration
+	EvalDuration   time.Duration
+	DoneReason     string // default "stop"
+}
+
+// Model is an installed model, listed by /api/tags and described by
+// /api/show.
+type Model struct {
+	Name              string
+	Family            string
+	ParameterSize     string // e.g. "4.0B"
+	QuantizationLevel string // e.g. "Q4_K_M"
+	Size              int64  // bytes on disk
+	ContextLength     int
+}
+
+// Server is a fake Ollama server. Set its exported fields before sending
+// requests.
+type Server struct {
+	*httptest.Server
+
+	// Chat answers chat requests once the queue is empty. Nil serves
+	// Default.
+	Chat    func(req ollama.ChatRequest) Reply
+	Default Reply
+
+	// Models are reported by /api/tags and /api/show. When nil every model
+	// name is accepted by /api/chat and /api/embed, but /api/tags lists
+	// none, so set Models to exercise a preflight check. Otherwise other
+	// names get a 404 as from a real server without the model.
+	Models []Model
+
+	// PullError fails /api/pull mid-stream with this message. When empty a
+	// pull succeeds and adds the model to Models.
+	PullError string
+
+	// Embed returns one vector per input. Nil uses HashEmbedding with 64
+	// dimensions.
+	Embed func(model string, input []string) [][]float64
+
+	mu       sync.Mutex // guards the fields below and Models during a pull
+	queue    []Reply
+	requests []ollama.ChatRequest
+	streamed []bool
+}
+
+// NewServer starts a Server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chat", s.handleChat)
+	mux.HandleFunc("GET /api/tags", s.handleTags)
+	mux.HandleFunc("POST /api/show", s.handleShow)
+	mux.HandleFunc("POST /api/pull", s.handlePull)
+	mux.HandleFunc("POST /api/embed", s.handleEmbed)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client returns an ollama.Client pointed at s whose retries wait only a
+// millisecond, so retry paths run quickly.
+func (s *Server) Client() *ollama.Client {
+	c := ollama.NewClient()
+	c.BaseURL = s.URL
+	c.HTTPClient = s.Server.Client()
+	c.Retry.BaseDelay = time.Millisecond
+	c.Retry.MaxDelay = time.Millisecond
+	return c
+}
+
+// Enqueue adds replies to be served, in order, before Chat or Default.
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, replies...)
+}
+
+// Requests returns every chat request received so far, in arrival order.
+func (s *Server) Requests() []ollama.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ollama.ChatRequest(nil), s.requests...)
+}
+
+// Streamed reports whether each request in Requests asked for streaming.
+func (s *Server) Streamed() []bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]bool(nil), s.streamed...)
+}
+
+// record adds req to Requests.
+func (s *Server) record(req ollama.ChatRequest, stream bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	s.streamed = append(s.streamed, stream)
+}
+
+// reply picks the Reply to req.
+func (s *Server) reply(req ollama.ChatRequest) Reply {
+	s.mu.Lock()
+	if len(s.queue) > 0 {
+		r := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		return r
+	}
+	s.mu.Unlock()
+
+	if s.Chat != nil {
+		return s.Chat(req)
+	}
+	return s.Default
+}
+
+func (s *Server) model(name string) (Model, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Models == nil {
+		return Model{Name: name}, true
+	}
+	// As in Ollama, a name without a tag means ":latest".
+	latest := name
+	if !strings.Contains(latest, ":") {
+		latest += ":latest"
+	}
+	for _, m := range s.Models {
+		if m.Name == name || m.Name == latest {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// chatMessage is one line of an /api/chat response.
+type chatMessage struct {
+	Model              string         `json:"model"`
+	CreatedAt          time.Time      `json:"created_at"`
+	Message            ollama.Message `json:"message"`
+	Done               bool           `json:"done"`
+	DoneReason         string         `json:"done_reason,omitempty"`
+	TotalDuration      int64          `json:"total_duration,omitempty"`
+	LoadDuration       int64          `json:"load_duration,omitempty"`
+	PromptEvalCount    int            `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64          `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int            `json:"eval_count,omitempty"`
+	EvalDuration       int64          `json:"eval_duration,omitempty"`
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ollama.ChatRequest
+		Stream *bool `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Ollama streams unless told otherwise.
+	stream := req.Stream == nil || *req.Stream
+	s.record(req.ChatRequest, stream)
+	if _, ok := s.model(req.Model); !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", req.Model))
+		return
+	}
+
+	reply := s.reply(req.ChatRequest)
+	if !sleep(r, reply.Delay) {
+		return
+	}
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		writeError(w, reply.Status, reply.Error)
+		return
+	}
+
+	chunks := reply.Chunks
+	if chunks == nil {
+		chunks = splitWords(reply.Content)
+	}
+	final := chatMessage{
+		Model:              req.Model,
+		CreatedAt:          time.Now().UTC(),
+		Message:            ollama.Message{Role: "assistant", Content: strings.Join(chunks, ""), ToolCalls: reply.ToolCalls},
+		Done:               true,
+		DoneReason:         reply.DoneReason,
+		TotalDuration:      int64(reply.LoadDuration + reply.PromptDuration + reply.EvalDuration),
+		LoadDuration:       int64(reply.LoadDuration),
+		PromptEvalCount:    reply.PromptTokens,
+		PromptEvalDuration: int64(reply.PromptDuration),
+		EvalCount:          reply.OutputTokens,
+		EvalDuration:       int64(reply.EvalDuration),
+	}
+	if final.DoneReason == "" {
+		final.DoneReason = "stop"
+	}
+	if final.EvalCount == 0 {
+		final.EvalCount = len(chunks)
+	}
+
+	if !stream {
+		if reply.Error != "" {
+			writeError(w, http.StatusInternalServerError, reply.Error)
+			return
+		}
+		writeJSON(w, http.StatusOK, final)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for i, chunk := range chunks {
+		if i > 0 && !sleep(r, reply.ChunkDelay) {
+			return
+		}
+		enc.Encode(chatMessage{
+			Model:     req.Model,
+			CreatedAt: time.Now().UTC(),
+			Message:   ollama.Message{Role: "assistant", Content: chunk},
+		})
+		flush()
+	}
+	if len(reply.ToolCalls) > 0 {
+		enc.Encode(chatMessage{
+			Model:     req.Model,
+			CreatedAt: time.Now().UTC(),
+			Message:   ollama.Message{Role: "assistant", ToolCalls: reply.ToolCalls},
+		})
+		flush()
+	}
+	switch {
+	case reply.Error != "":
+		enc.Encode(map[string]string{"error": reply.Error})
+	case reply.Truncate:
+	default:
+		final.Message.Content, final.Message.ToolCalls = "", nil
+		enc.Encode(final)
+	}
+	flush()
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	type tag struct {
+		Name       string    `json:"name"`
+		Model      string    `json:"model"`
+		ModifiedAt time.Time `json:"modified_at"`
+		Size       int64     `json:"size"`
+		Details    details   `json:"details"`
+	}
+	tags := []tag{}
+	s.mu.Lock()
+	for _, m := range s.Models {
+		tags = append(tags, tag{Name: m.Name, Model: m.Name, Size: m.Size, Details: m.details()})
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"models": tags})
+}
+
+func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+		Name  string `json:"name"` // older clients
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Model == "" {
+		req.Model = req.Name
+	}
+	m, ok := s.model(req.Model)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", req.Model))
+		return
+	}
+
+	info := map[string]any{"general.architecture": m.Family}
+	if m.ContextLength > 0 {
+		info[m.Family+".context_length"] = m.ContextLength
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"modelfile":  "FROM " + m.Name,
+		"template":   "{{ .Prompt }}",
+		"details":    m.details(),
+		"model_info": info,
+	})
+}
+
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.Encode(ollama.PullProgress{Status: "pulling manifest"})
+	const total = 4 << 20
+	for done := int64(0); done <= total; done += total / 4 {
+		enc.Encode(ollama.PullProgress{Status: "pulling layer", Digest: "sha256:0", Total: total, Completed: done})
+	}
+	if s.PullError != "" {
+		enc.Encode(ollama.PullProgress{Error: s.PullError})
+		return
+	}
+
+	s.mu.Lock()
+	if s.Models != nil {
+		s.Models = append(s.Models, Model{Name: req.Model, Size: total})
+	}
+	s.mu.Unlock()
+	enc.Encode(ollama.PullProgress{Status: "success"})
+}
+
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var req struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := s.model(req.Model); !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", req.Model))
+		return
+	}
+
+	// input is a string or an array of strings.
+	var input []string
+	if err := json.Unmarshal(req.Input, &input); err != nil {
+		var one string
+		if err := json.Unmarshal(req.Input, &one); err != nil {
+			writeError(w, http.StatusBadRequest, "input must be a string or an array of strings")
+			return
+		}
+		input = []string{one}
+	}
+
+	var vectors [][]float64
+	if s.Embed != nil {
+		vectors = s.Embed(req.Model, input)
+	} else {
+		for _, text := range input {
+			vectors = append(vectors, HashEmbedding(text, 64))
+		}
+	}
+	var tokens int
+	for _, text := range input {
+		tokens += len(strings.Fields(text))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"model":             req.Model,
+		"embeddings":        vectors,
+		"total_duration":    time.Since(start).Nanoseconds(),
+		"prompt_eval_count": tokens,
+	})
+}
+
+// details is the "details" object of /api/tags and /api/show.
+type details struct {
+	Format            string `json:"format"`
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+func (m Model) details() details {
+	return details{
+		Format:            "gguf",
+		Family:            m.Family,
+		ParameterSize:     m.ParameterSize,
+		QuantizationLevel: m.QuantizationLevel,
+	}
+}
+
+// HashEmbedding returns a deterministic unit vector for text by hashing each
+// lower-cased word into one of dims buckets. Texts sharing words have a
+// positive cosine similarity, which is enough to test ranking code.
+func HashEmbedding(text string, dims int) []float64 {
+	v := make([]float64, dims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		v[h.Sum32()%uint32(dims)]++
+	}
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range v {
+			v[i] /= norm
+		}
+	}
+	return v
+}
+
+// splitWords splits s into words, each keeping the whitespace after it, so
+// the chunks concatenate back to s.
+func splitWords(s string) []string {
+	var chunks []string
+	start := 0
+	for i := 1; i < len(s); i++ {
+		if unicode.IsSpace(rune(s[i-1])) && !unicode.IsSpace(rune(s[i])) {
+			chunks = append(chunks, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		chunks = append(chunks, s[start:])
+	}
+	return chunks
+}
+
+// sleep waits d, returning false if the client disconnects first.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
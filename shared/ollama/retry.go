@@ -0,0 +1,150 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// RetryPolicy controls how Complete retries a failed call. The zero value
+// makes a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. Each later retry doubles
+	// it, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter randomizes each wait by up to this fraction (0-1) so concurrent
+	// workers that failed together don't retry in lockstep.
+	Jitter float64
+	// AttemptTimeout bounds each attempt. Zero leaves only the HTTP client's
+	// timeout and the caller's context.
+	AttemptTimeout time.Duration
+}
+
+// DefaultRetryPolicy makes up to three attempts, waiting about 1s and then
+// 2s between them. That rides out a model load or a restarted server
+// without hiding a server that is down.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// Delay returns the wait before the given retry (1 for the first retry).
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		// Spread uniformly over d ± Jitter*d.
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// StatusError is returned when the server answers with a status other than
+// 200.
+type StatusError struct {
+	// Server names the server in the message; empty means Ollama.
+	Server     string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	server := e.Server
+	if server == "" {
+		server = "ollama"
+	}
+	return fmt.Sprintf("%s returned %d: %s", server, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether err is likely transient: a timeout, a dropped or
+// refused connection, a truncated response, or a 408, 429 or 5xx status
+// other than 501. Other statuses (a 404 for a model that isn't pulled, a 400
+// for a bad request), cancellation by the caller, an unknown host and a
+// malformed URL fail immediately.
+func Retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests,
+			http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// Every *url.Error is a net.Error, so only its timeouts count: a host
+	// that doesn't resolve or a malformed URL fails the same way next time.
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Do calls fn until it succeeds, fails with an error that is not Retryable,
+// ctx ends, or MaxAttempts is reached. The number of retries is recorded in
+// the response metadata whether or not the call eventually succeeded. Do is
+// shared by every client that returns a ChatResponse.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) (ChatResponse, error)) (ChatResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := p.attempt(ctx, fn)
+		if err == nil {
+			resp.Meta.Retries = attempt - 1
+			return resp, nil
+		}
+		if attempt >= p.MaxAttempts || !Retryable(err) || ctx.Err() != nil {
+			if attempt > 1 {
+				err = fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return failed(attempt), err
+		}
+
+		t := time.NewTimer(p.Delay(attempt))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return failed(attempt), fmt.Errorf("after %d attempts: %w", attempt, ctx.Err())
+		}
+	}
+}
+
+// attempt runs fn once, bounded by AttemptTimeout when it is positive.
+func (p RetryPolicy) attempt(ctx context.Context, fn func(ctx context.Context) (ChatResponse, error)) (ChatResponse, error) {
+	if p.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.AttemptTimeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
+
+// failed is the response returned alongside an error after attempt tries.
+func failed(attempt int) ChatResponse {
+	return ChatResponse{Meta: types.ModelMetadata{Retries: attempt - 1}}
+}
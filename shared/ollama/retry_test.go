@@ -0,0 +1,191 @@
+package ollama_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/ollama/ollamatest"
+)
+
+func TestCompleteRetries(t *testing.T) {
+	fail := func(status int) ollamatest.Reply {
+		return ollamatest.Reply{Status: status, Error: http.StatusText(status)}
+	}
+	ok := ollamatest.Reply{Content: "ok"}
+
+	tests := []struct {
+		name     string
+		stream   bool
+		replies  []ollamatest.Reply
+		wantErr  string // empty for success
+		requests int
+		retries  int
+	}{
+		{"success", false, []ollamatest.Reply{ok}, "", 1, 0},
+		{"429 then 503", false, []ollamatest.Reply{fail(429), fail(503), ok}, "", 3, 2},
+		{"500", false, []ollamatest.Reply{fail(500), ok}, "", 2, 1},
+		{"truncated stream", true, []ollamatest.Reply{{Content: "par", Truncate: true}, ok}, "", 2, 1},
+		{"gives up", false, []ollamatest.Reply{fail(503), fail(503), fail(503), ok}, "after 3 attempts: ollama returned 503", 3, 2},
+		{"400 is final", false, []ollamatest.Reply{fail(400), ok}, "ollama returned 400", 1, 0},
+		{"404 is final", false, []ollamatest.Reply{fail(404), ok}, "ollama returned 404", 1, 0},
+		{"501 is final", false, []ollamatest.Reply{fail(501), ok}, "ollama returned 501", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := ollamatest.NewServer()
+			defer srv.Close()
+			srv.Enqueue(tt.replies...)
+			c := srv.Client()
+			c.Stream = tt.stream
+
+			resp, err := c.Complete(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("err = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			case tt.wantErr == "" && resp.Message.Content != "ok":
+				t.Errorf("content = %q, want ok", resp.Message.Content)
+			}
+			if n := len(srv.Requests()); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+			if resp.Meta.Retries != tt.retries {
+				t.Errorf("Meta.Retries = %d, want %d", resp.Meta.Retries, tt.retries)
+			}
+		})
+	}
+}
+
+func TestChatCompletionRecordsRetriesOnError(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Status: http.StatusServiceUnavailable}
+
+	c := srv.Client()
+	c.Retry.MaxAttempts = 4
+	_, meta, err := c.ChatCompletion("qwen3:4b", "", "go", false)
+	var se *ollama.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want a 503 StatusError", err)
+	}
+	if meta.Retries != 3 || len(srv.Requests()) != 4 {
+		t.Errorf("Retries %d over %d requests, want 3 over 4", meta.Retries, len(srv.Requests()))
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(ollamatest.Reply{Delay: time.Second, Content: "slow"})
+	srv.Default = ollamatest.Reply{Content: "fast"}
+
+	c := srv.Client()
+	c.Retry.AttemptTimeout = 50 * time.Millisecond
+	resp, err := c.Complete(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "fast" || resp.Meta.Retries != 1 {
+		t.Errorf("got %q after %d retries, want fast after 1", resp.Message.Content, resp.Meta.Retries)
+	}
+}
+
+func TestCanceledContextIsNotRetried(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Delay: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	resp, err := srv.Client().Complete(ctx, ollama.NewChatRequest("qwen3:4b", "", "go", false))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if resp.Meta.Retries != 0 {
+		t.Errorf("Retries = %d after the caller's deadline, want 0", resp.Meta.Retries)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// TestUnknownHostIsNotRetried checks that a -base-url naming a host that
+// doesn't resolve fails on the first attempt instead of backing off.
+func TestUnknownHostIsNotRetried(t *testing.T) {
+	var attempts int
+	c := ollama.NewClient()
+	c.BaseURL = "http://gpu-box.invalid:11434"
+	c.Retry.BaseDelay, c.Retry.MaxDelay = time.Millisecond, time.Millisecond
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: r.URL.Hostname(), IsNotFound: true}}
+	})}
+
+	resp, err := c.Complete(context.Background(), ollama.NewChatRequest("qwen3:4b", "", "go", false))
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Fatalf("err = %v, want a DNS error", err)
+	}
+	if attempts != 1 || resp.Meta.Retries != 0 {
+		t.Errorf("%d attempts, %d retries; want 1, 0", attempts, resp.Meta.Retries)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&ollama.StatusError{StatusCode: 408}, true},
+		{&ollama.StatusError{StatusCode: 429}, true},
+		{&ollama.StatusError{StatusCode: 500}, true},
+		{&ollama.StatusError{StatusCode: 502}, true},
+		{&ollama.StatusError{StatusCode: 503}, true},
+		{&ollama.StatusError{StatusCode: 504}, true},
+		{&ollama.StatusError{StatusCode: 400}, false},
+		{&ollama.StatusError{StatusCode: 404}, false},
+		{&ollama.StatusError{StatusCode: 501}, false},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("unmarshal response: bad JSON"), false},
+		{&url.Error{Op: "Post", URL: "http://gpu-box:11434/api/chat", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, true},
+		{&url.Error{Op: "Post", URL: "http://gpu-box:11434/api/chat", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "gpu-box", IsTimeout: true}}}, true},
+		{&url.Error{Op: "Post", URL: "http://gpu-box:11434/api/chat", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "gpu-box", IsNotFound: true}}}, false},
+		{&url.Error{Op: "Post", URL: "localhost:11434/api/chat", Err: errors.New(`unsupported protocol scheme "localhost"`)}, false},
+	}
+	for _, tt := range tests {
+		if got := ollama.Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	p := ollama.RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := p.Delay(retry); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", retry, got, want)
+		}
+	}
+
+	p.Jitter = 0.2
+	for range 100 {
+		if d := p.Delay(2); d < 1600*time.Millisecond || d > 2400*time.Millisecond {
+			t.Fatalf("Delay(2) with 20%% jitter = %v, want within 2s ± 400ms", d)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ollama
+
+// Tool describes a function the model may call, in the form /api/chat
+// takes in its tools array. Models whose template supports tools reply
+// with Message.ToolCalls instead of (or alongside) text content.
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the name, purpose and parameters of a Tool.
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  ToolParameters `json:"parameters"`
+}
+
+// ToolParameters is the JSON Schema object describing a function's
+// arguments.
+type ToolParameters struct {
+	Type       string                  `json:"type"` // always "object"
+	Properties map[string]ToolProperty `json:"properties"`
+	Required   []string                `json:"required,omitempty"`
+}
+
+// ToolProperty describes one argument.
+type ToolProperty struct {
+	Type        string   `json:"type"` // string, number, integer, boolean, ...
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Format      string   `json:"format,omitempty"` // JSON Schema format, e.g. date, email
+}
+
+// NewTool builds a function Tool. A nil properties map is sent as an empty
+// object, as servers expect for functions without arguments.
+func NewTool(name, description string, properties map[string]ToolProperty, required []string) Tool {
+	if properties == nil {
+		properties = map[string]ToolProperty{}
+	}
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters:  ToolParameters{Type: "object", Properties: properties, Required: required},
+		},
+	}
+}
+
+// ToolCall is a call the model made through the native tool API.
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called function and its decoded arguments.
+type ToolCallFunction struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
@@ -0,0 +1,406 @@
+// Package openai is a client for OpenAI-compatible /v1/chat/completions
+// endpoints: the OpenAI API itself, llama.cpp server, vLLM, LM Studio or a
+// local mock. It accepts the same ollama.ChatRequest and returns the same
+// ollama.ChatResponse as the Ollama client, so examples can run against
+// either through provider.Provider.
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// DefaultBaseURL is the OpenAI API. Local servers usually listen on
+// http://localhost:8080/v1 (llama.cpp), :8000/v1 (vLLM) or :1234/v1
+// (LM Studio).
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Client communicates with an OpenAI-compatible server.
+type Client struct {
+	// BaseURL includes the version prefix, e.g. http://localhost:8080/v1.
+	BaseURL string
+	// APIKey is sent as a bearer token when set. Local servers usually
+	// ignore it.
+	APIKey     string
+	HTTPClient *http.Client
+	// Stream makes Complete consume the response as server-sent events so
+	// TTFT and inter-token latency are measured on the wire.
+	Stream bool
+	// Retry controls how Complete and ChatCompletion retry transient
+	// failures. Chat and ChatStream always make a single attempt.
+	Retry ollama.RetryPolicy
+}
+
+// NewClient returns a Client for baseURL, or DefaultBaseURL if it is empty.
+func NewClient(baseURL, apiKey string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		Retry: ollama.DefaultRetryPolicy(),
+	}
+}
+
+// wireRequest is the JSON body sent to /chat/completions. Ollama-only
+// options (top_k, repeat_penalty, num_ctx, keep_alive) have no standard
+// equivalent and are not sent.
+type wireRequest struct {
+	Model          string          `json:"model"`
+	Messages       []wireMessage   `json:"messages"`
+	Tools          []ollama.Tool   `json:"tools,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	Seed           *int            `json:"seed,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+}
+
+// wireMessage is a chat message as OpenAI-compatible servers exchange it.
+// Tools are declared in the same form as Ollama's, but tool call arguments
+// are a JSON-encoded string rather than an object.
+type wireMessage struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []wireToolCall `json:"tool_calls,omitempty"`
+}
+
+type wireToolCall struct {
+	// Index identifies the call a streamed delta continues.
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// newWireMessage encodes m, numbering its tool calls.
+func newWireMessage(m ollama.Message) wireMessage {
+	w := wireMessage{Role: m.Role, Content: m.Content}
+	for i, tc := range m.ToolCalls {
+		args, _ := json.Marshal(tc.Function.Arguments)
+		call := wireToolCall{ID: fmt.Sprintf("call_%d", i), Type: "function"}
+		call.Function.Name, call.Function.Arguments = tc.Function.Name, string(args)
+		w.ToolCalls = append(w.ToolCalls, call)
+	}
+	return w
+}
+
+// message decodes w, parsing each tool call's arguments.
+func (w wireMessage) message() (ollama.Message, error) {
+	m := ollama.Message{Role: w.Role, Content: w.Content}
+	for _, tc := range w.ToolCalls {
+		call := ollama.ToolCall{Function: ollama.ToolCallFunction{Name: tc.Function.Name}}
+		if args := strings.TrimSpace(tc.Function.Arguments); args != "" {
+			if err := json.Unmarshal([]byte(args), &call.Function.Arguments); err != nil {
+				return ollama.Message{}, fmt.Errorf("unmarshal arguments of tool call %s: %w", tc.Function.Name, err)
+			}
+		}
+		m.ToolCalls = append(m.ToolCalls, call)
+	}
+	return m, nil
+}
+
+type responseFormat struct {
+	Type       string      `json:"type"` // json_object or json_schema
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// newWireRequest translates req. ollama.JSONFormat becomes JSON mode and
+// any other Format is sent as a JSON Schema response format.
+func newWireRequest(req ollama.ChatRequest, stream bool) wireRequest {
+	w := wireRequest{
+		Model:       req.Model,
+		Tools:       req.Tools,
+		Temperature: req.Options.Temperature,
+		TopP:        req.Options.TopP,
+		Seed:        req.Options.Seed,
+		MaxTokens:   req.Options.NumPredict,
+		Stop:        req.Options.Stop,
+		Stream:      stream,
+	}
+	for _, m := range req.Messages {
+		w.Messages = append(w.Messages, newWireMessage(m))
+	}
+	switch format := bytes.TrimSpace(req.Format); {
+	case len(format) == 0:
+	case bytes.Equal(format, ollama.JSONFormat):
+		w.ResponseFormat = &responseFormat{Type: "json_object"}
+	default:
+		w.ResponseFormat = &responseFormat{
+			Type:       "json_schema",
+			JSONSchema: &jsonSchema{Name: "output", Schema: req.Format},
+		}
+	}
+	if stream {
+		w.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	return w
+}
+
+// wireResponse is the JSON body returned by /chat/completions, and each
+// streamed chunk, which carries a Delta instead of a Message.
+type wireResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      wireMessage `json:"message"`
+		Delta        wireMessage `json:"delta"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// metadata converts usage into ModelMetadata. OpenAI-compatible servers do
+// not report server-side timings, so TokensPerSec is measured over the whole
+// call and TTFT is only known when streaming.
+func (r wireResponse) metadata(totalTime time.Duration) types.ModelMetadata {
+	meta := types.ModelMetadata{Model: r.Model, TotalTime: totalTime}
+	if r.Usage != nil {
+		meta.TokensIn = r.Usage.PromptTokens
+		meta.TokensOut = r.Usage.CompletionTokens
+	}
+	if totalTime > 0 {
+		meta.TokensPerSec = float64(meta.TokensOut) / totalTime.Seconds()
+	}
+	return meta
+}
+
+// Chat sends req and waits for the complete response.
+func (c *Client) Chat(ctx context.Context, req ollama.ChatRequest) (ollama.ChatResponse, error) {
+	start := time.Now()
+
+	resp, err := c.post(ctx, req, false)
+	if err != nil {
+		return ollama.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out wireResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return ollama.ChatResponse{}, fmt.Errorf("unmarshal response: %w", err)
+	}
+	if out.Error != nil {
+		return ollama.ChatResponse{}, fmt.Errorf("%s: %s", c.BaseURL, out.Error.Message)
+	}
+	if len(out.Choices) == 0 {
+		return ollama.ChatResponse{}, fmt.Errorf("%s: response has no choices", c.BaseURL)
+	}
+
+	choice := out.Choices[0]
+	msg, err := choice.Message.message()
+	if err != nil {
+		return ollama.ChatResponse{}, err
+	}
+	return ollama.ChatResponse{
+		Message:    msg,
+		DoneReason: choice.FinishReason,
+		Meta:       out.metadata(time.Since(start)),
+	}, nil
+}
+
+// ChatStream sends req with streaming enabled and calls fn with each content
+// fragment as it arrives. fn may be nil; returning an error from fn aborts
+// the stream. TTFT and InterTokenLatency are measured as in
+// ollama.Client.ChatStream.
+func (c *Client) ChatStream(ctx context.Context, req ollama.ChatRequest, fn func(token string) error) (ollama.ChatResponse, error) {
+	start := time.Now()
+
+	resp, err := c.post(ctx, req, true)
+	if err != nil {
+		return ollama.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		content     strings.Builder
+		toolCalls   []wireToolCall
+		last        wireResponse
+		role        = "assistant"
+		finish      string
+		done        bool
+		first, prev time.Time
+		fragments   int
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue // blank separator, comment or other SSE field
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk wireResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return ollama.ChatResponse{}, fmt.Errorf("unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return ollama.ChatResponse{}, fmt.Errorf("%s stream error: %s", c.BaseURL, chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			last.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			last.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.Delta.Role != "" {
+			role = choice.Delta.Role
+		}
+		if choice.FinishReason != "" {
+			finish = choice.FinishReason
+		}
+		// A tool call streams as deltas sharing an index: the first names
+		// the function, the rest append fragments of its arguments.
+		for _, tc := range choice.Delta.ToolCalls {
+			i := len(toolCalls)
+			if tc.Index != nil {
+				i = *tc.Index
+			}
+			for len(toolCalls) <= i {
+				toolCalls = append(toolCalls, wireToolCall{})
+			}
+			if tc.Function.Name != "" {
+				toolCalls[i].Function.Name = tc.Function.Name
+			}
+			toolCalls[i].Function.Arguments += tc.Function.Arguments
+		}
+		if tok := choice.Delta.Content; tok != "" {
+			now := time.Now()
+			if fragments == 0 {
+				first = now
+			}
+			prev = now
+			fragments++
+			content.WriteString(tok)
+			if fn != nil {
+				if err := fn(tok); err != nil {
+					return ollama.ChatResponse{}, err
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ollama.ChatResponse{}, fmt.Errorf("read stream: %w", err)
+	}
+	if !done {
+		return ollama.ChatResponse{}, fmt.Errorf("stream ended before [DONE]: %w", io.ErrUnexpectedEOF)
+	}
+
+	meta := last.metadata(time.Since(start))
+	meta.Streamed = true
+	if fragments > 0 {
+		meta.TTFT = first.Sub(start)
+	}
+	if fragments > 1 {
+		meta.InterTokenLatency = prev.Sub(first) / time.Duration(fragments-1)
+	}
+
+	msg, err := wireMessage{Role: role, Content: content.String(), ToolCalls: toolCalls}.message()
+	if err != nil {
+		return ollama.ChatResponse{}, err
+	}
+	return ollama.ChatResponse{
+		Message:    msg,
+		DoneReason: finish,
+		Meta:       meta,
+	}, nil
+}
+
+// post sends req to /chat/completions and returns the response once a 200
+// status has been received. The caller must close the response body. Any
+// other status is returned as an *ollama.StatusError so the retry policy
+// classifies it the same way.
+func (c *Client) post(ctx context.Context, req ollama.ChatRequest, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(newWireRequest(req, stream))
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", c.BaseURL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &ollama.StatusError{Server: c.BaseURL, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return resp, nil
+}
+
+// Complete sends req via ChatStream when c.Stream is set and via Chat
+// otherwise, retrying transient failures according to c.Retry.
+func (c *Client) Complete(ctx context.Context, req ollama.ChatRequest) (ollama.ChatResponse, error) {
+	return c.Retry.Do(ctx, func(ctx context.Context) (ollama.ChatResponse, error) {
+		if c.Stream {
+			return c.ChatStream(ctx, req, nil)
+		}
+		return c.Chat(ctx, req)
+	})
+}
+
+// ChatCompletion sends a single-turn request built by ollama.NewChatRequest
+// and returns the response text and metadata, like
+// ollama.Client.ChatCompletion.
+func (c *Client) ChatCompletion(model, system, prompt string, jsonMode bool, maxTokens ...int) (string, types.ModelMetadata, error) {
+	resp, err := c.Complete(context.Background(), ollama.NewChatRequest(model, system, prompt, jsonMode, maxTokens...))
+	if err != nil {
+		return "", resp.Meta, err
+	}
+	return resp.Message.Content, resp.Meta, nil
+}
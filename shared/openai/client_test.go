@@ -0,0 +1,281 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/openai"
+)
+
+// server answers /v1/chat/completions with handle and records each request
+// body.
+type server struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []map[string]json.RawMessage
+}
+
+func newServer(t *testing.T, handle http.HandlerFunc) (*server, *openai.Client) {
+	t.Helper()
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "unexpected "+r.URL.Path+" "+r.Header.Get("Authorization"), http.StatusNotFound)
+			return
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		handle(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	c := openai.NewClient(s.URL+"/v1/", "sk-test")
+	c.Retry.BaseDelay = time.Millisecond
+	c.Retry.MaxDelay = time.Millisecond
+	return s, c
+}
+
+func (s *server) requests() []map[string]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]json.RawMessage(nil), s.bodies...)
+}
+
+// sse writes events as server-sent events, flushing after each write so
+// they reach the client in separate reads.
+func sse(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, e := range events {
+		io.WriteString(w, e)
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestChat(t *testing.T) {
+	s, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{
+			"model": "gpt-4o-mini-2024-07-18",
+			"choices": [{"message": {"role": "assistant", "content": "{\"label\": \"bug\"}"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 42, "completion_tokens": 7}
+		}`)
+	})
+
+	text, meta, err := c.ChatCompletion("gpt-4o-mini", "system", "prompt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != `{"label": "bug"}` {
+		t.Errorf("text = %q", text)
+	}
+	if meta.Model != "gpt-4o-mini-2024-07-18" || meta.TokensIn != 42 || meta.TokensOut != 7 {
+		t.Errorf("model %q, tokens %d in / %d out; want the served model, 42 / 7", meta.Model, meta.TokensIn, meta.TokensOut)
+	}
+	if meta.Streamed || meta.TTFT != 0 || meta.Retries != 0 {
+		t.Errorf("Streamed %v, TTFT %v, Retries %d; want an unstreamed single attempt", meta.Streamed, meta.TTFT, meta.Retries)
+	}
+
+	reqs := s.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("%d requests, want 1", len(reqs))
+	}
+	var messages []struct{ Role, Content string }
+	json.Unmarshal(reqs[0]["messages"], &messages)
+	if len(messages) != 2 || messages[0].Role != "system" || messages[1].Content != "prompt" {
+		t.Errorf("messages = %s", reqs[0]["messages"])
+	}
+	if string(reqs[0]["max_tokens"]) != "1024" || reqs[0]["stream"] != nil {
+		t.Errorf("max_tokens %s, stream %s; want 1024 and unset", reqs[0]["max_tokens"], reqs[0]["stream"])
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	s, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"choices": [{"message": {"role": "assistant", "content": "{}"}}]}`)
+	})
+
+	schema := json.RawMessage(`{"type": "object", "properties": {"label": {"type": "string"}}}`)
+	tests := []struct {
+		name   string
+		format json.RawMessage
+		want   string
+	}{
+		{"none", nil, ""},
+		{"json mode", ollama.JSONFormat, `{"type":"json_object"}`},
+		{"schema", schema, `{"type":"json_schema","json_schema":{"name":"output","schema":{"type":"object","properties":{"label":{"type":"string"}}}}}`},
+	}
+	for i, tt := range tests {
+		req := ollama.NewChatRequest("gpt-4o-mini", "", "prompt", false)
+		req.Format = tt.format
+		if _, err := c.Chat(context.Background(), req); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := s.requests()[i]["response_format"]
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("%s: response_format = %s, want unset", tt.name, got)
+			}
+			continue
+		}
+		var gotV, wantV any
+		json.Unmarshal(got, &gotV)
+		json.Unmarshal([]byte(tt.want), &wantV)
+		if fmt.Sprint(gotV) != fmt.Sprint(wantV) {
+			t.Errorf("%s: response_format = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	s, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sse(w,
+			`data: {"model": "llama-3.2-3b", "choices": [{"delta": {"role": "assistant", "content": ""}}]}`+"\n\n",
+			`data: {"choices": [{"delta": {"content": "Hel"}}]}`+"\n\n",
+			// One event split across two writes.
+			`data: {"choices": [{"delta": {"con`,
+			`tent": "lo"}}]}`+"\n\n",
+			": keep-alive comment\n\n",
+			`data: {"choices": [{"delta": {"content": " world"}, "finish_reason": "stop"}]}`+"\n\n",
+			`data: {"choices": [], "usage": {"prompt_tokens": 12, "completion_tokens": 3}}`+"\n\n",
+			"data: [DONE]\n\n",
+		)
+	})
+	c.Stream = true
+
+	var tokens []string
+	resp, err := c.ChatStream(context.Background(), ollama.NewChatRequest("llama-3.2-3b", "", "greet", false), func(tok string) error {
+		tokens = append(tokens, tok)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tokens, "|") != "Hel|lo| world" {
+		t.Errorf("tokens = %q, want Hel, lo, world", tokens)
+	}
+	if resp.Message.Role != "assistant" || resp.Message.Content != "Hello world" || resp.DoneReason != "stop" {
+		t.Errorf("message %+v, done reason %q", resp.Message, resp.DoneReason)
+	}
+	meta := resp.Meta
+	if !meta.Streamed || meta.Model != "llama-3.2-3b" || meta.TokensIn != 12 || meta.TokensOut != 3 {
+		t.Errorf("meta = %+v; want streamed llama-3.2-3b, 12 / 3 tokens", meta)
+	}
+	if meta.TTFT <= 0 || meta.TTFT > meta.TotalTime {
+		t.Errorf("TTFT %v outside (0, %v]", meta.TTFT, meta.TotalTime)
+	}
+
+	req := s.requests()[0]
+	if string(req["stream"]) != "true" || string(req["stream_options"]) != `{"include_usage":true}` {
+		t.Errorf("stream %s, stream_options %s; want true and usage included", req["stream"], req["stream_options"])
+	}
+}
+
+func TestChatStreamToolCalls(t *testing.T) {
+	_, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sse(w,
+			`data: {"choices": [{"delta": {"role": "assistant", "tool_calls": [{"index": 0, "id": "call_a", "type": "function", "function": {"name": "toggle_light", "arguments": ""}}]}}]}`+"\n\n",
+			`data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "{\"ro"}}]}}]}`+"\n\n",
+			`data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "om\": \"kit"}}]}}]}`+"\n\n",
+			`data: {"choices": [{"delta": {"tool_calls": [{"index": 1, "id": "call_b", "type": "function", "function": {"name": "set_timer", "arguments": "{\"minutes\":"}}]}}]}`+"\n\n",
+			`data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "chen\"}"}}]}}]}`+"\n\n",
+			`data: {"choices": [{"delta": {"tool_calls": [{"index": 1, "function": {"arguments": " 5}"}}]}, "finish_reason": "tool_calls"}]}`+"\n\n",
+			"data: [DONE]\n\n",
+		)
+	})
+
+	resp, err := c.ChatStream(context.Background(), ollama.NewChatRequest("gpt-4o-mini", "", "lights off, timer", false), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := resp.Message.ToolCalls
+	if len(calls) != 2 || resp.DoneReason != "tool_calls" {
+		t.Fatalf("tool calls = %+v, done reason %q", calls, resp.DoneReason)
+	}
+	if f := calls[0].Function; f.Name != "toggle_light" || f.Arguments["room"] != "kitchen" {
+		t.Errorf("call 0 = %+v, want toggle_light in the kitchen", f)
+	}
+	if f := calls[1].Function; f.Name != "set_timer" || f.Arguments["minutes"] != 5.0 {
+		t.Errorf("call 1 = %+v, want set_timer for 5 minutes", f)
+	}
+}
+
+func TestChatStreamTruncated(t *testing.T) {
+	_, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sse(w, `data: {"choices": [{"delta": {"content": "Hel"}}]}`+"\n\n")
+	})
+
+	_, err := c.ChatStream(context.Background(), ollama.NewChatRequest("gpt-4o-mini", "", "greet", false), nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	var mu sync.Mutex
+	statuses := []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+	s, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(statuses) > 0 {
+			status := statuses[0]
+			statuses = statuses[1:]
+			http.Error(w, `{"error": {"message": "overloaded"}}`, status)
+			return
+		}
+		io.WriteString(w, `{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`)
+	})
+	req := ollama.NewChatRequest("gpt-4o-mini", "", "prompt", false)
+
+	// A single attempt surfaces the status the same way the Ollama client
+	// does, so the retry policy classifies it.
+	_, err := c.Chat(context.Background(), req)
+	var statusErr *ollama.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || !strings.Contains(statusErr.Body, "overloaded") {
+		t.Fatalf("err = %v, want a 503 *ollama.StatusError", err)
+	}
+	if !ollama.Retryable(err) {
+		t.Errorf("Retryable(%v) = false", err)
+	}
+
+	// Complete retries the 502 and succeeds.
+	resp, err := c.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "ok" || resp.Meta.Retries != 1 {
+		t.Errorf("content %q after %d retries; want ok after 1", resp.Message.Content, resp.Meta.Retries)
+	}
+	if n := len(s.requests()); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
+
+func TestClientErrorNotRetried(t *testing.T) {
+	s, c := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"message": "unknown model"}}`, http.StatusNotFound)
+	})
+
+	_, err := c.Complete(context.Background(), ollama.NewChatRequest("no-such-model", "", "prompt", false))
+	var statusErr *ollama.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound || ollama.Retryable(err) {
+		t.Errorf("err = %v, want a non-retryable 404 *ollama.StatusError", err)
+	}
+	if n := len(s.requests()); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
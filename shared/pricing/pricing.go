@@ -0,0 +1,156 @@
+// Package pricing fills in BenchmarkResult.CostUSD from a JSON pricing
+// table, so reports show cost next to quality and latency.
+//
+// Hosted models are priced per token. Local models not in the table are
+// charged, if the table has a local section, for the time they ran: power
+// draw times electricity price, plus any amortized hardware cost. Any other
+// unpriced model, such as a hosted one reached with -provider openai, has an
+// unknown cost rather than the price of local electricity.
+//
+//	{
+//	  "models": {
+//	    "gpt-4o": {"input_per_mtok": 2.50, "output_per_mtok": 10.00}
+//	  },
+//	  "local": {"watts": 150, "usd_per_kwh": 0.17, "hardware_usd_per_hour": 0.05}
+//	}
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// FileName is the pricing table Find looks for.
+const FileName = "pricing.json"
+
+// Price is a hosted model's price in USD per million tokens.
+type Price struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// Local is the cost of running a model on local hardware.
+type Local struct {
+	// Watts is the machine's extra power draw while generating.
+	Watts     float64 `json:"watts"`
+	USDPerKWh float64 `json:"usd_per_kwh"`
+	// HardwareUSDPerHour amortizes the hardware's purchase price over its
+	// expected hours of use.
+	HardwareUSDPerHour float64 `json:"hardware_usd_per_hour"`
+}
+
+// PerHour returns the cost of one hour of generation.
+func (l Local) PerHour() float64 {
+	return l.Watts/1000*l.USDPerKWh + l.HardwareUSDPerHour
+}
+
+// Table is a pricing table. A nil *Table prices everything at zero.
+type Table struct {
+	Models map[string]Price `json:"models"`
+	Local  *Local           `json:"local,omitempty"`
+}
+
+// Find returns the first FileName in dir or one of its parents, or "" if
+// there is none.
+func Find(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the pricing table at path. An empty path looks for FileName
+// from dir upwards and returns a nil Table if there is none, so costs are
+// simply left at zero.
+func Load(path, dir string) (*Table, error) {
+	if path == "" {
+		if path = Find(dir); path == "" {
+			return nil, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing: %w", err)
+	}
+	var t Table
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Lookup returns the price for model. Names are matched exactly, then
+// ignoring case and the separators result file names rewrite, so
+// "gpt-4o" in a table also prices a result saved as "gpt_4o" and
+// "claude-sonnet-4-5" one run as "claude-sonnet-4.5".
+func (t *Table) Lookup(model string) (Price, bool) {
+	if t == nil {
+		return Price{}, false
+	}
+	if p, ok := t.Models[model]; ok {
+		return p, true
+	}
+	key := normalize(model)
+	for name, p := range t.Models {
+		if normalize(name) == key {
+			return p, true
+		}
+	}
+	return Price{}, false
+}
+
+// Cost returns the cost of one call and whether it is known: by token for a
+// priced model, by time for a local one. An unpriced model that is not local
+// may be a hosted API whose price the table cannot guess, so its cost is
+// unknown.
+func (t *Table) Cost(model string, local bool, tokensIn, tokensOut int, elapsed time.Duration) (float64, bool) {
+	if t == nil {
+		return 0, true
+	}
+	if p, ok := t.Lookup(model); ok {
+		return (float64(tokensIn)*p.InputPerMTok + float64(tokensOut)*p.OutputPerMTok) / 1e6, true
+	}
+	if !local {
+		return 0, false
+	}
+	if t.Local != nil {
+		return elapsed.Hours() * t.Local.PerHour(), true
+	}
+	return 0, true
+}
+
+// Apply sets CostUSD on every result to the cost of its typical call, from
+// the mean tokens and total time the row reports, and CostUnknown where there
+// is no price for it. Models are priced by their base name, ignoring any
+// variant label (see types.ModelLabel). A model counts as local when its row
+// carries the details Ollama reported at preflight, so results should go
+// through bench.WithModelInfo first.
+func (t *Table) Apply(results []types.BenchmarkResult) []types.BenchmarkResult {
+	for i := range results {
+		r := &results[i]
+		cost, ok := t.Cost(types.BaseModel(r.Model), r.ModelInfo != nil, r.TokensIn, r.TokensOut, r.TotalTime)
+		r.CostUSD, r.CostUnknown = cost, !ok
+	}
+	return results
+}
+
+func normalize(model string) string {
+	return strings.NewReplacer("/", "-", ":", "-", ".", "-", "_", "-", " ", "-").Replace(strings.ToLower(model))
+}
@@ -0,0 +1,44 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+func TestApply(t *testing.T) {
+	table := &Table{
+		Models: map[string]Price{"gpt-4o-mini": {InputPerMTok: 0.15, OutputPerMTok: 0.60}},
+		Local:  &Local{Watts: 200, USDPerKWh: 0.20, HardwareUSDPerHour: 0.01},
+	}
+	local := &types.ModelInfo{Name: "qwen3:4b", Family: "qwen3"}
+	results := table.Apply([]types.BenchmarkResult{
+		{Model: "gpt_4o_mini", TokensIn: 1000, TokensOut: 500},
+		{Model: "qwen3:4b [schema]", TotalTime: 30 * time.Minute, ModelInfo: local},
+		// Hosted through -provider openai: no preflight details, no price.
+		{Model: "gpt-4.1", TokensIn: 1000, TokensOut: 500, TotalTime: time.Minute},
+	})
+
+	if got, want := results[0].CostUSD, (1000*0.15+500*0.60)/1e6; !approx(got, want) || results[0].CostUnknown {
+		t.Errorf("priced: cost %v (unknown %v), want %v", got, results[0].CostUnknown, want)
+	}
+	// Half an hour at 0.2 kW and $0.20/kWh, plus $0.01/h of hardware.
+	if got, want := results[1].CostUSD, 0.5*(0.2*0.20+0.01); !approx(got, want) || results[1].CostUnknown {
+		t.Errorf("local: cost %v (unknown %v), want %v", got, results[1].CostUnknown, want)
+	}
+	if results[2].CostUSD != 0 || !results[2].CostUnknown {
+		t.Errorf("unpriced hosted: cost %v (unknown %v), want unknown", results[2].CostUSD, results[2].CostUnknown)
+	}
+
+	// Without a table nothing is priced, and nothing is unknown either.
+	var none *Table
+	if r := none.Apply([]types.BenchmarkResult{{Model: "gpt-4.1", TokensIn: 1000}})[0]; r.CostUSD != 0 || r.CostUnknown {
+		t.Errorf("nil table: cost %v (unknown %v), want 0", r.CostUSD, r.CostUnknown)
+	}
+}
+
+func approx(a, b float64) bool {
+	d := a - b
+	return d < 1e-12 && d > -1e-12
+}
@@ -0,0 +1,150 @@
+// Package provider selects the model backend an example talks to: a local
+// Ollama server, or any OpenAI-compatible /v1/chat/completions endpoint
+// (the OpenAI API, llama.cpp server, vLLM, LM Studio, a mock). Examples take
+// a Provider instead of a concrete client so the same cases can benchmark
+// local models and hosted baselines.
+package provider
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/cassette"
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/openai"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Provider sends chat requests to a model backend.
+type Provider interface {
+	// Complete sends req, retrying transient failures.
+	Complete(ctx context.Context, req ollama.ChatRequest) (ollama.ChatResponse, error)
+	// ChatCompletion sends a single-turn request built by
+	// ollama.NewChatRequest.
+	ChatCompletion(model, system, prompt string, jsonMode bool, maxTokens ...int) (string, types.ModelMetadata, error)
+}
+
+var (
+	_ Provider = (*ollama.Client)(nil)
+	_ Provider = (*openai.Client)(nil)
+)
+
+// Preflighter is implemented by providers that can check a model is
+// available before a run and describe it. ollama.Client implements it;
+// OpenAI-compatible servers have no standard way to describe a model.
+type Preflighter interface {
+	Preflight(ctx context.Context, model string, pull bool, w io.Writer) (types.ModelInfo, error)
+}
+
+var _ Preflighter = (*ollama.Client)(nil)
+
+// Preflight checks model with p's Preflight when p implements Preflighter,
+// writing any pull progress to w. Other providers are not checked and get
+// an info carrying only the name.
+func Preflight(ctx context.Context, p Provider, model string, pull bool, w io.Writer) (types.ModelInfo, error) {
+	if pf, ok := p.(Preflighter); ok {
+		return pf.Preflight(ctx, model, pull, w)
+	}
+	return types.ModelInfo{Name: model}, nil
+}
+
+// Embedder is implemented by providers that can embed text. ollama.Client
+// implements it with /api/embed.
+type Embedder interface {
+	Embed(ctx context.Context, model string, input []string) ([][]float64, types.ModelMetadata, error)
+}
+
+var _ Embedder = (*ollama.Client)(nil)
+
+// Names of the supported providers.
+const (
+	Ollama = "ollama"
+	OpenAI = "openai"
+)
+
+// Config describes the backend to connect to and how to call it.
+type Config struct {
+	Name    string
+	BaseURL string // empty selects the provider's default
+	// APIKey is sent to OpenAI-compatible servers. New reads it from
+	// OPENAI_API_KEY when it is empty, so keys stay out of shell history.
+	APIKey  string
+	Stream  bool
+	Retries int           // retries per call after a transient failure
+	Timeout time.Duration // per attempt; 0 for none
+	// Record and Replay name a cassette directory to save responses to or
+	// serve them from instead of calling the backend. At most one is set.
+	Record string
+	Replay string
+	// Pull downloads a missing model during Preflight instead of failing.
+	Pull bool
+}
+
+// AddFlags registers the connection flags shared by every example on fs:
+// -provider, -base-url, -stream, -retries, -timeout, -record, -replay and
+// -pull.
+func AddFlags(fs *flag.FlagSet) *Config {
+	c := &Config{}
+	fs.StringVar(&c.Name, "provider", Ollama, "Model backend: ollama, or openai for any OpenAI-compatible /v1/chat/completions server")
+	fs.StringVar(&c.BaseURL, "base-url", "", "Backend URL (default http://localhost:11434 for ollama, "+openai.DefaultBaseURL+" for openai)")
+	fs.BoolVar(&c.Stream, "stream", false, "Stream responses to measure wall-clock TTFT and inter-token latency")
+	fs.IntVar(&c.Retries, "retries", 2, "Retries per model call after a transient failure")
+	fs.DurationVar(&c.Timeout, "timeout", 0, "Per-attempt timeout for model calls (0 for none)")
+	fs.StringVar(&c.Record, "record", "", "Save every model response to this cassette directory")
+	fs.StringVar(&c.Replay, "replay", "", "Serve model responses from this cassette directory instead of the backend")
+	fs.BoolVar(&c.Pull, "pull", false, "Pull models that are not installed before running (ollama only)")
+	return c
+}
+
+// New returns the Provider c describes.
+func (c Config) New() (Provider, error) {
+	retry := ollama.DefaultRetryPolicy()
+	retry.MaxAttempts = c.Retries + 1
+	retry.AttemptTimeout = c.Timeout
+
+	transport, err := c.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Name {
+	case Ollama, "":
+		client := ollama.NewClient()
+		if c.BaseURL != "" {
+			client.BaseURL = c.BaseURL
+		}
+		client.Stream, client.Retry = c.Stream, retry
+		client.HTTPClient.Transport = transport
+		return client, nil
+	case OpenAI:
+		key := c.APIKey
+		if key == "" {
+			key = os.Getenv("OPENAI_API_KEY")
+		}
+		client := openai.NewClient(c.BaseURL, key)
+		client.Stream, client.Retry = c.Stream, retry
+		client.HTTPClient.Transport = transport
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want %s or %s)", c.Name, Ollama, OpenAI)
+	}
+}
+
+// transport returns the cassette transport selected by -record or -replay,
+// or nil for a direct connection.
+func (c Config) transport() (http.RoundTripper, error) {
+	switch {
+	case c.Record != "" && c.Replay != "":
+		return nil, fmt.Errorf("-record and -replay are mutually exclusive")
+	case c.Record != "":
+		return cassette.New(c.Record, cassette.Record)
+	case c.Replay != "":
+		return cassette.New(c.Replay, cassette.Replay)
+	}
+	return nil, nil
+}
@@ -0,0 +1,65 @@
+package repair
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/provider"
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Reply is the result of Complete.
+type Reply struct {
+	// Text is the recovered JSON, or the last raw reply if it could not be
+	// recovered.
+	Text string
+	// Raw is the last reply exactly as the model returned it.
+	Raw     string
+	Outcome Outcome
+	// Reasks is the number of follow-up turns sent.
+	Reasks int
+	// Meta covers every turn: token counts, times and retries are summed.
+	Meta types.ModelMetadata
+}
+
+// Complete sends req and recovers JSON from the reply. While the reply
+// cannot be recovered and fewer than reasks follow-ups have been sent, the
+// model is shown its reply and the parse error and asked to correct it. A
+// reply that needed a re-ask counts as Repaired, not Raw.
+func Complete(ctx context.Context, c provider.Provider, req ollama.ChatRequest, reasks int) (Reply, error) {
+	var reply Reply
+	for {
+		resp, err := c.Complete(ctx, req)
+		reply.Meta = types.SumMetadata(reply.Meta, resp.Meta)
+		if err != nil {
+			return reply, err
+		}
+		reply.Raw = resp.Message.Content
+		reply.Text, reply.Outcome = JSON(reply.Raw)
+		if reply.Outcome == Raw && reply.Reasks > 0 {
+			reply.Outcome = Repaired
+		}
+		if reply.Outcome != Invalid || reply.Reasks >= reasks {
+			return reply, nil
+		}
+
+		reply.Reasks++
+		req = Reask(req, reply.Raw)
+	}
+}
+
+// Reask returns req extended with the model's invalid reply and a user turn
+// quoting the parse error.
+func Reask(req ollama.ChatRequest, raw string) ollama.ChatRequest {
+	var v any
+	err := Unmarshal(raw, &v)
+
+	next := req
+	next.Messages = append(append([]ollama.Message(nil), req.Messages...),
+		ollama.Message{Role: "assistant", Content: raw},
+		ollama.Message{Role: "user", Content: fmt.Sprintf(
+			"Your reply was not valid JSON (%v). Reply again with only the corrected JSON, no prose or code fences.", err)},
+	)
+	return next
+}
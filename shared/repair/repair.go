@@ -0,0 +1,227 @@
+// Package repair recovers JSON from model output that is almost, but not
+// quite, valid: wrapped in markdown fences, preceded by a <think> block or
+// prose, cut off mid-array by the token cap, or left with trailing commas.
+//
+// JSON returns the recovered text along with an Outcome recording whether
+// repair was needed, so reports can show how much a model relies on it.
+package repair
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Outcome records how a model's output became parseable JSON.
+type Outcome string
+
+const (
+	// Raw output parsed as returned.
+	Raw Outcome = "raw"
+	// Repaired output parsed only after repair or a re-ask.
+	Repaired Outcome = "repaired"
+	// Invalid output could not be recovered.
+	Invalid Outcome = "invalid"
+)
+
+// Counts converts o to the JSONChecks, JSONRaw and JSONRepaired counts of a
+// types.BenchmarkResult. An empty Outcome (not recorded) counts nothing.
+func (o Outcome) Counts() (checks, raw, repaired int) {
+	switch o {
+	case Raw:
+		return 1, 1, 0
+	case Repaired:
+		return 1, 0, 1
+	case Invalid:
+		return 1, 0, 0
+	}
+	return 0, 0, 0
+}
+
+// JSON returns s as valid JSON together with how it got there. Output that
+// is already valid is returned unchanged. Otherwise JSON strips <think>
+// blocks and markdown fences, takes the first JSON object or array in what
+// remains, closes it if the output was truncated, and removes trailing
+// commas. If nothing valid can be recovered s is returned with Invalid.
+func JSON(s string) (string, Outcome) {
+	trimmed := strings.TrimSpace(s)
+	if json.Valid([]byte(trimmed)) {
+		return trimmed, Raw
+	}
+
+	text := stripFences(stripThink(trimmed))
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return s, Invalid
+	}
+	for _, candidate := range extract(text[start:]) {
+		candidate = removeTrailingCommas(candidate)
+		if json.Valid([]byte(candidate)) {
+			return candidate, Repaired
+		}
+	}
+	return s, Invalid
+}
+
+// Unmarshal decodes s into v after recovering it with JSON. The error is the
+// decoder's, so it describes what is still wrong with unrecoverable output.
+func Unmarshal(s string, v any) error {
+	text, _ := JSON(s)
+	return json.Unmarshal([]byte(text), v)
+}
+
+// stripThink removes reasoning blocks. An unclosed <think> drops everything
+// after it, and a stray </think> (some chat templates swallow the opening
+// tag) drops everything before it.
+func stripThink(s string) string {
+	for {
+		open := strings.Index(s, "<think>")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(s[open:], "</think>")
+		if end < 0 {
+			s = s[:open]
+			break
+		}
+		s = s[:open] + s[open+end+len("</think>"):]
+	}
+	if i := strings.LastIndex(s, "</think>"); i >= 0 {
+		s = s[i+len("</think>"):]
+	}
+	return strings.TrimSpace(s)
+}
+
+// stripFences returns the body of the first ``` fenced block in s, or s
+// unchanged if there is none. A fence left open by truncation runs to the
+// end of s.
+func stripFences(s string) string {
+	open := strings.Index(s, "```")
+	if open < 0 {
+		return s
+	}
+	body := s[open+3:]
+	// Skip the info string (```json).
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:]
+	} else {
+		return s
+	}
+	if end := strings.Index(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
+
+// extract scans the JSON value starting at s[0] and returns candidate
+// repairs in order of preference. A balanced value is the only candidate.
+// A truncated one yields the text closed where it stops, then the text cut
+// back to the last complete element and closed there.
+func extract(s string) []string {
+	var (
+		stack    []byte // expected closers
+		inString bool
+		escaped  bool
+		cut      = -1 // index of the last comma outside a string
+		cutStack []byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				// Mismatched closer: the value ends in a mess, so only the
+				// cut-back repair can help.
+				return truncated(s[:i], nil, false, cut, cutStack)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return []string{s[:i+1]}
+			}
+		case ',':
+			cut = i
+			cutStack = append(cutStack[:0], stack...)
+		}
+	}
+	return truncated(s, stack, inString, cut, cutStack)
+}
+
+// truncated builds the candidate repairs for a value that never closed.
+func truncated(s string, stack []byte, inString bool, cut int, cutStack []byte) []string {
+	var candidates []string
+	if stack != nil && !inString {
+		candidates = append(candidates, closeValue(s, stack))
+	}
+	if cut >= 0 {
+		candidates = append(candidates, closeValue(s[:cut], cutStack))
+	}
+	if stack != nil && inString {
+		// Last resort: keep the partial string.
+		candidates = append(candidates, closeValue(s+`"`, stack))
+	}
+	return candidates
+}
+
+// closeValue appends the closers in stack (innermost last) to s, dropping a
+// dangling comma first.
+func closeValue(s string, stack []byte) string {
+	s = strings.TrimRight(strings.TrimSpace(s), ",")
+	var sb strings.Builder
+	sb.WriteString(s)
+	for i := len(stack) - 1; i >= 0; i-- {
+		sb.WriteByte(stack[i])
+	}
+	return sb.String()
+}
+
+// removeTrailingCommas drops commas that directly precede a closing brace or
+// bracket, ignoring string contents.
+func removeTrailingCommas(s string) string {
+	var sb strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			sb.WriteByte(c)
+			continue
+		}
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			j := i + 1
+			for j < len(s) && strings.IndexByte(" \t\r\n", s[j]) >= 0 {
+				j++
+			}
+			if j < len(s) && (s[j] == '}' || s[j] == ']') {
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,133 @@
+package repair
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/statherm/local-llm-examples/shared/ollama"
+	"github.com/statherm/local-llm-examples/shared/ollama/ollamatest"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		outcome Outcome
+	}{
+		{"valid", "  {\"a\": 1}\n", `{"a": 1}`, Raw},
+		{"fenced", "```json\n{\"a\": 1}\n```", `{"a": 1}`, Repaired},
+		{"think", "<think>maybe {\"a\": 0}?</think>\n{\"a\": 1}", `{"a": 1}`, Repaired},
+		{"stray think close", `planning {"a": 0}</think>{"a": 1}`, `{"a": 1}`, Repaired},
+		{"prose around", `Here you go: {"a": "x}"} Hope that helps.`, `{"a": "x}"}`, Repaired},
+		{"escaped quote", `{"q": "say \"hi}\"", "n": 1,}`, `{"q": "say \"hi}\"", "n": 1}`, Repaired},
+		// Commas and braces inside strings are left alone.
+		{"trailing commas", `{"a": "b, }", "c": [1, 2,],}`, `{"a": "b, }", "c": [1, 2]}`, Repaired},
+		{"truncated array", `[{"id": 1}, {"id": 2}, {"id"`, `[{"id": 1}, {"id": 2}]`, Repaired},
+		{"truncated string", `{"summary": "cut off mid-sen`, `{"summary": "cut off mid-sen"}`, Repaired},
+		{"nested truncation", `{"items": [{"tags": ["a", "b"`, `{"items": [{"tags": ["a", "b"]}]}`, Repaired},
+		// Cut inside a key, so only the text up to the last complete
+		// element can be closed.
+		{"nested truncation in key", `{"items": [{"id": 1, "tags": ["a"]}, {"id": 2, "ta`, `{"items": [{"id": 1, "tags": ["a"]}, {"id": 2}]}`, Repaired},
+		{"truncated fence", "```json\n[1, 2, 3", `[1, 2, 3]`, Repaired},
+		{"mismatched closer", `{"a": [1, 2}`, `{"a": [1]}`, Repaired},
+		{"no json", "I cannot help with that.", "I cannot help with that.", Invalid},
+		{"bare word", `{"a": tru`, `{"a": tru`, Invalid},
+		{"empty", "", "", Invalid},
+	}
+	for _, tt := range tests {
+		got, outcome := JSON(tt.in)
+		if got != tt.want || outcome != tt.outcome {
+			t.Errorf("%s: JSON = %q, %s; want %q, %s", tt.name, got, outcome, tt.want, tt.outcome)
+		}
+	}
+}
+
+func TestCloseValue(t *testing.T) {
+	tests := []struct {
+		s, stack, want string
+	}{
+		{`[1, 2, `, "]", `[1, 2]`},
+		{`{"a": {"b": [1`, "}}]", `{"a": {"b": [1]}}`},
+		{`{"a": 1`, "", `{"a": 1`},
+	}
+	for _, tt := range tests {
+		if got := closeValue(tt.s, []byte(tt.stack)); got != tt.want {
+			t.Errorf("closeValue(%q, %q) = %q, want %q", tt.s, tt.stack, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveTrailingCommas(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`[1, 2, ]`, `[1, 2 ]`},
+		{"{\"a\": \",}\", \"b\": [1,\n],}", "{\"a\": \",}\", \"b\": [1\n]}"},
+		{`["a\\",]`, `["a\\"]`},
+		{`{"a": [1, 2]}`, `{"a": [1, 2]}`},
+	}
+	for _, tt := range tests {
+		if got := removeTrailingCommas(tt.in); got != tt.want {
+			t.Errorf("removeTrailingCommas(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteReask(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(
+		ollamatest.Reply{Content: `{"label": bug}`, OutputTokens: 4},
+		ollamatest.Reply{Content: `{"label": "bug"}`, OutputTokens: 5},
+	)
+
+	req := ollama.NewChatRequest("qwen3:4b", "", "classify", true)
+	reply, err := Complete(context.Background(), srv.Client(), req, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Text != `{"label": "bug"}` || reply.Outcome != Repaired || reply.Reasks != 1 {
+		t.Errorf("reply = %q, %s after %d re-asks; want the corrected JSON, repaired after 1", reply.Text, reply.Outcome, reply.Reasks)
+	}
+	if reply.Meta.TokensOut != 9 {
+		t.Errorf("TokensOut = %d, want both turns' 9", reply.Meta.TokensOut)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("%d requests, want 2", len(reqs))
+	}
+	msgs := reqs[1].Messages
+	if len(msgs) != len(reqs[0].Messages)+2 {
+		t.Fatalf("re-ask messages = %+v, want the first request's plus two", msgs)
+	}
+	if last := msgs[len(msgs)-2]; last.Role != "assistant" || last.Content != `{"label": bug}` {
+		t.Errorf("re-ask quotes %+v, want the invalid reply", last)
+	}
+	if last := msgs[len(msgs)-1]; last.Role != "user" || !strings.Contains(last.Content, "invalid character 'b'") {
+		t.Errorf("re-ask prompt %q does not carry the parse error", last.Content)
+	}
+}
+
+func TestCompleteReaskLimit(t *testing.T) {
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+	srv.Default = ollamatest.Reply{Content: "Sorry, no JSON today."}
+
+	req := ollama.NewChatRequest("qwen3:4b", "", "classify", true)
+	for _, reasks := range []int{0, 2} {
+		before := len(srv.Requests())
+		reply, err := Complete(context.Background(), srv.Client(), req, reasks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Outcome != Invalid || reply.Reasks != reasks || reply.Text != "Sorry, no JSON today." {
+			t.Errorf("reasks %d: reply = %q, %s after %d re-asks", reasks, reply.Text, reply.Outcome, reply.Reasks)
+		}
+		if n := len(srv.Requests()) - before; n != reasks+1 {
+			t.Errorf("reasks %d: %d requests, want %d", reasks, n, reasks+1)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package reporting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// modelSummary aggregates every result row for one model.
+type modelSummary struct {
+	Model        string
+	Cases        int
+	Quality      float64 // mean
+	Timed        int     // rows with timings, which latency and tokens/sec average
+	Latency      time.Duration
+	TokensPerSec float64
+	SchemaChecks int
+	SchemaValid  int
+	CostUSD      float64 // mean per call
+	CostUnknown  bool    // some row had no price, so the mean is unknown
+}
+
+// GenerateComparison produces a Markdown table comparing models across the
+// same set of results, one row per model. Quality, latency (TotalTime),
+// tokens/sec and cost per call are averaged over each model's rows. Models
+// are ranked by quality, then lower latency, then higher tokens/sec; the
+// latency and tokens/sec columns also show each model's rank on that metric
+// alone. Rows whose call failed count toward quality but have no timings
+// to average.
+func GenerateComparison(results []types.BenchmarkResult) string {
+	if len(results) == 0 {
+		return "_No results._\n"
+	}
+
+	byModel := make(map[string]*modelSummary)
+	var order []string
+	for _, r := range results {
+		s, ok := byModel[r.Model]
+		if !ok {
+			s = &modelSummary{Model: r.Model}
+			byModel[r.Model] = s
+			order = append(order, r.Model)
+		}
+		s.Cases++
+		s.Quality += r.Quality
+		if r.TotalTime > 0 {
+			s.Timed++
+			s.Latency += r.TotalTime
+			s.TokensPerSec += r.TokensPerSec
+		}
+		s.SchemaChecks += r.SchemaChecks
+		s.SchemaValid += r.SchemaValid
+		s.CostUSD += r.CostUSD
+		s.CostUnknown = s.CostUnknown || r.CostUnknown
+	}
+
+	summaries := make([]*modelSummary, 0, len(order))
+	for _, m := range order {
+		s := byModel[m]
+		n := float64(s.Cases)
+		s.Quality /= n
+		if s.Timed > 0 {
+			s.Latency /= time.Duration(s.Timed)
+			s.TokensPerSec /= float64(s.Timed)
+		}
+		s.CostUSD /= n
+		summaries = append(summaries, s)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Quality != b.Quality {
+			return a.Quality > b.Quality
+		}
+		if a.Latency != b.Latency {
+			return a.Latency < b.Latency
+		}
+		return a.TokensPerSec > b.TokensPerSec
+	})
+
+	latencyRank := rank(summaries, func(a, b *modelSummary) bool { return a.Latency < b.Latency })
+	speedRank := rank(summaries, func(a, b *modelSummary) bool { return a.TokensPerSec > b.TokensPerSec })
+
+	var sb strings.Builder
+
+	sb.WriteString("## Model Comparison\n\n")
+	sb.WriteString("| Rank | Model | Quality | Schema Valid | Latency | Tok/s | Cases | Cost/Call |\n")
+	sb.WriteString("|------|-------|---------|--------------|---------|-------|-------|-----------|\n")
+
+	for i, s := range summaries {
+		schemaStr := "-"
+		if s.SchemaChecks > 0 {
+			schemaStr = fmt.Sprintf("%.1f%%", float64(s.SchemaValid)/float64(s.SchemaChecks)*100)
+		}
+		costStr := formatCost(s.CostUSD, s.CostUnknown)
+
+		sb.WriteString(fmt.Sprintf("| %d | %s | %.1f%% | %s | %.2fs (#%d) | %.1f (#%d) | %d | %s |\n",
+			i+1, s.Model, s.Quality*100, schemaStr,
+			s.Latency.Seconds(), latencyRank[s.Model],
+			s.TokensPerSec, speedRank[s.Model],
+			s.Cases, costStr,
+		))
+	}
+
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// rank returns each model's 1-based position when ordered by better. Ties
+// share the better rank.
+func rank(summaries []*modelSummary, better func(a, b *modelSummary) bool) map[string]int {
+	sorted := append([]*modelSummary(nil), summaries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return better(sorted[i], sorted[j]) })
+
+	ranks := make(map[string]int, len(sorted))
+	for i, s := range sorted {
+		if i > 0 && !better(sorted[i-1], s) {
+			ranks[s.Model] = ranks[sorted[i-1].Model]
+			continue
+		}
+		ranks[s.Model] = i + 1
+	}
+	return ranks
+}
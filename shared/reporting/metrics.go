@@ -0,0 +1,50 @@
+package reporting
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MetricRow is one case's values for the columns of GenerateMetrics.
+type MetricRow struct {
+	Model  string
+	Values []float64
+}
+
+// GenerateMetrics produces a Markdown table of metrics an example computes
+// beyond its Quality column, one column per metric. Rows are averaged per
+// model, in the order models first appear. Columns for one metric at
+// increasing cutoffs (P@1, P@3, ...) read along a row as the model's curve.
+func GenerateMetrics(title string, columns []string, rows []MetricRow) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	sums := make(map[string][]float64)
+	counts := make(map[string]int)
+	var order []string
+	for _, r := range rows {
+		if _, ok := sums[r.Model]; !ok {
+			sums[r.Model] = make([]float64, len(columns))
+			order = append(order, r.Model)
+		}
+		for i := 0; i < len(columns) && i < len(r.Values); i++ {
+			sums[r.Model][i] += r.Values[i]
+		}
+		counts[r.Model]++
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## %s\n\n", title))
+	sb.WriteString("| Model | " + strings.Join(columns, " | ") + " |\n")
+	sb.WriteString("|-------|" + strings.Repeat("------|", len(columns)) + "\n")
+	for _, model := range order {
+		cells := make([]string, len(columns))
+		for i, sum := range sums[model] {
+			cells[i] = fmt.Sprintf("%.3f", sum/float64(counts[model]))
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s |\n", model, strings.Join(cells, " | ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
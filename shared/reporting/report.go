@@ -16,26 +16,120 @@ func GenerateReport(results []types.BenchmarkResult) string {
 	var sb strings.Builder
 
 	sb.WriteString("## Benchmark Results\n\n")
-	sb.WriteString("| Model | Quality | Metric | Tokens In | Tokens Out | Tok/s | TTFT | Total | Cost |\n")
-	sb.WriteString("|-------|---------|--------|-----------|------------|-------|------|-------|------|\n")
+	sb.WriteString("| Model | Quality | Metric | Schema Valid | Raw JSON | Repaired JSON | Tokens In | Tokens Out | Tok/s | TTFT | ITL | Total | Cost/Call |\n")
+	sb.WriteString("|-------|---------|--------|--------------|----------|---------------|-----------|------------|-------|------|-----|-------|-----------|\n")
 
 	for _, r := range results {
 		qualityStr := fmt.Sprintf("%.1f%%", r.Quality*100)
+		schemaStr := "-"
+		if r.SchemaChecks > 0 {
+			schemaStr = fmt.Sprintf("%.1f%%", r.SchemaValidRate()*100)
+		}
+		// Parse rates before and after shared/repair, for JSON outputs only.
+		rawStr, repairedStr := "-", "-"
+		if r.JSONChecks > 0 {
+			rawStr = fmt.Sprintf("%.1f%%", r.RawJSONRate()*100)
+			repairedStr = fmt.Sprintf("%.1f%%", r.RepairedJSONRate()*100)
+		}
 		ttftStr := fmt.Sprintf("%.0fms", r.TTFT.Seconds()*1000)
 		totalStr := fmt.Sprintf("%.2fs", r.TotalTime.Seconds())
 		tokSecStr := fmt.Sprintf("%.1f", r.TokensPerSec)
-		costStr := "$0.00"
-		if r.CostUSD > 0 {
-			costStr = fmt.Sprintf("$%.4f", r.CostUSD)
+		// Inter-token latency is only measured for streamed runs.
+		itlStr := "-"
+		if r.InterTokenLatency > 0 {
+			itlStr = fmt.Sprintf("%.1fms", r.InterTokenLatency.Seconds()*1000)
 		}
+		costStr := formatCost(r.CostUSD, r.CostUnknown)
 
-		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %d | %s | %s | %s | %s |\n",
-			r.Model, qualityStr, r.QualityName,
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %d | %d | %s | %s | %s | %s | %s |\n",
+			r.Model, qualityStr, r.QualityName, schemaStr, rawStr, repairedStr,
 			r.TokensIn, r.TokensOut,
-			tokSecStr, ttftStr, totalStr, costStr,
+			tokSecStr, ttftStr, itlStr, totalStr, costStr,
 		))
 	}
 
 	sb.WriteString("\n")
+	sb.WriteString(retryNote(results))
+	sb.WriteString(errorNote(results))
+	sb.WriteString(callsNote(results))
+	sb.WriteString(modelNote(results))
 	return sb.String()
 }
+
+// formatCost renders a per-call cost, or "-" if it is unknown. Local calls
+// cost fractions of a cent, so small amounts keep six decimal places.
+func formatCost(usd float64, unknown bool) string {
+	switch {
+	case unknown:
+		return "-"
+	case usd == 0:
+		return "$0.00"
+	case usd < 0.01:
+		return fmt.Sprintf("$%.6f", usd)
+	default:
+		return fmt.Sprintf("$%.4f", usd)
+	}
+}
+
+// retryNote lists the rows whose calls needed retries, so a run that only
+// succeeded after transient failures is distinguishable from a clean one.
+func retryNote(results []types.BenchmarkResult) string {
+	var rows []string
+	for _, r := range results {
+		if r.Retries > 0 {
+			rows = append(rows, fmt.Sprintf("%s %s (%d)", r.Model, r.Example, r.Retries))
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_Retried calls: %s._\n\n", strings.Join(rows, ", "))
+}
+
+// errorNote lists the rows whose call failed, so a zero-quality row from a
+// failed call is distinguishable from a bad answer.
+func errorNote(results []types.BenchmarkResult) string {
+	var rows []string
+	for _, r := range results {
+		if r.Error != "" {
+			rows = append(rows, fmt.Sprintf("%s %s (%s)", r.Model, r.Example, r.Error))
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_Failed calls: %s._\n\n", strings.Join(rows, "; "))
+}
+
+// callsNote lists the rows whose cases took more than one model call, since
+// their token counts, latency and cost cover all of them.
+func callsNote(results []types.BenchmarkResult) string {
+	var rows []string
+	for _, r := range results {
+		if r.Calls > 1 {
+			rows = append(rows, fmt.Sprintf("%s %s (%.0f)", r.Model, r.Example, r.Calls))
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_Model calls per case: %s._\n\n", strings.Join(rows, ", "))
+}
+
+// modelNote describes each model whose details were recorded at preflight,
+// so rows can be told apart by quantization and size.
+func modelNote(results []types.BenchmarkResult) string {
+	var models []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if r.ModelInfo == nil || seen[r.Model] {
+			continue
+		}
+		seen[r.Model] = true
+		models = append(models, fmt.Sprintf("%s (%s)", r.Model, r.ModelInfo.Summary()))
+	}
+	if len(models) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("_Models: %s._\n\n", strings.Join(models, "; "))
+}
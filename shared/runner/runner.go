@@ -0,0 +1,126 @@
+// Package runner executes benchmark cases with bounded concurrency.
+//
+// Results are always returned in input order, so result files stay
+// deterministic regardless of how many requests run in parallel. Each result
+// records how long the case waited for a free worker (queue time) separately
+// from how long it took to execute (service time); comparing the two across
+// -parallel settings shows how far Ollama's throughput scales.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Timing separates the time a case spent waiting for a worker from the time
+// spent executing it.
+type Timing struct {
+	Queue   time.Duration `json:"queue"`
+	Service time.Duration `json:"service"`
+}
+
+// Result is the outcome of one case.
+type Result[R any] struct {
+	Index int
+	Value R
+	Err   error
+	Timing
+}
+
+// Summary aggregates a run for throughput reporting.
+type Summary struct {
+	Requests    int
+	Errors      int
+	Parallel    int
+	Wall        time.Duration // elapsed time for the whole run
+	MeanQueue   time.Duration
+	MeanService time.Duration
+}
+
+// Throughput returns completed requests per second of wall time.
+func (s Summary) Throughput() float64 {
+	if s.Wall <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / s.Wall.Seconds()
+}
+
+// TokensPerSec returns aggregate generation throughput for a run that
+// produced tokensOut output tokens in total.
+func (s Summary) TokensPerSec(tokensOut int) float64 {
+	if s.Wall <= 0 {
+		return 0
+	}
+	return float64(tokensOut) / s.Wall.Seconds()
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d requests (%d errors), parallel=%d: wall %.1fs, %.2f req/s, mean queue %.2fs, mean service %.2fs",
+		s.Requests, s.Errors, s.Parallel, s.Wall.Seconds(), s.Throughput(),
+		s.MeanQueue.Seconds(), s.MeanService.Seconds())
+}
+
+// Run calls fn for every item using at most parallel concurrent workers and
+// returns one Result per item, in input order. A parallel value below 1 is
+// treated as 1 (sequential). Queue time is measured from the start of the
+// run, so with parallel=1 it grows with each case's position.
+//
+// Once ctx is cancelled, cases that have not started are skipped and their
+// Result carries ctx.Err().
+func Run[T, R any](ctx context.Context, items []T, parallel int, fn func(ctx context.Context, i int, item T) (R, error)) ([]Result[R], Summary) {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]Result[R], len(items))
+	start := time.Now()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				picked := time.Now()
+				res := Result[R]{Index: i}
+				res.Queue = picked.Sub(start)
+				if err := ctx.Err(); err != nil {
+					res.Err = err
+				} else {
+					res.Value, res.Err = fn(ctx, i, items[i])
+				}
+				res.Service = time.Since(picked)
+				results[i] = res
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, summarize(results, parallel, time.Since(start))
+}
+
+func summarize[R any](results []Result[R], parallel int, wall time.Duration) Summary {
+	s := Summary{Requests: len(results), Parallel: parallel, Wall: wall}
+	if len(results) == 0 {
+		return s
+	}
+	var queue, service time.Duration
+	for _, r := range results {
+		if r.Err != nil {
+			s.Errors++
+		}
+		queue += r.Queue
+		service += r.Service
+	}
+	s.MeanQueue = queue / time.Duration(len(results))
+	s.MeanService = service / time.Duration(len(results))
+	return s
+}
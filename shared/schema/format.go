@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"net/mail"
+	"net/url"
+	"time"
+)
+
+// FormatMatches reports whether s is valid for the JSON Schema format name:
+//
+//   - date: a full date, 2006-01-02
+//   - date-time: an RFC 3339 timestamp
+//   - time: a time of day, 15:04:05 with an optional UTC offset
+//   - email: a bare address, without a display name
+//   - uri: an absolute URI with a scheme
+//
+// As in JSON Schema, other format names are annotations and match any
+// string.
+func FormatMatches(s, format string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "time":
+		for _, layout := range []string{"15:04:05Z07:00", time.TimeOnly} {
+			if _, err := time.Parse(layout, s); err == nil {
+				return true
+			}
+		}
+		return false
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	default:
+		return true
+	}
+}
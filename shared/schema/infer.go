@@ -0,0 +1,82 @@
+package schema
+
+import "sort"
+
+// Infer derives a structural schema from an example value, such as an
+// expected output fixture. Objects require every key present in the example,
+// arrays take their item schema from the union of their elements, and
+// numbers are typed as "number" so integral examples still accept decimals.
+// Value constraints (enum, pattern, ranges) are never inferred.
+func Infer(v any) *Schema {
+	switch val := v.(type) {
+	case map[string]any:
+		s := &Schema{Type: TypeList{"object"}, Properties: make(map[string]*Schema)}
+		for k, child := range val {
+			s.Properties[k] = Infer(child)
+			s.Required = append(s.Required, k)
+		}
+		sort.Strings(s.Required)
+		return s
+
+	case []any:
+		s := &Schema{Type: TypeList{"array"}}
+		for _, item := range val {
+			s.Items = merge(s.Items, Infer(item))
+		}
+		return s
+
+	case nil:
+		// A null in the example says nothing about the type of a real value.
+		return &Schema{}
+
+	default:
+		t := TypeOf(v)
+		if t == "integer" {
+			t = "number"
+		}
+		return &Schema{Type: TypeList{t}}
+	}
+}
+
+// merge combines two inferred schemas for elements of the same array. Object
+// properties are unioned and only keys required by both stay required;
+// conflicting types widen to an unconstrained schema.
+func merge(a, b *Schema) *Schema {
+	if a == nil {
+		return b
+	}
+	if len(a.Type) == 0 || len(b.Type) == 0 || a.Type[0] != b.Type[0] {
+		return &Schema{}
+	}
+
+	switch a.Type[0] {
+	case "object":
+		out := &Schema{Type: a.Type, Properties: make(map[string]*Schema)}
+		for k, p := range a.Properties {
+			out.Properties[k] = p
+		}
+		for k, p := range b.Properties {
+			out.Properties[k] = merge(out.Properties[k], p)
+		}
+		inB := make(map[string]bool, len(b.Required))
+		for _, r := range b.Required {
+			inB[r] = true
+		}
+		for _, r := range a.Required {
+			if inB[r] {
+				out.Required = append(out.Required, r)
+			}
+		}
+		return out
+
+	case "array":
+		out := &Schema{Type: a.Type, Items: a.Items}
+		if b.Items != nil {
+			out.Items = merge(a.Items, b.Items)
+		}
+		return out
+
+	default:
+		return a
+	}
+}
@@ -0,0 +1,357 @@
+// Package schema validates decoded JSON values against a JSON Schema.
+//
+// It implements the subset of JSON Schema used by the examples: type,
+// properties, required, additionalProperties, items, enum, const, pattern,
+// format (see FormatMatches), minLength/maxLength, minimum/maximum and
+// minItems/maxItems. Violations are
+// reported with a JSON path so a model's output can be debugged field by field.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a parsed JSON Schema document.
+type Schema struct {
+	Type        TypeList           `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties is either a bool or a schema; see Additional.
+	AdditionalProperties json.RawMessage `json:"additionalProperties,omitempty"`
+	Items                *Schema         `json:"items,omitempty"`
+	Enum                 []any           `json:"enum,omitempty"`
+	Const                any             `json:"const,omitempty"`
+	Pattern              string          `json:"pattern,omitempty"`
+	Format               string          `json:"format,omitempty"`
+	MinLength            *int            `json:"minLength,omitempty"`
+	MaxLength            *int            `json:"maxLength,omitempty"`
+	Minimum              *float64        `json:"minimum,omitempty"`
+	Maximum              *float64        `json:"maximum,omitempty"`
+	MinItems             *int            `json:"minItems,omitempty"`
+	MaxItems             *int            `json:"maxItems,omitempty"`
+
+	pattern    *regexp.Regexp
+	additional *Schema
+	closed     bool // additionalProperties: false
+}
+
+// TypeList holds the "type" keyword, which may be a single type name or an
+// array of names.
+type TypeList []string
+
+// UnmarshalJSON accepts both "string" and ["string", "null"].
+func (t *TypeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = TypeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or array of strings: %w", err)
+	}
+	*t = many
+	return nil
+}
+
+// MarshalJSON writes a single type as a plain string.
+func (t TypeList) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Violation describes one way a value fails to satisfy a schema.
+type Violation struct {
+	Path    string `json:"path"` // JSON path, e.g. $.line_items[2].amount
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Parse decodes and compiles a JSON Schema document.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if err := s.compile("$"); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Load reads and parses a JSON Schema file.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema %s: %w", path, err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for schemas
+// declared as constants in example code.
+func MustParse(data string) *Schema {
+	s, err := Parse([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// compile prepares regular expressions and additionalProperties for s and
+// all nested schemas.
+func (s *Schema) compile(path string) error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", path, s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	if len(s.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(s.AdditionalProperties, &allowed); err == nil {
+			s.closed = !allowed
+		} else {
+			var sub Schema
+			if err := json.Unmarshal(s.AdditionalProperties, &sub); err != nil {
+				return fmt.Errorf("%s: additionalProperties must be a bool or schema: %w", path, err)
+			}
+			if err := sub.compile(path + ".*"); err != nil {
+				return err
+			}
+			s.additional = &sub
+		}
+	}
+
+	for name, p := range s.Properties {
+		if err := p.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a value decoded by encoding/json (map[string]any, []any,
+// string, float64, bool or nil) against s and returns every violation found.
+// A nil result means the value is valid.
+func (s *Schema) Validate(v any) []Violation {
+	return s.validate(v, "$")
+}
+
+// ValidateJSON decodes data and validates it. Malformed JSON is reported as a
+// single violation at the root path.
+func (s *Schema) ValidateJSON(data []byte) []Violation {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []Violation{{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	return s.Validate(v)
+}
+
+// Valid reports whether v satisfies s.
+func (s *Schema) Valid(v any) bool {
+	return len(s.Validate(v)) == 0
+}
+
+func (s *Schema) validate(v any, path string) []Violation {
+	if len(s.Type) > 0 && !s.typeMatches(v) {
+		return []Violation{{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.Type, " or "), TypeOf(v))}}
+	}
+
+	var out []Violation
+	fail := func(format string, args ...any) {
+		out = append(out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Enum) > 0 && !containsValue(s.Enum, v) {
+		fail("%s is not one of %s", formatValue(v), formatValues(s.Enum))
+	}
+	if s.Const != nil && !equalValues(s.Const, v) {
+		fail("%s does not equal %s", formatValue(v), formatValue(s.Const))
+	}
+
+	switch val := v.(type) {
+	case string:
+		n := len([]rune(val))
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("length %d is less than minLength %d", n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("length %d is greater than maxLength %d", n, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			fail("%q does not match pattern %q", val, s.Pattern)
+		}
+		if s.Format != "" && !FormatMatches(val, s.Format) {
+			fail("%q is not a valid %s", val, s.Format)
+		}
+
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			fail("%v is less than minimum %v", val, *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			fail("%v is greater than maximum %v", val, *s.Maximum)
+		}
+
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			fail("%d items is less than minItems %d", len(val), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			fail("%d items is greater than maxItems %d", len(val), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				out = append(out, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		// Visit keys in sorted order so violations are reported deterministically.
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "." + k
+			if p, ok := s.Properties[k]; ok {
+				out = append(out, p.validate(val[k], child)...)
+			} else if s.additional != nil {
+				out = append(out, s.additional.validate(val[k], child)...)
+			} else if s.closed {
+				out = append(out, Violation{Path: child, Message: "additional property not allowed"})
+			}
+		}
+	}
+
+	return out
+}
+
+func (s *Schema) typeMatches(v any) bool {
+	for _, t := range s.Type {
+		if TypeMatches(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeMatches reports whether a decoded JSON value has the given JSON Schema
+// type. Unknown type names match any value.
+func TypeMatches(v any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && isInteger(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	default:
+		return true
+	}
+}
+
+// isInteger reports whether f has no fractional part. Converting through
+// int64 instead would overflow for magnitudes of 2^63 and above.
+func isInteger(f float64) bool {
+	return !math.IsInf(f, 0) && f == math.Trunc(f)
+}
+
+// TypeOf returns the JSON Schema type name of a decoded JSON value.
+func TypeOf(v any) string {
+	switch val := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if isInteger(val) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func containsValue(list []any, v any) bool {
+	for _, e := range list {
+		if equalValues(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares two decoded JSON values structurally.
+func equalValues(a, b any) bool {
+	aj, err1 := json.Marshal(a)
+	bj, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(aj) == string(bj)
+}
+
+func formatValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func formatValues(vs []any) string {
+	parts := make([]string, len(vs))
+	for i, v := range vs {
+		parts[i] = formatValue(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,212 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+const invoiceSchema = `{
+	"type": "object",
+	"properties": {
+		"vendor": {"type": "string", "minLength": 1},
+		"status": {"type": "string", "enum": ["paid", "due"]},
+		"issued": {"type": "string", "format": "date"},
+		"total": {"type": "number", "minimum": 0},
+		"line_items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"properties": {
+					"sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+					"quantity": {"type": "integer"}
+				},
+				"required": ["sku", "quantity"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["vendor", "total"]
+}`
+
+func TestValidateJSON(t *testing.T) {
+	s := MustParse(invoiceSchema)
+	tests := []struct {
+		name string
+		data string
+		want []Violation
+	}{
+		{
+			name: "valid",
+			data: `{"vendor": "Acme", "status": "paid", "issued": "2024-03-01", "total": 12.5,
+				"line_items": [{"sku": "ABC-1", "quantity": 2}]}`,
+		},
+		{
+			name: "malformed",
+			data: `{"vendor": "Acme",`,
+			want: []Violation{{Path: "$", Message: "invalid JSON: unexpected end of JSON input"}},
+		},
+		{
+			name: "root type",
+			data: `[]`,
+			want: []Violation{{Path: "$", Message: "expected object, got array"}},
+		},
+		{
+			name: "missing required",
+			data: `{"vendor": "Acme"}`,
+			want: []Violation{{Path: "$", Message: `missing required property "total"`}},
+		},
+		{
+			name: "wrong type",
+			data: `{"vendor": "Acme", "total": "12.50"}`,
+			want: []Violation{{Path: "$.total", Message: "expected number, got string"}},
+		},
+		{
+			name: "enum",
+			data: `{"vendor": "Acme", "total": 1, "status": "overdue"}`,
+			want: []Violation{{Path: "$.status", Message: `"overdue" is not one of ["paid", "due"]`}},
+		},
+		{
+			name: "format",
+			data: `{"vendor": "Acme", "total": 1, "issued": "03/01/2024"}`,
+			want: []Violation{{Path: "$.issued", Message: `"03/01/2024" is not a valid date`}},
+		},
+		{
+			name: "minimum and minLength",
+			data: `{"vendor": "", "total": -1}`,
+			want: []Violation{
+				{Path: "$.total", Message: "-1 is less than minimum 0"},
+				{Path: "$.vendor", Message: "length 0 is less than minLength 1"},
+			},
+		},
+		{
+			name: "nested array items",
+			data: `{"vendor": "Acme", "total": 1, "line_items": [
+				{"sku": "ABC-1", "quantity": 1},
+				{"sku": "abc", "quantity": 1.5},
+				{"quantity": 3}
+			]}`,
+			want: []Violation{
+				{Path: "$.line_items[1].quantity", Message: "expected integer, got number"},
+				{Path: "$.line_items[1].sku", Message: `"abc" does not match pattern "^[A-Z]{3}-[0-9]+$"`},
+				{Path: "$.line_items[2]", Message: `missing required property "sku"`},
+			},
+		},
+		{
+			name: "minItems",
+			data: `{"vendor": "Acme", "total": 1, "line_items": []}`,
+			want: []Violation{{Path: "$.line_items", Message: "0 items is less than minItems 1"}},
+		},
+		{
+			name: "additional property",
+			data: `{"vendor": "Acme", "total": 1, "line_items": [{"sku": "ABC-1", "quantity": 1, "note": "x"}]}`,
+			want: []Violation{{Path: "$.line_items[0].note", Message: "additional property not allowed"}},
+		},
+		{
+			name: "additional property open by default",
+			data: `{"vendor": "Acme", "total": 1, "currency": "USD"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.ValidateJSON([]byte(tt.data))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdditionalPropertiesSchema(t *testing.T) {
+	s := MustParse(`{"type": "object", "additionalProperties": {"type": "number"}}`)
+	if got := s.ValidateJSON([]byte(`{"a": 1, "b": 2.5}`)); got != nil {
+		t.Errorf("numeric values: got %v", got)
+	}
+	want := []Violation{{Path: "$.b", Message: "expected number, got string"}}
+	if got := s.ValidateJSON([]byte(`{"a": 1, "b": "2"}`)); !reflect.DeepEqual(got, want) {
+		t.Errorf("string value: got %v, want %v", got, want)
+	}
+}
+
+func TestNullableType(t *testing.T) {
+	s := MustParse(`{"type": ["string", "null"]}`)
+	for _, v := range []any{"x", nil} {
+		if !s.Valid(v) {
+			t.Errorf("Valid(%v) = false", v)
+		}
+	}
+	want := []Violation{{Path: "$", Message: "expected string or null, got integer"}}
+	if got := s.Validate(float64(3)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate(3) = %v, want %v", got, want)
+	}
+}
+
+func TestTypeMatchesInteger(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want bool
+	}{
+		{0, true},
+		{-7, true},
+		{3.5, false},
+		{1 << 53, true},
+		{1 << 63, true},
+		{-(1 << 63), true},
+		{1e20, true},
+		{1e300, true},
+		{1e20 + 0.5, true}, // below float64 resolution at this magnitude
+	}
+	for _, tt := range tests {
+		if got := TypeMatches(tt.v, "integer"); got != tt.want {
+			t.Errorf("TypeMatches(%v, integer) = %v, want %v", tt.v, got, tt.want)
+		}
+		want := "number"
+		if tt.want {
+			want = "integer"
+		}
+		if got := TypeOf(tt.v); got != want {
+			t.Errorf("TypeOf(%v) = %s, want %s", tt.v, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"type": 3}`,
+		`{"pattern": "("}`,
+		`{"properties": {"a": {"pattern": "["}}}`,
+		`{"additionalProperties": "no"}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%s) succeeded, want error", data)
+		}
+	}
+}
+
+func TestFormatMatches(t *testing.T) {
+	tests := []struct {
+		format, s string
+		want      bool
+	}{
+		{"date", "2024-02-29", true},
+		{"date", "2023-02-29", false},
+		{"date", "2024-2-1", false},
+		{"date-time", "2024-03-01T09:30:00Z", true},
+		{"date-time", "2024-03-01T09:30:00+02:00", true},
+		{"date-time", "2024-03-01 09:30:00", false},
+		{"time", "09:30:00", true},
+		{"time", "09:30:00Z", true},
+		{"time", "9:30", false},
+		{"email", "ada@example.com", true},
+		{"email", "Ada <ada@example.com>", false},
+		{"email", "ada.example.com", false},
+		{"uri", "https://example.com/x?y=1", true},
+		{"uri", "/relative/path", false},
+		{"hostname", "anything goes", true},
+	}
+	for _, tt := range tests {
+		if got := FormatMatches(tt.s, tt.format); got != tt.want {
+			t.Errorf("FormatMatches(%q, %s) = %v, want %v", tt.s, tt.format, got, tt.want)
+		}
+	}
+}
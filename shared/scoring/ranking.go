@@ -0,0 +1,202 @@
+package scoring
+
+import (
+	"math"
+	"sort"
+)
+
+// The ranking metrics below take a ranking as an ordered list of IDs, best
+// first, and graded gold relevance as a map from ID to grade (0 = not
+// relevant). IDs missing from the map have grade 0. Binary metrics count an
+// ID as relevant when its grade is at least threshold.
+
+// NDCG computes Normalized Discounted Cumulative Gain over the top k
+// positions, with gain 2^grade - 1. The ideal ranking orders every graded
+// ID by grade and fills all k positions it can, so a ranking shorter than k
+// that leaves out relevant IDs scores below 1.
+func NDCG(ranking []string, relevance map[string]int, k int) float64 {
+	if k <= 0 || len(ranking) == 0 {
+		return 0
+	}
+
+	dcg := 0.0
+	for i := 0; i < k && i < len(ranking); i++ {
+		dcg += gain(relevance[ranking[i]]) / math.Log2(float64(i+2))
+	}
+
+	ideal := make([]int, 0, len(relevance))
+	for _, rel := range relevance {
+		ideal = append(ideal, rel)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ideal)))
+
+	idcg := 0.0
+	for i := 0; i < k && i < len(ideal); i++ {
+		idcg += gain(ideal[i]) / math.Log2(float64(i+2))
+	}
+
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+func gain(rel int) float64 {
+	return math.Pow(2, float64(rel)) - 1
+}
+
+// MRR computes the reciprocal rank of the first relevant ID. Averaged over
+// queries it is the Mean Reciprocal Rank.
+func MRR(ranking []string, relevance map[string]int, threshold int) float64 {
+	for i, id := range ranking {
+		if relevance[id] >= threshold {
+			return 1.0 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// PrecisionAtK is the fraction of the top k positions holding a relevant
+// ID. A ranking shorter than k counts the missing positions as misses.
+func PrecisionAtK(ranking []string, relevance map[string]int, k, threshold int) float64 {
+	if k <= 0 {
+		return 0
+	}
+	return float64(relevantInTop(ranking, relevance, k, threshold)) / float64(k)
+}
+
+// RecallAtK is the fraction of all relevant IDs that appear in the top k
+// positions. It is 0 when no ID is relevant.
+func RecallAtK(ranking []string, relevance map[string]int, k, threshold int) float64 {
+	total := countRelevant(relevance, threshold)
+	if total == 0 || k <= 0 {
+		return 0
+	}
+	return float64(relevantInTop(ranking, relevance, k, threshold)) / float64(total)
+}
+
+// AveragePrecision is the mean of the precision at each position holding a
+// relevant ID, divided over all relevant IDs so ones the ranking leaves out
+// count as zero. Averaged over queries it is Mean Average Precision (MAP).
+func AveragePrecision(ranking []string, relevance map[string]int, threshold int) float64 {
+	total := countRelevant(relevance, threshold)
+	if total == 0 {
+		return 0
+	}
+	var hits int
+	var sum float64
+	for i, id := range ranking {
+		if relevance[id] >= threshold {
+			hits++
+			sum += float64(hits) / float64(i+1)
+		}
+	}
+	return sum / float64(total)
+}
+
+func relevantInTop(ranking []string, relevance map[string]int, k, threshold int) int {
+	var n int
+	for i := 0; i < k && i < len(ranking); i++ {
+		if relevance[ranking[i]] >= threshold {
+			n++
+		}
+	}
+	return n
+}
+
+func countRelevant(relevance map[string]int, threshold int) int {
+	var n int
+	for _, rel := range relevance {
+		if rel >= threshold {
+			n++
+		}
+	}
+	return n
+}
+
+// KendallTau computes Kendall's tau-b between paired observations x and y,
+// which corrects for ties in either (graded relevance has many). It ranges
+// from -1 (reversed order) to 1 (same order) and is 0 when either side is
+// constant or the lengths differ.
+func KendallTau(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0
+	}
+	var concordant, discordant, tiesX, tiesY float64
+	for i := 0; i < len(x); i++ {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tiesX++
+			case dy == 0:
+				tiesY++
+			case (dx > 0) == (dy > 0):
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+	denom := math.Sqrt((concordant + discordant + tiesX) * (concordant + discordant + tiesY))
+	if denom == 0 {
+		return 0
+	}
+	return (concordant - discordant) / denom
+}
+
+// Spearman computes Spearman's rank correlation between paired observations
+// x and y: the Pearson correlation of their ranks, with tied values given
+// their average rank. It is 0 when either side is constant or the lengths
+// differ.
+func Spearman(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0
+	}
+	return pearson(ranks(x), ranks(y))
+}
+
+// ranks returns the 1-based rank of each value in v, averaging ties.
+func ranks(v []float64) []float64 {
+	idx := make([]int, len(v))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return v[idx[a]] < v[idx[b]] })
+
+	r := make([]float64, len(v))
+	for i := 0; i < len(idx); {
+		j := i
+		for j+1 < len(idx) && v[idx[j+1]] == v[idx[i]] {
+			j++
+		}
+		avg := float64(i+j)/2 + 1
+		for ; i <= j; i++ {
+			r[idx[i]] = avg
+		}
+	}
+	return r
+}
+
+func pearson(x, y []float64) float64 {
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(len(x))
+	my /= float64(len(y))
+
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
@@ -0,0 +1,175 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// graded has more graded IDs than the k most tests use, with grades out of
+// rank order: ideal order is b(3), d(2), e(2), a(1), c(0).
+var graded = map[string]int{"a": 1, "b": 3, "c": 0, "d": 2, "e": 2}
+
+func TestNDCG(t *testing.T) {
+	tests := []struct {
+		name      string
+		ranking   []string
+		relevance map[string]int
+		k         int
+		want      float64
+	}{
+		{
+			// DCG = (2^1-1)/log2(2) + (2^3-1)/log2(3) + 0; the ideal takes
+			// the top 3 of all five grades, 3, 2, 2, not just the ranked ones.
+			name:      "relevance longer than k",
+			ranking:   []string{"a", "b", "c", "d", "e"},
+			relevance: graded,
+			k:         3,
+			want:      (1 + 7/math.Log2(3)) / (7 + 3/math.Log2(3) + 3.0/2),
+		},
+		{
+			name:      "ideal order",
+			ranking:   []string{"b", "d", "e", "a", "c"},
+			relevance: graded,
+			k:         5,
+			want:      1,
+		},
+		{
+			name:      "tied grades in either order",
+			ranking:   []string{"b", "e", "d"},
+			relevance: graded,
+			k:         3,
+			want:      1,
+		},
+		{
+			// A two-item ranking is scored against a three-position ideal,
+			// so leaving out e costs its gain.
+			name:      "k beyond ranking",
+			ranking:   []string{"b", "d"},
+			relevance: graded,
+			k:         3,
+			want:      (7 + 3/math.Log2(3)) / (7 + 3/math.Log2(3) + 3.0/2),
+		},
+		{
+			name:      "k beyond relevance",
+			ranking:   []string{"x", "a"},
+			relevance: map[string]int{"a": 1},
+			k:         10,
+			want:      1 / math.Log2(3),
+		},
+		{"no relevant IDs", []string{"a", "b"}, map[string]int{"a": 0}, 5, 0},
+		{"empty ranking", nil, graded, 5, 0},
+		{"k zero", []string{"b"}, graded, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NDCG(tt.ranking, tt.relevance, tt.k); !approx(got, tt.want) {
+				t.Errorf("NDCG = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryRankingMetrics(t *testing.T) {
+	// With threshold 1, a, b, d and e are relevant; with threshold 2, b, d
+	// and e are.
+	ranking := []string{"c", "a", "x", "b", "d"}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"MRR", MRR(ranking, graded, 1), 1.0 / 2},
+		{"MRR threshold 2", MRR(ranking, graded, 2), 1.0 / 4},
+		{"MRR none relevant", MRR([]string{"c", "x"}, graded, 1), 0},
+
+		{"P@2", PrecisionAtK(ranking, graded, 2, 1), 1.0 / 2},
+		{"P@5", PrecisionAtK(ranking, graded, 5, 1), 3.0 / 5},
+		{"P@5 threshold 2", PrecisionAtK(ranking, graded, 5, 2), 2.0 / 5},
+		{"P@k beyond ranking", PrecisionAtK(ranking, graded, 10, 1), 3.0 / 10},
+		{"P@0", PrecisionAtK(ranking, graded, 0, 1), 0},
+
+		{"R@2", RecallAtK(ranking, graded, 2, 1), 1.0 / 4},
+		{"R@5", RecallAtK(ranking, graded, 5, 1), 3.0 / 4},
+		{"R@k beyond ranking", RecallAtK(ranking, graded, 10, 2), 2.0 / 3},
+		{"R@k no relevant IDs", RecallAtK(ranking, map[string]int{"c": 0}, 5, 1), 0},
+
+		// Hits at 2, 4 and 5: (1/2 + 2/4 + 3/5) / 4 relevant, e never ranked.
+		{"AP", AveragePrecision(ranking, graded, 1), (1.0/2 + 2.0/4 + 3.0/5) / 4},
+		// Hits at 4 and 5: (1/4 + 2/5) / 3 relevant.
+		{"AP threshold 2", AveragePrecision(ranking, graded, 2), (1.0/4 + 2.0/5) / 3},
+		{"AP perfect", AveragePrecision([]string{"b", "d", "e"}, graded, 2), 1},
+		{"AP no relevant IDs", AveragePrecision(ranking, map[string]int{"c": 0}, 1), 0},
+	}
+	for _, tt := range tests {
+		if !approx(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestKendallTau(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		{"same order", []float64{1, 2, 3, 4}, []float64{10, 20, 30, 40}, 1},
+		{"reversed", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, -1},
+		// Pairs: 5 concordant, 1 tied in y only, so
+		// tau-b = 5 / sqrt((5+0+0) * (5+0+1)).
+		{"ties in y", []float64{1, 2, 3, 4}, []float64{1, 2, 2, 3}, 5 / math.Sqrt(30)},
+		// Pairs (0,1) tied in x, (0,2) discordant, (1,2) tied in y, the
+		// other three concordant: (3-1) / sqrt((3+1+1) * (3+1+1)).
+		{"ties in both", []float64{1, 1, 2, 3}, []float64{2, 1, 1, 3}, 2.0 / 5},
+		// The pair tied in both counts in neither the numerator nor the
+		// denominator: 5 concordant, 0 discordant.
+		{"joint tie", []float64{1, 1, 2, 3}, []float64{5, 5, 6, 7}, 1},
+		{"constant", []float64{1, 2, 3}, []float64{2, 2, 2}, 0},
+		{"length mismatch", []float64{1, 2, 3}, []float64{1, 2}, 0},
+		{"one observation", []float64{1}, []float64{1}, 0},
+	}
+	for _, tt := range tests {
+		if got := KendallTau(tt.x, tt.y); !approx(got, tt.want) {
+			t.Errorf("%s: KendallTau = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		{"monotonic", []float64{1, 4, 9, 16}, []float64{1, 2, 3, 4}, 1},
+		{"reversed", []float64{1, 2, 3}, []float64{0.9, 0.5, 0.1}, -1},
+		// Ranks of x are 1, 2.5, 2.5, 4. Centred on 2.5 both sides:
+		// cov = 2.25 + 2.25, var x = 4.5, var y = 2.25 + 0.25 + 0.25 + 2.25.
+		{"average ranks", []float64{10, 20, 20, 30}, []float64{1, 2, 3, 4}, 4.5 / math.Sqrt(4.5*5)},
+		// Ranks x 1, 2, 3, 4, 5 and y 2, 2, 2, 4.5, 4.5: cov 7.5, var x 10,
+		// var y 7.5.
+		{"tied block", []float64{1, 2, 3, 4, 5}, []float64{0, 0, 0, 1, 1}, 7.5 / math.Sqrt(10*7.5)},
+		{"constant", []float64{1, 2, 3}, []float64{4, 4, 4}, 0},
+		{"length mismatch", []float64{1, 2, 3}, []float64{3, 2}, 0},
+	}
+	for _, tt := range tests {
+		if got := Spearman(tt.x, tt.y); !approx(got, tt.want) {
+			t.Errorf("%s: Spearman = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRanks(t *testing.T) {
+	got := ranks([]float64{3, 1, 3, 2, 3})
+	want := []float64{4, 1, 4, 2, 4}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ranks = %v, want %v", got, want)
+		}
+	}
+}
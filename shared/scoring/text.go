@@ -0,0 +1,184 @@
+package scoring
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Tokenize lowercases s and splits it into runs of letters and digits,
+// dropping everything else, as the reference ROUGE scorer does. The overlap
+// metrics below all take its output, so their numbers are comparable.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// PRF is a precision, recall and F1 triple.
+type PRF struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F         float64 `json:"f"`
+}
+
+func newPRF(overlap, candidateTotal, referenceTotal int) PRF {
+	var p PRF
+	if candidateTotal > 0 {
+		p.Precision = float64(overlap) / float64(candidateTotal)
+	}
+	if referenceTotal > 0 {
+		p.Recall = float64(overlap) / float64(referenceTotal)
+	}
+	if p.Precision+p.Recall > 0 {
+		p.F = 2 * p.Precision * p.Recall / (p.Precision + p.Recall)
+	}
+	return p
+}
+
+// RougeN computes ROUGE-N between tokenized reference and candidate texts:
+// the overlap of their n-grams, each counted at most as often as it appears
+// in the other text.
+func RougeN(reference, candidate []string, n int) PRF {
+	ref, cand := ngrams(reference, n), ngrams(candidate, n)
+	overlap := 0
+	for g, c := range cand {
+		overlap += min(c, ref[g])
+	}
+	return newPRF(overlap, total(cand), total(ref))
+}
+
+// RougeL computes ROUGE-L between tokenized reference and candidate texts
+// from the length of their longest common subsequence, which rewards words
+// in the same order without requiring them to be adjacent.
+func RougeL(reference, candidate []string) PRF {
+	return newPRF(lcs(reference, candidate), len(candidate), len(reference))
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// bleuOrder is the longest n-gram BLEU counts, as in the standard BLEU-4.
+const bleuOrder = 4
+
+// BLEU computes sentence-level BLEU-4 of a tokenized candidate against one
+// reference: the geometric mean of clipped 1- to 4-gram precisions times a
+// brevity penalty for candidates shorter than the reference. Orders above
+// unigrams use add-one smoothing (Lin and Och, 2004) so a short text
+// without a matching 4-gram does not score zero. The result is in [0, 1].
+func BLEU(reference, candidate []string) float64 {
+	if len(candidate) == 0 || len(reference) == 0 {
+		return 0
+	}
+
+	var logSum float64
+	for n := 1; n <= bleuOrder; n++ {
+		ref, cand := ngrams(reference, n), ngrams(candidate, n)
+		matches := 0
+		for g, c := range cand {
+			matches += min(c, ref[g])
+		}
+		count := total(cand)
+		if n == 1 {
+			if matches == 0 {
+				return 0
+			}
+			logSum += math.Log(float64(matches) / float64(count))
+			continue
+		}
+		logSum += math.Log(float64(matches+1) / float64(count+1))
+	}
+
+	brevity := 1.0
+	if len(candidate) < len(reference) {
+		brevity = math.Exp(1 - float64(len(reference))/float64(len(candidate)))
+	}
+	return brevity * math.Exp(logSum/bleuOrder)
+}
+
+// chrF parameters: character n-grams up to chrFOrder, with recall weighted
+// chrFBeta times as much as precision (chrF2).
+const (
+	chrFOrder = 6
+	chrFBeta  = 2
+)
+
+// ChrF computes the character n-gram F-score (Popović, 2015) of candidate
+// against reference, with whitespace removed: precision and recall of
+// 1- to 6-grams are averaged over the orders both texts are long enough
+// for, then combined into an F-score weighting recall twice as much as
+// precision. Unlike the word metrics it gives partial credit for
+// inflections and compounds. The result is in [0, 1].
+func ChrF(reference, candidate string) float64 {
+	ref, cand := chars(reference), chars(candidate)
+
+	var precision, recall float64
+	var orders int
+	for n := 1; n <= chrFOrder; n++ {
+		refGrams, candGrams := ngrams(ref, n), ngrams(cand, n)
+		refTotal, candTotal := total(refGrams), total(candGrams)
+		if refTotal == 0 || candTotal == 0 {
+			break
+		}
+		matches := 0
+		for g, c := range candGrams {
+			matches += min(c, refGrams[g])
+		}
+		precision += float64(matches) / float64(candTotal)
+		recall += float64(matches) / float64(refTotal)
+		orders++
+	}
+	if orders == 0 {
+		return 0
+	}
+	precision /= float64(orders)
+	recall /= float64(orders)
+
+	beta2 := float64(chrFBeta * chrFBeta)
+	if precision+recall == 0 {
+		return 0
+	}
+	return (1 + beta2) * precision * recall / (beta2*precision + recall)
+}
+
+// chars splits s into its non-space characters.
+func chars(s string) []string {
+	var out []string
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			out = append(out, string(r))
+		}
+	}
+	return out
+}
+
+// ngrams counts the n-grams of tokens.
+func ngrams(tokens []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(tokens); i++ {
+		counts[strings.Join(tokens[i:i+n], "\x00")]++
+	}
+	return counts
+}
+
+func total(counts map[string]int) int {
+	var n int
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
@@ -0,0 +1,149 @@
+package scoring
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("The quick-brown fox, 2 times!  Ünïcode")
+	want := []string{"the", "quick", "brown", "fox", "2", "times", "ünïcode"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func approxPRF(a, b PRF) bool {
+	return approx(a.Precision, b.Precision) && approx(a.Recall, b.Recall) && approx(a.F, b.F)
+}
+
+func f1(p, r float64) float64 {
+	return 2 * p * r / (p + r)
+}
+
+// The fox pair is the example from the rouge-score README, which reports
+// rouge1 P 0.75, R 0.6667 and rougeL P 0.625, R 0.5556 for it.
+var (
+	foxReference = Tokenize("The quick brown fox jumps over the lazy dog")
+	foxCandidate = Tokenize("The quick brown dog jumps on the log.")
+)
+
+func TestRougeN(t *testing.T) {
+	tests := []struct {
+		name                 string
+		reference, candidate []string
+		n                    int
+		want                 PRF
+	}{
+		// the x2, quick, brown, dog and jumps: 6 of 8 candidate and 9
+		// reference unigrams.
+		{"rouge1", foxReference, foxCandidate, 1, PRF{6.0 / 8, 6.0 / 9, f1(6.0/8, 6.0/9)}},
+		// "the quick" and "quick brown": 2 of 7 candidate and 8 reference
+		// bigrams.
+		{"rouge2", foxReference, foxCandidate, 2, PRF{2.0 / 7, 2.0 / 8, f1(2.0/7, 2.0/8)}},
+		// A repeated word only matches as often as the reference has it.
+		{"clipped", Tokenize("the cat"), Tokenize("the the the"), 1, PRF{1.0 / 3, 1.0 / 2, f1(1.0/3, 1.0/2)}},
+		{"identical", foxReference, foxReference, 2, PRF{1, 1, 1}},
+		{"no overlap", foxReference, Tokenize("nothing in common"), 1, PRF{}},
+		{"empty candidate", foxReference, nil, 1, PRF{}},
+		{"both empty", nil, nil, 1, PRF{}},
+		{"shorter than n", Tokenize("the cat"), Tokenize("the cat"), 3, PRF{}},
+	}
+	for _, tt := range tests {
+		if got := RougeN(tt.reference, tt.candidate, tt.n); !approxPRF(got, tt.want) {
+			t.Errorf("%s: RougeN = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRougeL(t *testing.T) {
+	tests := []struct {
+		name                 string
+		reference, candidate []string
+		want                 PRF
+	}{
+		// LCS "the quick brown jumps the", 5 tokens.
+		{"fox", foxReference, foxCandidate, PRF{5.0 / 8, 5.0 / 9, f1(5.0/8, 5.0/9)}},
+		// In order but not adjacent: LCS "a c e".
+		{"gaps", Tokenize("a b c d e"), Tokenize("a c e"), PRF{1, 3.0 / 5, f1(1, 3.0/5)}},
+		// Reversed, only one word can be in order.
+		{"reversed", Tokenize("a b c"), Tokenize("c b a"), PRF{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+		{"identical", foxReference, foxReference, PRF{1, 1, 1}},
+		{"empty candidate", foxReference, nil, PRF{}},
+		{"both empty", nil, nil, PRF{}},
+	}
+	for _, tt := range tests {
+		if got := RougeL(tt.reference, tt.candidate); !approxPRF(got, tt.want) {
+			t.Errorf("%s: RougeL = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The BLEU values are computed by hand the way sacrebleu's sentence BLEU
+// does with smooth_method="add-k" (k = 1, orders 2 to 4 only) on lowercased
+// text without punctuation, where its tokenizer and Tokenize agree.
+func TestBLEU(t *testing.T) {
+	mat := Tokenize("the cat is on the mat")
+	tests := []struct {
+		name                 string
+		reference, candidate []string
+		want                 float64
+	}{
+		// Clipped matches per order: 5/6 unigrams, 3/5 bigrams, 1/4
+		// trigrams, 0/3 4-grams; smoothed 5/6, 4/6, 2/5, 1/4. Same length,
+		// so no brevity penalty.
+		{"one word swapped", mat, Tokenize("the cat sat on the mat"), math.Pow(5.0/6*4.0/6*2.0/5*1.0/4, 0.25)},
+		// 5/5, 3/4, 1/3, 0/2, smoothed 1, 4/5, 2/4, 1/3; 5 tokens against
+		// 6 gives a brevity penalty of exp(1 - 6/5).
+		{"short candidate", mat, Tokenize("the cat on the mat"), math.Exp(1-6.0/5) * math.Pow(1*4.0/5*2.0/4*1.0/3, 0.25)},
+		// A longer candidate is not penalized for length, only through its
+		// precisions: 6/7, 5/6, 4/5, 3/4 smoothed to 6/7, 6/7, 5/6, 4/5.
+		{"long candidate", mat, Tokenize("the cat is on the mat today"), math.Pow(6.0/7*6.0/7*5.0/6*4.0/5, 0.25)},
+		{"identical", mat, mat, 1},
+		// With no 2-grams or longer to match, smoothing leaves those orders
+		// at 1 rather than zeroing the score.
+		{"identical single word", Tokenize("yes"), Tokenize("yes"), 1},
+		{"no unigram match", mat, Tokenize("dogs bark"), 0},
+		{"empty candidate", mat, nil, 0},
+		{"empty reference", nil, mat, 0},
+	}
+	for _, tt := range tests {
+		if got := BLEU(tt.reference, tt.candidate); !approx(got, tt.want) {
+			t.Errorf("%s: BLEU = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The chrF values are computed by hand from the definition: per-order
+// character n-gram precision and recall averaged over the orders both texts
+// have, then F with beta = 2.
+func TestChrF(t *testing.T) {
+	chrF2 := func(p, r float64) float64 { return 5 * p * r / (4*p + r) }
+	tests := []struct {
+		name                 string
+		reference, candidate string
+		want                 float64
+	}{
+		// Whitespace is dropped, so this is abc against abd: 1-grams 2/3,
+		// 2-grams 1/2 and 3-grams 0 for both precision and recall.
+		{"whitespace removed", "ab c", "abd", (2.0/3 + 1.0/2 + 0) / 3},
+		// Recall counts twice as much as precision: a candidate with half
+		// the reference scores lower than one with twice the reference.
+		// 1-grams P 1, R 1/2; 2-grams P 1, R 1/3.
+		{"short candidate", "abcd", "ab", chrF2(1, (1.0/2+1.0/3)/2)},
+		{"long candidate", "ab", "abcd", chrF2((1.0/2+1.0/3)/2, 1)},
+		// An inflection gets partial credit: 1- to 3-grams all match, with
+		// recall 3/4, 2/3 and 1/2.
+		{"inflection", "cats", "cat", chrF2(1, (3.0/4+2.0/3+1.0/2)/3)},
+		{"identical", "The quick brown fox", "The quick brown fox", 1},
+		{"no overlap", "abc", "xyz", 0},
+		{"empty candidate", "abc", "", 0},
+		{"whitespace only", "abc", "  \n", 0},
+	}
+	for _, tt := range tests {
+		if got := ChrF(tt.reference, tt.candidate); !approx(got, tt.want) {
+			t.Errorf("%s: ChrF = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
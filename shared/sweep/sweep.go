@@ -0,0 +1,96 @@
+// Package sweep runs an example across several models in one invocation.
+//
+// Examples resolve their model list with Models from the -model, -models and
+// -models-file flags, run the same cases once per model (writing each model's
+// result file as usual), and then pass the combined results to
+// reporting.GenerateComparison.
+package sweep
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/statherm/local-llm-examples/shared/types"
+)
+
+// Models returns the models to run. A models file takes precedence over a
+// comma-separated list, which takes precedence over the single -model value.
+// Duplicates are dropped, keeping the first occurrence.
+func Models(model, list, file string) ([]string, error) {
+	var models []string
+	switch {
+	case file != "":
+		var err error
+		if models, err = ReadModelsFile(file); err != nil {
+			return nil, err
+		}
+	case list != "":
+		models = strings.Split(list, ",")
+	default:
+		models = []string{model}
+	}
+
+	seen := make(map[string]bool, len(models))
+	var out []string
+	for _, m := range models {
+		m = strings.TrimSpace(m)
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+		out = append(out, m)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no models given")
+	}
+	return out, nil
+}
+
+// ReadModelsFile reads one model name per line. Blank lines and lines
+// starting with # are ignored, so the README's model lists can be kept in a
+// file with comments.
+func ReadModelsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open models file: %w", err)
+	}
+	defer f.Close()
+
+	var models []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		models = append(models, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read models file %s: %w", path, err)
+	}
+	return models, nil
+}
+
+// Only returns the results produced by one of models. Some examples recover
+// the model name from a sanitized result filename, so both sides are passed
+// through name (typically the example's sanitizeModelName) before comparing;
+// a nil name compares them as-is.
+func Only(results []types.BenchmarkResult, models []string, name func(string) string) []types.BenchmarkResult {
+	if name == nil {
+		name = func(s string) string { return s }
+	}
+	want := make(map[string]bool, len(models))
+	for _, m := range models {
+		want[name(m)] = true
+	}
+
+	var out []types.BenchmarkResult
+	for _, r := range results {
+		if want[name(r.Model)] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -1,29 +1,223 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ModelMetadata captures performance metrics from a single model call.
+//
+// TTFT is the wall-clock time to the first streamed token when Streamed is
+// true; otherwise it is the server-side estimate (model load + prompt eval).
 type ModelMetadata struct {
-	Model       string        `json:"model"`
-	TokensIn    int           `json:"tokens_in"`
-	TokensOut   int           `json:"tokens_out"`
-	TTFT        time.Duration `json:"ttft"`
-	TotalTime   time.Duration `json:"total_time"`
-	TokensPerSec float64      `json:"tokens_per_sec"`
+	Model             string        `json:"model"`
+	TokensIn          int           `json:"tokens_in"`
+	TokensOut         int           `json:"tokens_out"`
+	TTFT              time.Duration `json:"ttft"`
+	TotalTime         time.Duration `json:"total_time"`
+	TokensPerSec      float64       `json:"tokens_per_sec"`
+	LoadDuration      time.Duration `json:"load_duration,omitempty"`
+	InterTokenLatency time.Duration `json:"inter_token_latency,omitempty"` // mean gap between streamed tokens
+	Streamed          bool          `json:"streamed,omitempty"`
+	// QueueTime is how long the request waited for a free worker before it
+	// was sent when cases run concurrently (see shared/runner).
+	QueueTime time.Duration `json:"queue_time,omitempty"`
+	// Retries is how many times the call was retried after a transient
+	// failure. It is set on failed calls too, so flaky runs stay visible.
+	Retries int `json:"retries,omitempty"`
+}
+
+// MeanMetadata averages per-call metadata so a result row that covers many
+// calls (e.g. a whole classification run) reports a typical call. Zero-valued
+// entries, such as calls that failed, are skipped. Retries is the exception:
+// it is summed over every entry, failed calls included, so the row shows how
+// many retries the whole run needed.
+func MeanMetadata(metas []ModelMetadata) ModelMetadata {
+	var sum ModelMetadata
+	var n, streamed int
+	var retries int
+	for _, m := range metas {
+		retries += m.Retries
+		if m.TotalTime == 0 {
+			continue
+		}
+		n++
+		if sum.Model == "" {
+			sum.Model = m.Model
+		}
+		sum.TokensIn += m.TokensIn
+		sum.TokensOut += m.TokensOut
+		sum.TTFT += m.TTFT
+		sum.TotalTime += m.TotalTime
+		sum.TokensPerSec += m.TokensPerSec
+		sum.LoadDuration += m.LoadDuration
+		sum.QueueTime += m.QueueTime
+		if m.Streamed {
+			sum.InterTokenLatency += m.InterTokenLatency
+			streamed++
+		}
+	}
+	if n == 0 {
+		sum.Retries = retries
+		return sum
+	}
+
+	d := time.Duration(n)
+	mean := ModelMetadata{
+		Model:        sum.Model,
+		TokensIn:     sum.TokensIn / n,
+		TokensOut:    sum.TokensOut / n,
+		TTFT:         sum.TTFT / d,
+		TotalTime:    sum.TotalTime / d,
+		TokensPerSec: sum.TokensPerSec / float64(n),
+		LoadDuration: sum.LoadDuration / d,
+		QueueTime:    sum.QueueTime / d,
+		Streamed:     streamed == n,
+		Retries:      retries,
+	}
+	if streamed > 0 {
+		mean.InterTokenLatency = sum.InterTokenLatency / time.Duration(streamed)
+	}
+	return mean
+}
+
+// SumMetadata combines the metadata of calls that together make one logical
+// call, such as a re-asked reply or a multi-step ranking. Token counts, times
+// and retries are summed, TTFT is that of the first call, and TokensPerSec
+// is that of the last call that produced output.
+func SumMetadata(metas ...ModelMetadata) ModelMetadata {
+	var sum ModelMetadata
+	for _, m := range metas {
+		if sum.TotalTime == 0 && sum.Retries == 0 {
+			sum = m
+			continue
+		}
+		sum.TokensIn += m.TokensIn
+		sum.TokensOut += m.TokensOut
+		sum.TotalTime += m.TotalTime
+		sum.LoadDuration += m.LoadDuration
+		sum.Retries += m.Retries
+		if m.TotalTime > 0 {
+			sum.TokensPerSec = m.TokensPerSec
+		}
+	}
+	return sum
 }
 
 // BenchmarkResult holds the outcome of running one model on one example.
 type BenchmarkResult struct {
-	Example     string        `json:"example"`
-	Model       string        `json:"model"`
-	Quality     float64       `json:"quality"`
-	QualityName string        `json:"quality_name"`
-	TokensIn    int           `json:"tokens_in"`
-	TokensOut   int           `json:"tokens_out"`
-	TTFT        time.Duration `json:"ttft"`
-	TotalTime   time.Duration `json:"total_time"`
-	TokensPerSec float64      `json:"tokens_per_sec"`
-	CostUSD     float64       `json:"cost_usd"`
+	Example           string        `json:"example"`
+	Model             string        `json:"model"`
+	Quality           float64       `json:"quality"`
+	QualityName       string        `json:"quality_name"`
+	TokensIn          int           `json:"tokens_in"`
+	TokensOut         int           `json:"tokens_out"`
+	TTFT              time.Duration `json:"ttft"`
+	TotalTime         time.Duration `json:"total_time"`
+	TokensPerSec      float64       `json:"tokens_per_sec"`
+	InterTokenLatency time.Duration `json:"inter_token_latency,omitempty"`
+	CostUSD           float64       `json:"cost_usd"` // per call; see shared/pricing
+	// CostUnknown marks a row whose model the pricing table has no price
+	// for and that did not run locally. Its CostUSD is zero.
+	CostUnknown bool `json:"cost_unknown,omitempty"`
+	// SchemaChecks is the number of outputs validated against a JSON Schema
+	// and SchemaValid how many of them passed; zero checks means the example
+	// did not validate its output.
+	SchemaChecks int `json:"schema_checks,omitempty"`
+	SchemaValid  int `json:"schema_valid,omitempty"`
+	// JSONChecks is the number of outputs parsed as JSON. JSONRaw of them
+	// parsed as returned and JSONRepaired only after shared/repair fixed them
+	// up or the model was re-asked.
+	JSONChecks   int `json:"json_checks,omitempty"`
+	JSONRaw      int `json:"json_raw,omitempty"`
+	JSONRepaired int `json:"json_repaired,omitempty"`
+	// Retries is the total number of retried calls behind this row.
+	Retries int `json:"retries,omitempty"`
+	// Error is why the call behind this row failed, after any retries. A
+	// failed row has zero quality and no timings.
+	Error string `json:"error,omitempty"`
+	// Calls is the mean number of model calls per case, for examples where
+	// one case can take several.
+	Calls float64 `json:"calls,omitempty"`
+	// ModelInfo describes the model that produced the row, when the
+	// provider reported it at preflight.
+	ModelInfo *ModelInfo `json:"model_info,omitempty"`
+}
+
+// ModelInfo describes an installed model, as reported by the server before a
+// run. Fields the server does not report are left empty.
+type ModelInfo struct {
+	Name          string `json:"name"`
+	Family        string `json:"family,omitempty"`
+	ParameterSize string `json:"parameter_size,omitempty"` // e.g. "4.0B"
+	Quantization  string `json:"quantization,omitempty"`   // e.g. "Q4_K_M"
+	ContextLength int    `json:"context_length,omitempty"`
+	SizeBytes     int64  `json:"size_bytes,omitempty"`
+}
+
+// Summary describes m in one line, e.g.
+// "qwen3 4.0B Q4_K_M, 2.5 GB, 40960-token context".
+func (m ModelInfo) Summary() string {
+	var parts []string
+	if s := strings.Join(strings.Fields(m.Family+" "+m.ParameterSize+" "+m.Quantization), " "); s != "" {
+		parts = append(parts, s)
+	}
+	switch {
+	case m.SizeBytes >= 1e9:
+		parts = append(parts, fmt.Sprintf("%.1f GB", float64(m.SizeBytes)/1e9))
+	case m.SizeBytes > 0:
+		parts = append(parts, fmt.Sprintf("%.0f MB", float64(m.SizeBytes)/1e6))
+	}
+	if m.ContextLength > 0 {
+		parts = append(parts, fmt.Sprintf("%d-token context", m.ContextLength))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SchemaValidRate returns the fraction of checked outputs that were
+// schema-valid, or 0 if nothing was checked.
+func (r BenchmarkResult) SchemaValidRate() float64 {
+	if r.SchemaChecks == 0 {
+		return 0
+	}
+	return float64(r.SchemaValid) / float64(r.SchemaChecks)
+}
+
+// RawJSONRate returns the fraction of JSON outputs that parsed as returned,
+// or 0 if nothing was parsed.
+func (r BenchmarkResult) RawJSONRate() float64 {
+	if r.JSONChecks == 0 {
+		return 0
+	}
+	return float64(r.JSONRaw) / float64(r.JSONChecks)
+}
+
+// RepairedJSONRate returns the fraction of JSON outputs that parsed with or
+// without repair, or 0 if nothing was parsed.
+func (r BenchmarkResult) RepairedJSONRate() float64 {
+	if r.JSONChecks == 0 {
+		return 0
+	}
+	return float64(r.JSONRaw+r.JSONRepaired) / float64(r.JSONChecks)
+}
+
+// ModelLabel returns the report label for model run in a variant of an
+// example, such as a different strategy: "qwen3:4b [listwise]". An empty
+// variant labels the model as itself.
+func ModelLabel(model, variant string) string {
+	if variant == "" {
+		return model
+	}
+	return model + " [" + variant + "]"
+}
+
+// BaseModel returns the model a ModelLabel names.
+func BaseModel(label string) string {
+	if i := strings.LastIndex(label, " ["); i > 0 && strings.HasSuffix(label, "]") {
+		return label[:i]
+	}
+	return label
 }
 
 // FieldResult describes the match outcome for a single JSON field.